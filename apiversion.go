@@ -0,0 +1,92 @@
+package dockerexec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/docker/docker/api/types/versions"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// APIVersionError is returned when the Cmd uses a feature that the API version negotiated with
+// the daemon doesn't support.
+type APIVersionError struct {
+	Feature  string // the unsupported feature, e.g. "Config.StopTimeout"
+	Version  string // the negotiated API version
+	Required string // the minimal API version supporting the feature
+}
+
+func (e *APIVersionError) Error() string {
+	return fmt.Sprintf("dockerexec: daemon API %s does not support %s (requires %s)", e.Version, e.Feature, e.Required)
+}
+
+// versionNegotiator is implemented by *client.Client, and negotiates the API version, if enabled
+// and not done yet, before comparing it.
+type versionNegotiator interface {
+	NewVersionError(ctx context.Context, APIrequired, feature string) error
+}
+
+// newVersionError calls NewVersionError if cli is a versionNegotiator, and otherwise returns nil,
+// leaving the comparison to checkAPIVersion. Wrapping clients forward their NewVersionError here.
+func newVersionError(ctx context.Context, cli Client, required, feature string) error {
+	if vn, ok := cli.(versionNegotiator); ok {
+		return vn.NewVersionError(ctx, required, feature)
+	}
+	return nil
+}
+
+// checkAPIVersion returns an *APIVersionError if the API version used by cli is older than
+// required.
+func checkAPIVersion(ctx context.Context, cli Client, required, feature string) error {
+	if err := newVersionError(ctx, cli, required, feature); err != nil {
+		version := cli.ClientVersion()
+		if version == "" || !versions.LessThan(version, required) {
+			// Failed negotiating rather than an old version.
+			return wrapError("negotiate API version", err)
+		}
+	}
+
+	if version := cli.ClientVersion(); version != "" && versions.LessThan(version, required) {
+		return &APIVersionError{Feature: feature, Version: version, Required: required}
+	}
+	return nil
+}
+
+// checkAPIFeatures checks the configuration of c against the API version of the daemon, so that
+// old daemons fail with a clear error rather than a raw one from the API.
+//
+// Platform is dropped with a warning when unsupported rather than failing, as the daemon will
+// then simply use its own platform. Older wait conditions are already handled by the client.
+func (c *Cmd) checkAPIFeatures(ctx context.Context) (*ocispec.Platform, []Warning, error) {
+	platform := c.Platform
+	var warnings []Warning
+
+	if platform != nil {
+		err := checkAPIVersion(ctx, c.cli, "1.41", "Platform")
+		var versionErr *APIVersionError
+		if errors.As(err, &versionErr) {
+			platform = nil
+			warnings = append(warnings, Warning{
+				Code:    WarningUnsupportedFeature,
+				Message: versionErr.Error() + ", ignoring it",
+			})
+		} else if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if c.Config.StopTimeout != nil {
+		if err := checkAPIVersion(ctx, c.cli, "1.25", "Config.StopTimeout"); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if c.Config.Healthcheck != nil && c.Config.Healthcheck.StartInterval != 0 {
+		if err := checkAPIVersion(ctx, c.cli, "1.44", "Config.Healthcheck.StartInterval"); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return platform, warnings, nil
+}
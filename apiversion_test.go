@@ -0,0 +1,66 @@
+package dockerexec_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestAPIVersionFallback(t *testing.T) {
+	oldClient, err := client.NewClientWithOpts(client.FromEnv, client.WithVersion("1.39"))
+	require.NoError(t, err)
+	defer oldClient.Close()
+
+	cmd := dockerexec.Command(oldClient, testImage, "true")
+	cmd.Platform = &ocispec.Platform{OS: "linux"}
+	require.NoError(t, cmd.Run())
+	assert.Contains(t, cmd.Warnings, dockerexec.Warning{
+		Code:    dockerexec.WarningUnsupportedFeature,
+		Message: "dockerexec: daemon API 1.39 does not support Platform (requires 1.41), ignoring it",
+	})
+
+	cmd = dockerexec.Command(oldClient, testImage, "true")
+	cmd.Config.Healthcheck = &container.HealthConfig{StartInterval: time.Second}
+	err = cmd.Run()
+	var versionErr *dockerexec.APIVersionError
+	require.ErrorAs(t, err, &versionErr)
+	assert.Equal(t, "1.44", versionErr.Required)
+}
+
+// negotiatingClient records API version negotiation.
+type negotiatingClient struct {
+	dockerexec.Client
+	negotiations int
+}
+
+func (c *negotiatingClient) NewVersionError(ctx context.Context, APIrequired, feature string) error {
+	c.negotiations++
+	return dockerClient.NewVersionError(ctx, APIrequired, feature)
+}
+
+func TestAPIVersionNegotiationWrapped(t *testing.T) {
+	cli := &negotiatingClient{Client: dockerClient}
+	stopTimeout := 5
+
+	cmd := dockerexec.Command(cli, testImage, "true")
+	cmd.Config.StopTimeout = &stopTimeout
+	cmd.Trace = &dockerexec.Trace{}
+	require.NoError(t, cmd.Run())
+	assert.Equal(t, 1, cli.negotiations, "should negotiate through Trace")
+
+	noFaults := dockerexec.FaultInjectorFunc(func(ctx context.Context, stage dockerexec.FaultStage, id string) error {
+		return nil
+	})
+	cmd = dockerexec.Command(dockerexec.WithFaults(cli, noFaults), testImage, "true")
+	cmd.Config.StopTimeout = &stopTimeout
+	require.NoError(t, cmd.Run())
+	assert.Equal(t, 2, cli.negotiations, "should negotiate through WithFaults")
+}
@@ -0,0 +1,210 @@
+package dockerexec
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ArtifactSpec is a path to copy out of the container once it exits, see Cmd.Artifacts.
+type ArtifactSpec struct {
+	// ContainerPath is the path of a file or a directory in the container.
+	ContainerPath string
+
+	// HostDest is the path on the host to copy the file to, or to copy the contents of the
+	// directory into, creating it and its parents as needed. Existing files are overwritten.
+	HostDest string
+}
+
+// ArtifactError records a failure to copy an artifact out of the container, see Cmd.Artifacts.
+type ArtifactError struct {
+	Artifact ArtifactSpec
+	Err      error
+}
+
+func (e *ArtifactError) Error() string {
+	return fmt.Sprintf("dockerexec: artifact %s: %v", e.Artifact.ContainerPath, e.Err)
+}
+
+func (e *ArtifactError) Unwrap() error {
+	return e.Err
+}
+
+// applyArtifacts disables HostConfig.AutoRemove when there are Artifacts to copy, remembering to
+// remove the container once they're copied instead.
+func (c *Cmd) applyArtifacts() {
+	if len(c.Artifacts) != 0 && c.HostConfig != nil && c.HostConfig.AutoRemove {
+		c.HostConfig.AutoRemove = false
+		c.removeAfterWait = true
+	}
+}
+
+// copyArtifacts copies Artifacts out of the exited container, recording failures in
+// ArtifactErrors, and returns them joined.
+func (c *Cmd) copyArtifacts() error {
+	var errs []error
+	for _, artifact := range c.Artifacts {
+		if err := c.copyArtifact(artifact); err != nil {
+			artifactErr := &ArtifactError{Artifact: artifact, Err: err}
+			c.ArtifactErrors = append(c.ArtifactErrors, artifactErr)
+			errs = append(errs, artifactErr)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (c *Cmd) copyArtifact(artifact ArtifactSpec) error {
+	rc, _, err := c.cli.CopyFromContainer(context.Background(), c.ContainerID, artifact.ContainerPath)
+	if err != nil {
+		return wrapError("copy from container", err)
+	}
+	defer rc.Close()
+	return extractArtifact(rc, artifact.HostDest)
+}
+
+// extractArtifact extracts the tar archive of a file or directory copied from a container to
+// dest. Entries are named relative to the parent of the copied path, so the first component of
+// each name, the base name of the copied path, is replaced by dest. Symbolic links are
+// recreated, but never followed when extracting, hard links are recreated, or copied if that
+// fails, and other file types fail the extraction.
+func extractArtifact(r io.Reader, dest string) error {
+	tr := tar.NewReader(r)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		target, err := artifactTarget(dest, h.Name)
+		if err != nil {
+			return err
+		}
+		if target == dest {
+			if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+				return err
+			}
+		}
+
+		mode := h.FileInfo().Mode().Perm()
+		switch h.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, mode|0o700); err != nil {
+				return err
+			}
+
+		case tar.TypeReg:
+			if err := writeArtifactFile(target, tr, mode); err != nil {
+				return err
+			}
+
+		case tar.TypeSymlink:
+			if err := os.Remove(target); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+			if err := os.Symlink(h.Linkname, target); err != nil {
+				return err
+			}
+
+		case tar.TypeLink:
+			// The link target is an earlier entry of the archive, named like the entries are
+			linkTarget, err := artifactTarget(dest, h.Linkname)
+			if err != nil {
+				return err
+			}
+			if err := linkArtifactFile(linkTarget, target, mode); err != nil {
+				return err
+			}
+
+		case tar.TypeXGlobalHeader:
+			// Applies to later entries, which the tar reader already takes care of
+
+		default:
+			return fmt.Errorf("unsupported file type %q of %q in archive", h.Typeflag, h.Name)
+		}
+	}
+}
+
+// artifactTarget returns the path name, an entry of an archive extracted by extractArtifact, is
+// extracted to under dest, making sure it can't escape dest.
+func artifactTarget(dest, name string) (string, error) {
+	_, rel, _ := strings.Cut(strings.TrimSuffix(name, "/"), "/")
+	if rel == "" {
+		return dest, nil
+	}
+	if !filepath.IsLocal(filepath.FromSlash(rel)) {
+		return "", fmt.Errorf("unsafe path %q in archive", name)
+	}
+	target := filepath.Join(dest, filepath.FromSlash(rel))
+	if err := checkInside(dest, filepath.Dir(target)); err != nil {
+		return "", err
+	}
+	return target, nil
+}
+
+// writeArtifactFile writes the contents of r to a new file at target, replacing any existing one.
+func writeArtifactFile(target string, r io.Reader, mode os.FileMode) error {
+	// Never write through an existing symbolic link
+	if err := os.Remove(target); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, mode|0o600)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+	return err
+}
+
+// linkArtifactFile hard links target to the already extracted regular file linkTarget, or copies
+// it if hard linking fails, e.g. on file systems not supporting hard links.
+func linkArtifactFile(linkTarget, target string, mode os.FileMode) error {
+	// Only link regular files, never symbolic links, so that copying never follows them
+	fi, err := os.Lstat(linkTarget)
+	if err != nil {
+		return err
+	}
+	if !fi.Mode().IsRegular() {
+		return fmt.Errorf("hard link target %s is not a regular file", linkTarget)
+	}
+
+	if err := os.Remove(target); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := os.Link(linkTarget, target); err == nil {
+		return nil
+	}
+
+	f, err := os.Open(linkTarget)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return writeArtifactFile(target, f, mode)
+}
+
+// checkInside returns an error if dir, after resolving symbolic links, isn't inside root.
+func checkInside(root, dir string) error {
+	resolvedRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return err
+	}
+	resolved, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(resolvedRoot, resolved)
+	if err != nil || !filepath.IsLocal(rel) && rel != "." {
+		return fmt.Errorf("path %s escapes %s", dir, root)
+	}
+	return nil
+}
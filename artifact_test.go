@@ -0,0 +1,106 @@
+package dockerexec_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestArtifacts(t *testing.T) {
+	dir := t.TempDir()
+	cmd := dockerexec.Command(dockerClient, testImage, "sh", "-c",
+		"mkdir -p /out/sub && echo report > /report.txt && echo a > /out/a && echo b > /out/sub/b && ln -s a /out/link")
+	cmd.Artifacts = []dockerexec.ArtifactSpec{
+		{ContainerPath: "/report.txt", HostDest: filepath.Join(dir, "reports", "report.txt")},
+		{ContainerPath: "/out", HostDest: filepath.Join(dir, "out")},
+	}
+	err := cmd.Run()
+	require.NoError(t, err)
+	assert.Empty(t, cmd.ArtifactErrors)
+
+	data, err := os.ReadFile(filepath.Join(dir, "reports", "report.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "report\n", string(data))
+
+	data, err = os.ReadFile(filepath.Join(dir, "out", "a"))
+	require.NoError(t, err)
+	assert.Equal(t, "a\n", string(data))
+
+	data, err = os.ReadFile(filepath.Join(dir, "out", "sub", "b"))
+	require.NoError(t, err)
+	assert.Equal(t, "b\n", string(data))
+
+	link, err := os.Readlink(filepath.Join(dir, "out", "link"))
+	require.NoError(t, err)
+	assert.Equal(t, "a", link)
+
+	_, err = dockerClient.ContainerInspect(context.Background(), cmd.ContainerID)
+	assert.Error(t, err, "container should have been removed")
+}
+
+func TestArtifactsOnFailure(t *testing.T) {
+	dir := t.TempDir()
+	cmd := dockerexec.Command(dockerClient, testImage, "sh", "-c", "echo report > /report.txt; exit 3")
+	cmd.Artifacts = []dockerexec.ArtifactSpec{
+		{ContainerPath: "/report.txt", HostDest: filepath.Join(dir, "report.txt")},
+	}
+	err := cmd.Run()
+
+	var exitErr *dockerexec.ExitError
+	require.ErrorAs(t, err, &exitErr)
+	data, err := os.ReadFile(filepath.Join(dir, "report.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "report\n", string(data))
+}
+
+func TestArtifactsMissing(t *testing.T) {
+	dir := t.TempDir()
+	cmd := dockerexec.Command(dockerClient, testImage, "true")
+	cmd.Artifacts = []dockerexec.ArtifactSpec{
+		{ContainerPath: "/does-not-exist", HostDest: filepath.Join(dir, "missing")},
+		{ContainerPath: "/etc/hostname", HostDest: filepath.Join(dir, "hostname")},
+	}
+	err := cmd.Run()
+
+	var artifactErr *dockerexec.ArtifactError
+	require.ErrorAs(t, err, &artifactErr)
+	assert.Equal(t, "/does-not-exist", artifactErr.Artifact.ContainerPath)
+	require.Len(t, cmd.ArtifactErrors, 1)
+	assert.FileExists(t, filepath.Join(dir, "hostname"))
+}
+
+func TestArtifactsNotCached(t *testing.T) {
+	var cache dockerexec.MemoryResultCache
+	for i := 0; i < 2; i++ {
+		dir := t.TempDir()
+		cmd := dockerexec.Command(dockerClient, testImage, "sh", "-c", "echo report > /report.txt")
+		cmd.Cache = &cache
+		cmd.Artifacts = []dockerexec.ArtifactSpec{
+			{ContainerPath: "/report.txt", HostDest: filepath.Join(dir, "report.txt")},
+		}
+		require.NoError(t, cmd.Run())
+		assert.False(t, cmd.Cached())
+		assert.FileExists(t, filepath.Join(dir, "report.txt"))
+	}
+}
+
+func TestArtifactsHardLink(t *testing.T) {
+	dir := t.TempDir()
+	cmd := dockerexec.Command(dockerClient, testImage, "sh", "-c", "mkdir /out && echo a > /out/a && ln /out/a /out/b")
+	cmd.Artifacts = []dockerexec.ArtifactSpec{
+		{ContainerPath: "/out", HostDest: filepath.Join(dir, "out")},
+	}
+	require.NoError(t, cmd.Run())
+
+	for _, name := range []string{"a", "b"} {
+		data, err := os.ReadFile(filepath.Join(dir, "out", name))
+		require.NoError(t, err)
+		assert.Equal(t, "a\n", string(data))
+	}
+}
@@ -0,0 +1,156 @@
+package dockerexec
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/distribution/reference"
+)
+
+// AuditEvent is a structured record of a single run, delivered to Cmd.Audit.
+type AuditEvent struct {
+	// Principal is Cmd.Principal.
+	Principal string `json:"principal,omitempty"`
+
+	// ContainerID is the ID of the container, empty if it was never created. It's set even if the
+	// container failed to start after being created, in which case it was removed.
+	ContainerID string `json:"containerId,omitempty"`
+
+	// Image is the image reference the container was run from, and ImageID is the ID of the local
+	// image it resolved to. ImageDigest is the repository digest of that image, such as
+	// "ubuntu@sha256:...", preferring the repository of Image, and is empty if the image was never
+	// pushed to or pulled from a registry.
+	Image       string `json:"image"`
+	ImageID     string `json:"imageId,omitempty"`
+	ImageDigest string `json:"imageDigest,omitempty"`
+
+	// Command is the command of the container, including its entrypoint if overridden.
+	Command []string `json:"command"`
+
+	// Mounts lists the bind mounts, volumes and other mounts of the container, as
+	// "source:target".
+	Mounts []string `json:"mounts,omitempty"`
+
+	// NetworkMode is the network mode of the container, empty for the daemon's default.
+	NetworkMode string `json:"networkMode,omitempty"`
+
+	// Privileged is set if the container ran privileged.
+	Privileged bool `json:"privileged,omitempty"`
+
+	// GateDecision is the decision of Cmd.ImageGate, if any.
+	GateDecision *GateDecision `json:"gateDecision,omitempty"`
+
+	// StartTime and EndTime are the times the run started and ended.
+	StartTime time.Time `json:"startTime"`
+	EndTime   time.Time `json:"endTime"`
+
+	// StatusCode is the status code of the container, or -1 if it didn't exit.
+	StatusCode int64 `json:"statusCode"`
+
+	// Error is the error the run failed with, if any.
+	Error string `json:"error,omitempty"`
+
+	// Cached is set if the run was skipped as its result was found in Cmd.Cache, in which case
+	// there's no container.
+	Cached bool `json:"cached,omitempty"`
+}
+
+// An AuditSink receives AuditEvents. It may be called concurrently by different Cmds.
+type AuditSink interface {
+	Audit(event *AuditEvent)
+}
+
+// AuditFunc is an adapter to allow the use of ordinary functions as an AuditSink.
+type AuditFunc func(event *AuditEvent)
+
+// Audit calls f(event).
+func (f AuditFunc) Audit(event *AuditEvent) {
+	f(event)
+}
+
+// JSONAuditSink is an AuditSink writing each event as a line of JSON, which is safe for concurrent
+// use.
+type JSONAuditSink struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewJSONAuditSink returns a JSONAuditSink writing to w.
+func NewJSONAuditSink(w io.Writer) *JSONAuditSink {
+	return &JSONAuditSink{enc: json.NewEncoder(w)}
+}
+
+// Audit writes event as a line of JSON. Write errors are ignored.
+func (s *JSONAuditSink) Audit(event *AuditEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_ = s.enc.Encode(event)
+}
+
+// resolveImageDigest records the ID and repository digest of the image the container was created
+// from.
+func (c *Cmd) resolveImageDigest(ctx context.Context, id string) {
+	inspect, err := c.cli.ContainerInspect(ctx, id)
+	if err != nil {
+		return
+	}
+	c.imageID = inspect.Image
+
+	img, _, err := c.cli.ImageInspectWithRaw(ctx, inspect.Image)
+	if err != nil || len(img.RepoDigests) == 0 {
+		return
+	}
+	c.imageDigest = img.RepoDigests[0]
+	if named, err := reference.ParseNormalizedNamed(c.Config.Image); err == nil {
+		name := reference.FamiliarName(named)
+		for _, digest := range img.RepoDigests {
+			if repo, _, _ := strings.Cut(digest, "@"); repo == name {
+				c.imageDigest = digest
+				break
+			}
+		}
+	}
+}
+
+// audit delivers an AuditEvent for the run to c.Audit, if set.
+func (c *Cmd) audit(err error) {
+	if c.Audit == nil {
+		return
+	}
+
+	event := &AuditEvent{
+		Principal:    c.Principal,
+		ContainerID:  c.ContainerID,
+		Image:        c.Config.Image,
+		ImageID:      c.imageID,
+		ImageDigest:  c.imageDigest,
+		GateDecision: c.GateDecision,
+		StartTime:    c.startTime,
+		EndTime:      time.Now(),
+		StatusCode:   c.StatusCode,
+		Privileged:   c.privileged(),
+		Cached:       c.Cached(),
+	}
+	if event.ContainerID == "" {
+		event.ContainerID = c.createdID
+	}
+	event.Command = append(event.Command, c.Config.Entrypoint...)
+	event.Command = append(event.Command, c.Config.Cmd...)
+	if c.HostConfig != nil {
+		event.Mounts = append(event.Mounts, c.HostConfig.Binds...)
+		for _, m := range c.HostConfig.Mounts {
+			event.Mounts = append(event.Mounts, m.Source+":"+m.Target)
+		}
+		event.NetworkMode = string(c.HostConfig.NetworkMode)
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+
+	c.Audit.Audit(event)
+}
@@ -0,0 +1,86 @@
+package dockerexec_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestAudit(t *testing.T) {
+	var events []*dockerexec.AuditEvent
+
+	cmd := dockerexec.Command(dockerClient, testImage, "sh", "-c", "exit 3")
+	cmd.Principal = "alice"
+	cmd.HostConfig.Binds = []string{t.TempDir() + ":/data"}
+	cmd.HostConfig.NetworkMode = "none"
+	cmd.Audit = dockerexec.AuditFunc(func(event *dockerexec.AuditEvent) {
+		events = append(events, event)
+	})
+
+	err := cmd.Run()
+	require.Error(t, err)
+
+	require.Len(t, events, 1)
+	event := events[0]
+	assert.Equal(t, "alice", event.Principal)
+	assert.Equal(t, cmd.ContainerID, event.ContainerID)
+	assert.Equal(t, testImage, event.Image)
+	assert.Contains(t, event.ImageID, "sha256:")
+	assert.Contains(t, event.ImageDigest, "ubuntu@sha256:")
+	assert.Equal(t, []string{"sh", "-c", "exit 3"}, event.Command)
+	assert.Equal(t, cmd.HostConfig.Binds, event.Mounts)
+	assert.Equal(t, "none", event.NetworkMode)
+	assert.False(t, event.StartTime.IsZero())
+	assert.False(t, event.EndTime.Before(event.StartTime))
+	assert.Equal(t, int64(3), event.StatusCode)
+	assert.Equal(t, "exit status 3", event.Error)
+}
+
+func TestAuditRejected(t *testing.T) {
+	var buf bytes.Buffer
+
+	cmd := dockerexec.Command(dockerClient, testImage, "true")
+	cmd.Audit = dockerexec.NewJSONAuditSink(&buf)
+	cmd.ImageGate = func(ctx context.Context, image string) (dockerexec.GateDecision, error) {
+		return dockerexec.GateDecision{Reason: "too many vulnerabilities"}, nil
+	}
+
+	err := cmd.Run()
+	require.Error(t, err)
+
+	var event dockerexec.AuditEvent
+	err = json.Unmarshal(buf.Bytes(), &event)
+	require.NoError(t, err)
+	assert.Empty(t, event.ContainerID)
+	assert.Equal(t, &dockerexec.GateDecision{Reason: "too many vulnerabilities"}, event.GateDecision)
+	assert.Equal(t, int64(-1), event.StatusCode)
+	assert.Contains(t, event.Error, "rejected")
+}
+
+func TestAuditCreatedNotStarted(t *testing.T) {
+	var events []*dockerexec.AuditEvent
+
+	// Adding the user fails with a read-only root filesystem, after the container was created.
+	cmd := dockerexec.Command(dockerClient, testImage, "true")
+	cmd.ApplyProfile(dockerexec.ProfileSecure)
+	cmd.Config.User = "12345"
+	cmd.EnsurePasswd = true
+	cmd.Audit = dockerexec.AuditFunc(func(event *dockerexec.AuditEvent) {
+		events = append(events, event)
+	})
+
+	err := cmd.Run()
+	require.Error(t, err)
+	assert.Empty(t, cmd.ContainerID)
+
+	require.Len(t, events, 1)
+	require.NotEmpty(t, events[0].ContainerID)
+	_, err = dockerClient.ContainerInspect(context.Background(), events[0].ContainerID)
+	assert.Error(t, err, "container should have been removed")
+}
@@ -0,0 +1,83 @@
+package dockerexec
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// AddBind adds a bind mount of the host path source at target in the container to
+// HostConfig.Binds, making source absolute first.
+//
+// On a Windows host running Linux containers, source is translated using WindowsBindSource, as
+// the daemon can't parse drive-letter paths in bind strings.
+func (c *Cmd) AddBind(source, target string, readOnly bool) error {
+	source, err := filepath.Abs(source)
+	if err != nil {
+		return fmt.Errorf("dockerexec: bind source: %w", err)
+	}
+
+	windowsContainer := c.Platform != nil && c.Platform.OS == "windows"
+	if runtime.GOOS == "windows" && !windowsContainer {
+		source, err = WindowsBindSource(source)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !windowsContainer && !path.IsAbs(target) {
+		return fmt.Errorf("dockerexec: bind target %q is not an absolute path", target)
+	}
+
+	bind := source + ":" + target
+	if readOnly {
+		bind += ":ro"
+	}
+
+	if c.HostConfig == nil {
+		c.HostConfig = &container.HostConfig{}
+	}
+	c.HostConfig.Binds = append(c.HostConfig.Binds, bind)
+	return nil
+}
+
+// WindowsBindSource translates an absolute Windows drive-letter path, such as C:\Users\me, to the
+// form accepted by the daemon in bind strings for Linux containers, such as /c/Users/me.
+//
+// UNC and relative paths are rejected, as they can't be translated.
+func WindowsBindSource(p string) (string, error) {
+	p = strings.ReplaceAll(p, `\`, "/")
+	if strings.HasPrefix(p, "//") {
+		return "", fmt.Errorf("dockerexec: bind source %q: UNC paths are not supported", p)
+	}
+	if len(p) < 2 || p[1] != ':' || !isDriveLetter(p[0]) {
+		return "", fmt.Errorf("dockerexec: bind source %q is not an absolute drive-letter path", p)
+	}
+
+	rest := p[2:]
+	if rest != "" && rest[0] != '/' {
+		return "", fmt.Errorf("dockerexec: bind source %q is relative to the current directory of drive %c", p, p[0])
+	}
+	if strings.Contains(rest, ":") {
+		return "", errors.New("dockerexec: bind source can't contain a colon")
+	}
+
+	return path.Clean("/" + strings.ToLower(p[:1]) + "/" + rest), nil
+}
+
+// windowsHostPath reverses WindowsBindSource, returning p unchanged if it's not in its form.
+func windowsHostPath(p string) string {
+	if len(p) < 2 || p[0] != '/' || !isDriveLetter(p[1]) || (len(p) > 2 && p[2] != '/') {
+		return p
+	}
+	return strings.ToUpper(p[1:2]) + ":" + filepath.FromSlash("/"+strings.TrimPrefix(p[2:], "/"))
+}
+
+func isDriveLetter(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
@@ -0,0 +1,43 @@
+package dockerexec_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestWindowsBindSource(t *testing.T) {
+	source, err := dockerexec.WindowsBindSource(`C:\Users\me\project`)
+	require.NoError(t, err)
+	assert.Equal(t, "/c/Users/me/project", source)
+
+	source, err = dockerexec.WindowsBindSource(`d:/data/`)
+	require.NoError(t, err)
+	assert.Equal(t, "/d/data", source)
+
+	_, err = dockerexec.WindowsBindSource(`\\server\share`)
+	assert.ErrorContains(t, err, "UNC paths are not supported")
+
+	_, err = dockerexec.WindowsBindSource(`C:data`)
+	assert.ErrorContains(t, err, "is relative to the current directory of drive C")
+
+	_, err = dockerexec.WindowsBindSource(`data`)
+	assert.ErrorContains(t, err, "is not an absolute drive-letter path")
+}
+
+func TestAddBind(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(dir+"/hello.txt", []byte("Hello, World!\n"), 0o644))
+
+	cmd := dockerexec.Command(dockerClient, testImage, "cat", "/data/hello.txt")
+	require.NoError(t, cmd.AddBind(dir, "/data", true))
+	output, err := cmd.Output()
+	require.NoError(t, err)
+	assert.Equal(t, "Hello, World!\n", string(output))
+
+	assert.ErrorContains(t, cmd.AddBind(dir, "data", false), "is not an absolute path")
+}
@@ -0,0 +1,228 @@
+package dockerexec
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"sync"
+)
+
+// BufferedStdoutPipe is like StdoutPipe, but the output of the container is buffered, so it's safe
+// to call Wait (or Run) before reading all of it, and reads remain valid after Wait returns.
+//
+// Up to memoryLimit bytes of unread output are buffered in memory. Beyond that, the output is
+// spilled to a temporary file, which is removed once the pipe is closed. The caller must close
+// the pipe once done with it.
+func (c *Cmd) BufferedStdoutPipe(memoryLimit int) (io.ReadCloser, error) {
+	if c.Stdout != nil {
+		return nil, errors.New("dockerexec: Stdout already set")
+	}
+	if len(c.ContainerID) != 0 {
+		return nil, errors.New("dockerexec: BufferedStdoutPipe after container started")
+	}
+	b := newSpillBuffer(memoryLimit)
+	c.Stdout = b
+	c.closeAfterOutput = append(c.closeAfterOutput, spillBufferWriteCloser{b})
+	return b, nil
+}
+
+// BufferedStderrPipe is like StderrPipe, but the output of the container is buffered, see
+// BufferedStdoutPipe.
+func (c *Cmd) BufferedStderrPipe(memoryLimit int) (io.ReadCloser, error) {
+	if c.Stderr != nil {
+		return nil, errors.New("dockerexec: Stderr already set")
+	}
+	if len(c.ContainerID) != 0 {
+		return nil, errors.New("dockerexec: BufferedStderrPipe after container started")
+	}
+	b := newSpillBuffer(memoryLimit)
+	c.Stderr = b
+	c.closeAfterOutput = append(c.closeAfterOutput, spillBufferWriteCloser{b})
+	return b, nil
+}
+
+// spillBuffer is an unbounded in-process pipe, which buffers up to memoryLimit bytes in memory and
+// spills the rest to a temporary file. Writes never block, while reads block until data is
+// available or the write side is closed.
+type spillBuffer struct {
+	mu          sync.Mutex
+	cond        *sync.Cond
+	memoryLimit int
+	mem         bytes.Buffer
+	file        *os.File // once created, all later writes go to it, even after it's drained
+	readOff     int64
+	writeOff    int64
+	writeClosed bool
+	readClosed  bool
+	err         error // sticky error from the temporary file
+}
+
+func newSpillBuffer(memoryLimit int) *spillBuffer {
+	b := &spillBuffer{memoryLimit: memoryLimit}
+	b.cond = sync.NewCond(&b.mu)
+	return b
+}
+
+func (b *spillBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.writeClosed {
+		return 0, io.ErrClosedPipe
+	}
+	if b.readClosed {
+		// Nobody is going to read it anyway.
+		return len(p), nil
+	}
+	if b.err != nil {
+		return 0, b.err
+	}
+
+	defer b.cond.Broadcast()
+
+	if b.file == nil && b.mem.Len()+len(p) <= b.memoryLimit {
+		return b.mem.Write(p)
+	}
+
+	if b.file == nil {
+		b.file, b.err = os.CreateTemp("", "dockerexec-")
+		if b.err != nil {
+			return 0, b.err
+		}
+
+		// Move the unread data in memory to the file, so it's all in one place.
+		n, err := b.file.WriteAt(b.mem.Bytes(), 0)
+		b.writeOff = int64(n)
+		if err != nil {
+			b.err = err
+			return 0, err
+		}
+		b.mem.Reset()
+	}
+
+	n, err := b.file.WriteAt(p, b.writeOff)
+	b.writeOff += int64(n)
+	if err != nil {
+		b.err = err
+	}
+	return n, err
+}
+
+func (b *spillBuffer) Read(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for {
+		if b.readClosed {
+			return 0, io.ErrClosedPipe
+		}
+
+		// Data is either in memory or in the file, never both.
+		if b.mem.Len() > 0 {
+			return b.mem.Read(p)
+		}
+
+		if b.file != nil && b.readOff < b.writeOff {
+			if remain := b.writeOff - b.readOff; int64(len(p)) > remain {
+				p = p[:remain]
+			}
+			n, err := b.file.ReadAt(p, b.readOff)
+			b.readOff += int64(n)
+			if err == io.EOF {
+				err = nil
+			}
+			if b.readOff == b.writeOff {
+				// Drained, reclaim the disk space.
+				if err1 := b.file.Truncate(0); err1 == nil {
+					b.readOff, b.writeOff = 0, 0
+				}
+			}
+			return n, err
+		}
+
+		if b.err != nil {
+			return 0, b.err
+		}
+		if b.writeClosed {
+			return 0, io.EOF
+		}
+
+		b.cond.Wait()
+	}
+}
+
+func (b *spillBuffer) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.readClosed {
+		return nil
+	}
+	b.readClosed = true
+	b.mem.Reset()
+	b.cond.Broadcast()
+
+	if b.file != nil {
+		err := b.file.Close()
+		if err1 := os.Remove(b.file.Name()); err == nil {
+			err = err1
+		}
+		return err
+	}
+	return nil
+}
+
+func (b *spillBuffer) closeWrite() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.writeClosed = true
+	b.cond.Broadcast()
+}
+
+// readSeeker returns a seekable reader of the unread data of b, which must not be written to or
+// read anymore. Closing the reader closes b, removing the temporary file, if any.
+func (b *spillBuffer) readSeeker() (io.ReadSeekCloser, error) {
+	b.mu.Lock()
+	err := b.err
+	mem, file, off, n := b.mem.Bytes(), b.file, b.readOff, b.writeOff-b.readOff
+	b.mu.Unlock()
+
+	if err != nil {
+		b.Close()
+		return nil, err
+	}
+	if file == nil {
+		return nopSeekCloser{bytes.NewReader(mem)}, nil
+	}
+	return spillBufferReader{io.NewSectionReader(file, off, n), b}, nil
+}
+
+type nopSeekCloser struct {
+	io.ReadSeeker
+}
+
+func (nopSeekCloser) Close() error {
+	return nil
+}
+
+// spillBufferReader is the reader returned by spillBuffer.readSeeker.
+type spillBufferReader struct {
+	*io.SectionReader
+	b *spillBuffer
+}
+
+func (r spillBufferReader) Close() error {
+	return r.b.Close()
+}
+
+// spillBufferWriteCloser closes the write side of a spillBuffer.
+type spillBufferWriteCloser struct {
+	b *spillBuffer
+}
+
+func (w spillBufferWriteCloser) Close() error {
+	w.b.closeWrite()
+	return nil
+}
@@ -0,0 +1,46 @@
+package dockerexec_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestBufferedPipes(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "sh", "-c", "seq 1 10000; echo stderr >&2")
+
+	stdout, err := cmd.BufferedStdoutPipe(1024)
+	require.NoError(t, err)
+	defer stdout.Close()
+	stderr, err := cmd.BufferedStderrPipe(1024)
+	require.NoError(t, err)
+	defer stderr.Close()
+
+	// Safe to call Run, unlike with StdoutPipe.
+	err = cmd.Run()
+	require.NoError(t, err)
+
+	output, err := io.ReadAll(stdout)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	require.Len(t, lines, 10000)
+	assert.Equal(t, "1", lines[0])
+	assert.Equal(t, "10000", lines[9999])
+
+	output, err = io.ReadAll(stderr)
+	require.NoError(t, err)
+	assert.Equal(t, "stderr\n", string(output))
+}
+
+func TestBufferedStdoutPipeAlreadySet(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "true")
+	cmd.Stdout = io.Discard
+
+	_, err := cmd.BufferedStdoutPipe(1024)
+	assert.EqualError(t, err, "dockerexec: Stdout already set")
+}
@@ -0,0 +1,202 @@
+package dockerexec
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// CachedResult is the recorded output of a successful run, see ResultCache.
+type CachedResult struct {
+	// Output is the output of the container, in the order it was written.
+	Output []Frame
+}
+
+// ResultCache stores the results of successful runs by a key identifying the run, see Cmd.Cache.
+// It must be safe for concurrent use.
+type ResultCache interface {
+	Get(key string) (*CachedResult, bool)
+	Put(key string, result *CachedResult)
+}
+
+// MemoryResultCache is a ResultCache keeping results in memory. The zero value is an empty cache
+// ready to use.
+type MemoryResultCache struct {
+	mu      sync.Mutex
+	results map[string]*CachedResult
+}
+
+// Get returns the result stored under key, if any.
+func (c *MemoryResultCache) Get(key string) (*CachedResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	result, ok := c.results[key]
+	return result, ok
+}
+
+// Put stores result under key.
+func (c *MemoryResultCache) Put(key string, result *CachedResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.results == nil {
+		c.results = make(map[string]*CachedResult)
+	}
+	c.results[key] = result
+}
+
+// cacheState is the state of a Cmd using Cache.
+type cacheState struct {
+	key    string
+	hit    *CachedResult
+	mu     sync.Mutex
+	output []Frame // recorded while running, on a miss
+}
+
+// captureWriter records the output written to it as frames of stream.
+type captureWriter struct {
+	state  *cacheState
+	stream Stream
+}
+
+func (w *captureWriter) Write(p []byte) (int, error) {
+	w.state.mu.Lock()
+	defer w.state.mu.Unlock()
+	w.state.output = append(w.state.output, Frame{Stream: w.stream, Payload: bytes.Clone(p)})
+	return len(p), nil
+}
+
+// runKey returns the cache key of the run, hashing the ID of the image, the configuration of the
+// container, as hashed by configHash, the settings of c applied to it by prepare, and stdin, which
+// is read fully and replaced.
+func (c *Cmd) runKey(ctx context.Context) (string, error) {
+	if _, ok := c.Stdin.(*io.PipeReader); ok {
+		return "", errors.New("dockerexec: can't use Cache with a pipe as Stdin, such as from StdinPipe, as it's read fully by Start")
+	}
+
+	img, _, err := c.cli.ImageInspectWithRaw(ctx, c.Config.Image)
+	if err != nil {
+		return "", wrapError("inspect image", err)
+	}
+
+	var stdinHash string
+	if c.Stdin != nil {
+		h := sha256.New()
+		var stdin bytes.Buffer
+		if _, err := io.Copy(io.MultiWriter(h, &stdin), c.Stdin); err != nil {
+			return "", fmt.Errorf("dockerexec: read stdin for cache key: %w", err)
+		}
+		c.Stdin = &stdin
+		stdinHash = hex.EncodeToString(h.Sum(nil))
+	}
+
+	b, err := json.Marshal(struct {
+		ImageID      string
+		Config       json.RawMessage
+		Hostname     string
+		PidsLimit    int64
+		ShmSize      string
+		EnsurePasswd bool
+		Stdin        string
+	}{
+		ImageID:      img.ID,
+		Config:       c.configJSON(),
+		Hostname:     c.Hostname,
+		PidsLimit:    c.PidsLimit,
+		ShmSize:      c.ShmSize,
+		EnsurePasswd: c.EnsurePasswd,
+		Stdin:        stdinHash,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// checkCache looks the run up in Cache. On a hit, it replays the cached output and returns true,
+// and otherwise the output is recorded by outputWriters.
+func (c *Cmd) checkCache(ctx context.Context) (bool, error) {
+	key, err := c.runKey(ctx)
+	if err != nil {
+		return false, err
+	}
+	c.cache = &cacheState{key: key}
+
+	if result, ok := c.Cache.Get(key); ok {
+		c.cache.hit = result
+		c.replayCached(result)
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// replayCached writes the cached output to Stdout and Stderr, as if the container wrote it.
+func (c *Cmd) replayCached(result *CachedResult) {
+	stdout, stderr := c.outputWriters()
+	c.closeDescriptors(c.closeAfterStdin)
+
+	c.goroutine = append(c.goroutine, func() error {
+		defer c.closeDescriptors(c.closeAfterOutput)
+		for _, frame := range result.Output {
+			w := stdout
+			if frame.Stream == StreamStderr {
+				w = stderr
+			}
+			if w == nil {
+				continue
+			}
+			if _, err := w.Write(frame.Payload); err != nil {
+				return &CopyError{Stream: frame.Stream, Err: err, write: true}
+			}
+		}
+		return nil
+	})
+	c.startGoroutines()
+}
+
+// waitCached is Wait for a run whose result was found in Cache.
+func (c *Cmd) waitCached() error {
+	if c.finished {
+		return errors.New("dockerexec: Wait was already called")
+	}
+	c.finished = true
+	defer c.finishLifecycle()
+
+	var copyError error
+	for range c.goroutine {
+		if err := <-c.errch; err != nil && copyError == nil {
+			copyError = err
+		}
+	}
+	c.closeDescriptors(c.closeAfterWait)
+	c.sumOutputHashes()
+
+	c.StatusCode = 0
+	c.audit(copyError)
+	return copyError
+}
+
+// storeCached stores the result of the run in Cache if it succeeded.
+func (c *Cmd) storeCached(err error) {
+	if c.cache == nil || c.cache.hit != nil || err != nil {
+		return
+	}
+
+	c.cache.mu.Lock()
+	output := c.cache.output
+	c.cache.mu.Unlock()
+	c.Cache.Put(c.cache.key, &CachedResult{Output: output})
+}
+
+// Cached reports whether the run was skipped as its result was found in Cache.
+func (c *Cmd) Cached() bool {
+	return c.cache != nil && c.cache.hit != nil
+}
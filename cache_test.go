@@ -0,0 +1,105 @@
+package dockerexec_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestCache(t *testing.T) {
+	var cache dockerexec.MemoryResultCache
+
+	run := func(stdin string) (*dockerexec.Cmd, string) {
+		cmd := dockerexec.Command(dockerClient, testImage, "sh", "-c", "cat; echo err >&2; date +%s%N")
+		cmd.Cache = &cache
+		cmd.Stdin = strings.NewReader(stdin)
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err)
+		return cmd, string(output)
+	}
+
+	first, output1 := run("hello\n")
+	assert.False(t, first.Cached())
+	assert.NotEmpty(t, first.ContainerID)
+
+	second, output2 := run("hello\n")
+	assert.True(t, second.Cached())
+	assert.Empty(t, second.ContainerID)
+	assert.Equal(t, output1, output2)
+	assert.EqualValues(t, 0, second.StatusCode)
+
+	third, output3 := run("bye\n")
+	assert.False(t, third.Cached())
+	assert.True(t, strings.HasPrefix(output3, "bye\nerr\n"), output3)
+}
+
+func TestCacheFailureNotCached(t *testing.T) {
+	var cache dockerexec.MemoryResultCache
+
+	for i := 0; i < 2; i++ {
+		cmd := dockerexec.Command(dockerClient, testImage, "false")
+		cmd.Cache = &cache
+		err := cmd.Run()
+		assert.Error(t, err)
+		assert.False(t, cmd.Cached())
+	}
+}
+
+func TestCacheKeyHostConfig(t *testing.T) {
+	var cache dockerexec.MemoryResultCache
+
+	run := func(networkMode string) *dockerexec.Cmd {
+		cmd := dockerexec.Command(dockerClient, testImage, "true")
+		cmd.Cache = &cache
+		cmd.HostConfig.NetworkMode = container.NetworkMode(networkMode)
+		require.NoError(t, cmd.Run())
+		return cmd
+	}
+
+	assert.False(t, run("none").Cached())
+	assert.True(t, run("none").Cached())
+	assert.False(t, run("bridge").Cached())
+
+	cmd := dockerexec.Command(dockerClient, testImage, "true")
+	cmd.Cache = &cache
+	cmd.HostConfig.NetworkMode = "none"
+	cmd.PidsLimit = 100
+	require.NoError(t, cmd.Run())
+	assert.False(t, cmd.Cached())
+}
+
+func TestCacheStdinPipe(t *testing.T) {
+	var cache dockerexec.MemoryResultCache
+
+	cmd := dockerexec.Command(dockerClient, testImage, "cat")
+	cmd.Cache = &cache
+	_, err := cmd.StdinPipe()
+	require.NoError(t, err)
+	assert.ErrorContains(t, cmd.Start(), "can't use Cache with a pipe as Stdin")
+}
+
+func TestCacheAudit(t *testing.T) {
+	var cache dockerexec.MemoryResultCache
+	var events []*dockerexec.AuditEvent
+
+	for i := 0; i < 2; i++ {
+		cmd := dockerexec.Command(dockerClient, testImage, "true")
+		cmd.Cache = &cache
+		cmd.Audit = dockerexec.AuditFunc(func(event *dockerexec.AuditEvent) {
+			events = append(events, event)
+		})
+		require.NoError(t, cmd.Run())
+	}
+
+	require.Len(t, events, 2)
+	assert.False(t, events[0].Cached)
+	assert.NotEmpty(t, events[0].ContainerID)
+	assert.True(t, events[1].Cached)
+	assert.Empty(t, events[1].ContainerID)
+	assert.Equal(t, int64(0), events[1].StatusCode)
+}
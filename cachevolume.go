@@ -0,0 +1,119 @@
+package dockerexec
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"time"
+
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/errdefs"
+)
+
+// cacheVolumePrefix prefixes the names of cache volumes, see WithCacheVolume.
+const cacheVolumePrefix = "dockerexec-cache-"
+
+// validCacheName matches the names allowed by WithCacheVolume, so that the volume name is valid.
+var validCacheName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// WithCacheVolume mounts the named volume of the cache called name at the absolute containerPath,
+// such as /root/.cache or /app/node_modules, creating it on first use, so that it persists across
+// runs, e.g. to keep package manager caches warm. Concurrent runs share the same volume, so the
+// tool using it should tolerate that. It's unrelated to Cache, which caches the results of runs.
+//
+// Cache volumes are named "dockerexec-cache-<name>", and labeled with LabelManaged and
+// LabelCacheVolume, see ListCacheVolumes and PruneCacheVolumes. An invalid name or a relative
+// containerPath sets Err, so that Start fails. It returns c to allow chaining.
+func (c *Cmd) WithCacheVolume(name, containerPath string) *Cmd {
+	if !validCacheName.MatchString(name) {
+		c.setErr(fmt.Errorf("dockerexec: invalid cache name %q", name))
+		return c
+	}
+	if !path.IsAbs(containerPath) {
+		c.setErr(fmt.Errorf("dockerexec: cache volume path %q is not absolute", containerPath))
+		return c
+	}
+
+	hc := c.hostConfig()
+	hc.Mounts = append(hc.Mounts, mount.Mount{
+		Type:   mount.TypeVolume,
+		Source: cacheVolumePrefix + name,
+		Target: containerPath,
+		// Only applied by the daemon when it creates the volume
+		VolumeOptions: &mount.VolumeOptions{
+			Labels: map[string]string{
+				LabelManaged:     "true",
+				LabelCacheVolume: name,
+			},
+		},
+	})
+	return c
+}
+
+// CacheVolume is a cache volume created by Cmd.WithCacheVolume, see ListCacheVolumes.
+type CacheVolume struct {
+	// Name is the name of the cache, and Volume the name of its volume.
+	Name   string
+	Volume string
+
+	// CreatedAt is when the volume was created, zero if unknown.
+	CreatedAt time.Time
+}
+
+// ListCacheVolumes lists the cache volumes created by Cmd.WithCacheVolume.
+func ListCacheVolumes(ctx context.Context, cli VolumeClient) ([]CacheVolume, error) {
+	resp, err := cli.VolumeList(ctx, volume.ListOptions{
+		Filters: filters.NewArgs(filters.Arg("label", LabelCacheVolume)),
+	})
+	if err != nil {
+		return nil, wrapError("list volumes", err)
+	}
+
+	caches := make([]CacheVolume, 0, len(resp.Volumes))
+	for _, v := range resp.Volumes {
+		if v == nil {
+			continue
+		}
+		createdAt, _ := time.Parse(time.RFC3339, v.CreatedAt)
+		caches = append(caches, CacheVolume{
+			Name:      v.Labels[LabelCacheVolume],
+			Volume:    v.Name,
+			CreatedAt: createdAt,
+		})
+	}
+	return caches, nil
+}
+
+// RemoveCacheVolume removes the volume of the cache called name. It fails if the volume is in use
+// by a container.
+func RemoveCacheVolume(ctx context.Context, cli VolumeClient, name string) error {
+	return wrapError("remove volume", cli.VolumeRemove(ctx, cacheVolumePrefix+name, false))
+}
+
+// PruneCacheVolumes removes the cache volumes created by Cmd.WithCacheVolume more than olderThan
+// ago, or all of them if olderThan is zero, returning the names of the removed caches. Volumes in
+// use by a container, and, unless olderThan is zero, of unknown age, are skipped.
+func PruneCacheVolumes(ctx context.Context, cli VolumeClient, olderThan time.Duration) ([]string, error) {
+	caches, err := ListCacheVolumes(ctx, cli)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []string
+	for _, cache := range caches {
+		if olderThan > 0 && (cache.CreatedAt.IsZero() || time.Since(cache.CreatedAt) < olderThan) {
+			continue
+		}
+		err := cli.VolumeRemove(ctx, cache.Volume, false)
+		if errdefs.IsConflict(err) {
+			continue
+		} else if err != nil && !errdefs.IsNotFound(err) {
+			return removed, wrapError("remove volume", err)
+		}
+		removed = append(removed, cache.Name)
+	}
+	return removed, nil
+}
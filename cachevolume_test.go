@@ -0,0 +1,65 @@
+package dockerexec_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestWithCacheVolume(t *testing.T) {
+	const name = "test-with-cache"
+	defer func() {
+		_ = dockerexec.RemoveCacheVolume(context.Background(), dockerClient, name)
+	}()
+
+	cmd := dockerexec.Command(dockerClient, testImage, "sh", "-c", "echo cached > /cache/file").
+		WithCacheVolume(name, "/cache")
+	require.NoError(t, cmd.Run())
+
+	cmd = dockerexec.Command(dockerClient, testImage, "cat", "/cache/file").WithCacheVolume(name, "/cache")
+	output, err := cmd.Output()
+	require.NoError(t, err)
+	assert.Equal(t, "cached\n", string(output))
+
+	caches, err := dockerexec.ListCacheVolumes(context.Background(), dockerClient)
+	require.NoError(t, err)
+	var found *dockerexec.CacheVolume
+	for i := range caches {
+		if caches[i].Name == name {
+			found = &caches[i]
+		}
+	}
+	require.NotNil(t, found)
+	assert.Equal(t, "dockerexec-cache-"+name, found.Volume)
+	assert.False(t, found.CreatedAt.IsZero())
+}
+
+func TestPruneCacheVolumes(t *testing.T) {
+	const name = "test-prune-cache"
+	cmd := dockerexec.Command(dockerClient, testImage, "true").WithCacheVolume(name, "/cache")
+	require.NoError(t, cmd.Run())
+
+	removed, err := dockerexec.PruneCacheVolumes(context.Background(), dockerClient, 0)
+	require.NoError(t, err)
+	assert.Contains(t, removed, name)
+
+	caches, err := dockerexec.ListCacheVolumes(context.Background(), dockerClient)
+	require.NoError(t, err)
+	for _, cache := range caches {
+		assert.NotEqual(t, name, cache.Name)
+	}
+}
+
+func TestWithCacheVolumeInvalidName(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "true").WithCacheVolume("../etc", "/cache")
+	assert.Error(t, cmd.Err)
+}
+
+func TestWithCacheVolumeRelativePath(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "true").WithCacheVolume("test", "node_modules")
+	assert.Error(t, cmd.Err)
+}
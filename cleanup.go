@@ -0,0 +1,95 @@
+package dockerexec
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+)
+
+// RemoveSession force-removes all containers created by this process, that is, all containers
+// labeled with the SessionID of the process, along with their anonymous volumes. It returns the
+// IDs of the removed containers.
+//
+// RemoveSession attempts to remove all matching containers even if some fail, in which case the
+// returned error joins all failures.
+func RemoveSession(ctx context.Context, cli Client) ([]string, error) {
+	containers, err := cli.ContainerList(ctx, container.ListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("label", LabelSession+"="+sessionID)),
+	})
+	if err != nil {
+		return nil, wrapError("list containers", err)
+	}
+
+	var removed []string
+	var errs []error
+	for _, cont := range containers {
+		err := cli.ContainerRemove(ctx, cont.ID, container.RemoveOptions{
+			RemoveVolumes: true,
+			Force:         true,
+		})
+		if err != nil {
+			if !client.IsErrNotFound(err) {
+				errs = append(errs, wrapError("remove container", err))
+			}
+			continue
+		}
+		removed = append(removed, cont.ID)
+	}
+
+	return removed, errors.Join(errs...)
+}
+
+// SignalCleanupTimeout bounds how long the handler installed by CleanupOnSignal spends removing
+// containers before exiting.
+var SignalCleanupTimeout = 10 * time.Second
+
+// CleanupOnSignal installs a handler for SIGINT and SIGTERM, or the given signals if any, that
+// removes all containers created by this process using RemoveSession and then exits the process
+// with status 128+signal (130 for SIGINT), so that interrupting a program or a test run doesn't
+// leave containers behind.
+//
+// Since the handler exits the process, deferred functions do not run. Call the returned function
+// to uninstall the handler.
+func CleanupOnSignal(cli Client, signals ...os.Signal) (stop func()) {
+	if len(signals) == 0 {
+		signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+
+	ch := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(ch, signals...)
+
+	go func() {
+		select {
+		case sig := <-ch:
+			ctx, cancel := context.WithTimeout(context.Background(), SignalCleanupTimeout)
+			// Errors are ignored, there's nowhere to report them to
+			_, _ = RemoveSession(ctx, cli)
+			cancel()
+
+			code := 1
+			if sig, ok := sig.(syscall.Signal); ok {
+				code = 128 + int(sig)
+			}
+			os.Exit(code)
+		case <-done:
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package dockerexec_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestRemoveSession(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "sleep", "120")
+	err := cmd.Start()
+	require.NoError(t, err)
+
+	removed, err := dockerexec.RemoveSession(context.Background(), dockerClient)
+	require.NoError(t, err)
+	assert.Contains(t, removed, cmd.ContainerID)
+
+	err = cmd.Wait()
+	assert.Error(t, err)
+}
+
+func TestCleanupOnSignalStop(t *testing.T) {
+	stop := dockerexec.CleanupOnSignal(dockerClient)
+	stop()
+	// Stopping twice is harmless
+	stop()
+}
@@ -0,0 +1,158 @@
+package dockerexec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/registry"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// Client is the subset of client.APIClient used by this package, other than by Dispatcher and
+// SwarmCmd, which is satisfied by *client.Client. It's narrow enough to be conveniently mocked
+// or wrapped, e.g. by embedding a Client and overriding some of its methods.
+//
+// Optional features use narrower interfaces, such as DiffClient and StatsClient, which a Client
+// may also implement. Using such a feature with a Client that doesn't implement its interface
+// fails with an error wrapping errors.ErrUnsupported.
+type Client interface {
+	ClientVersion() string
+	DaemonHost() string
+	Ping(ctx context.Context) (types.Ping, error)
+
+	ContainerAttach(ctx context.Context, container string, options container.AttachOptions) (types.HijackedResponse, error)
+	ContainerCreate(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, platform *ocispec.Platform, containerName string) (container.CreateResponse, error)
+	ContainerExecAttach(ctx context.Context, execID string, options container.ExecAttachOptions) (types.HijackedResponse, error)
+	ContainerExecCreate(ctx context.Context, container string, options container.ExecOptions) (types.IDResponse, error)
+	ContainerExecInspect(ctx context.Context, execID string) (container.ExecInspect, error)
+	ContainerInspect(ctx context.Context, container string) (types.ContainerJSON, error)
+	ContainerKill(ctx context.Context, container, signal string) error
+	ContainerList(ctx context.Context, options container.ListOptions) ([]types.Container, error)
+	ContainerLogs(ctx context.Context, container string, options container.LogsOptions) (io.ReadCloser, error)
+	ContainerRemove(ctx context.Context, container string, options container.RemoveOptions) error
+	ContainerResize(ctx context.Context, container string, options container.ResizeOptions) error
+	ContainerStart(ctx context.Context, container string, options container.StartOptions) error
+	ContainerStop(ctx context.Context, container string, options container.StopOptions) error
+	ContainerWait(ctx context.Context, container string, condition container.WaitCondition) (<-chan container.WaitResponse, <-chan error)
+	CopyFromContainer(ctx context.Context, container, srcPath string) (io.ReadCloser, container.PathStat, error)
+	CopyToContainer(ctx context.Context, container, path string, content io.Reader, options container.CopyToContainerOptions) error
+
+	ImageInspectWithRaw(ctx context.Context, image string) (types.ImageInspect, []byte, error)
+	ImagePull(ctx context.Context, ref string, options image.PullOptions) (io.ReadCloser, error)
+	ImageTag(ctx context.Context, image, ref string) error
+}
+
+// DiffClient is implemented by a Client supporting the filesystem changes of a container, used by
+// DebugOnFailure and PostMortem.
+type DiffClient interface {
+	ContainerDiff(ctx context.Context, container string) ([]container.FilesystemChange, error)
+}
+
+// StatsClient is implemented by a Client supporting container stats, used by Stats, CollectUsage
+// and PostMortem.
+type StatsClient interface {
+	ContainerStats(ctx context.Context, container string, stream bool) (container.StatsResponseReader, error)
+	ContainerStatsOneShot(ctx context.Context, container string) (container.StatsResponseReader, error)
+}
+
+// DistributionClient is implemented by a Client supporting inspecting images in their registry,
+// used by Validate for images that aren't available locally.
+type DistributionClient interface {
+	DistributionInspect(ctx context.Context, image, encodedRegistryAuth string) (registry.DistributionInspect, error)
+}
+
+// VolumeClient is the subset of client.APIClient used by the cache volume functions, such as
+// ListCacheVolumes.
+type VolumeClient interface {
+	VolumeList(ctx context.Context, options volume.ListOptions) (volume.ListResponse, error)
+	VolumeRemove(ctx context.Context, volumeID string, force bool) error
+}
+
+// Make sure *client.Client satisfies Client and the optional interfaces.
+var (
+	_ Client             = (*client.Client)(nil)
+	_ DiffClient         = (*client.Client)(nil)
+	_ StatsClient        = (*client.Client)(nil)
+	_ DistributionClient = (*client.Client)(nil)
+	_ VolumeClient       = (*client.Client)(nil)
+)
+
+// errUnsupported returns the error for using method with a client not implementing it.
+func errUnsupported(method string) error {
+	return fmt.Errorf("dockerexec: client does not support %s: %w", method, errors.ErrUnsupported)
+}
+
+func containerDiff(ctx context.Context, cli Client, id string) ([]container.FilesystemChange, error) {
+	dc, ok := cli.(DiffClient)
+	if !ok {
+		return nil, errUnsupported("ContainerDiff")
+	}
+	return dc.ContainerDiff(ctx, id)
+}
+
+func containerStats(ctx context.Context, cli Client, id string, stream bool) (container.StatsResponseReader, error) {
+	sc, ok := cli.(StatsClient)
+	if !ok {
+		return container.StatsResponseReader{}, errUnsupported("ContainerStats")
+	}
+	return sc.ContainerStats(ctx, id, stream)
+}
+
+func containerStatsOneShot(ctx context.Context, cli Client, id string) (container.StatsResponseReader, error) {
+	sc, ok := cli.(StatsClient)
+	if !ok {
+		return container.StatsResponseReader{}, errUnsupported("ContainerStatsOneShot")
+	}
+	return sc.ContainerStatsOneShot(ctx, id)
+}
+
+func distributionInspect(ctx context.Context, cli Client, image, encodedRegistryAuth string) (registry.DistributionInspect, error) {
+	dc, ok := cli.(DistributionClient)
+	if !ok {
+		return registry.DistributionInspect{}, errUnsupported("DistributionInspect")
+	}
+	return dc.DistributionInspect(ctx, image, encodedRegistryAuth)
+}
+
+var (
+	defaultClientOnce sync.Once
+	defaultClient     client.APIClient
+	defaultClientErr  error
+)
+
+// DefaultClient returns a client configured from the environment (see client.FromEnv), with API
+// version negotiation, creating it on first use. It is the client used by a Cmd created with a
+// nil client, which is convenient for small scripts and examples.
+func DefaultClient() (client.APIClient, error) {
+	defaultClientOnce.Do(func() {
+		cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+		if err != nil {
+			defaultClientErr = wrapError("create client", err)
+			return
+		}
+		defaultClient = cli
+	})
+	return defaultClient, defaultClientErr
+}
+
+// ensureClient sets the client of c to DefaultClient if it was created with a nil client.
+func (c *Cmd) ensureClient() error {
+	if c.cli != nil {
+		return nil
+	}
+	cli, err := DefaultClient()
+	if err != nil {
+		return err
+	}
+	c.cli = cli
+	return nil
+}
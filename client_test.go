@@ -0,0 +1,89 @@
+package dockerexec_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestNilClient(t *testing.T) {
+	cmd := dockerexec.Command(nil, testImage, "echo", "hello")
+	output, err := cmd.Output()
+	require.NoError(t, err)
+	assert.Equal(t, "hello\n", string(output))
+}
+
+func TestNilClientValidate(t *testing.T) {
+	cmd := dockerexec.Command(nil, testImage, "true")
+	assert.NoError(t, cmd.Validate(context.Background()))
+}
+
+func TestNilClientStandbyPool(t *testing.T) {
+	pool := dockerexec.NewStandbyPool(1, func() *dockerexec.Cmd {
+		return dockerexec.Command(nil, testImage, "echo", "hello")
+	})
+	defer func() {
+		assert.NoError(t, pool.Close())
+	}()
+
+	require.Eventually(t, func() bool {
+		return pool.Ready() == 1
+	}, 30*time.Second, 50*time.Millisecond)
+
+	output, err := pool.Get().Output()
+	require.NoError(t, err)
+	assert.Equal(t, "hello\n", string(output))
+}
+
+func TestDefaultClient(t *testing.T) {
+	cli1, err := dockerexec.DefaultClient()
+	require.NoError(t, err)
+	cli2, err := dockerexec.DefaultClient()
+	require.NoError(t, err)
+	assert.Same(t, cli1, cli2)
+}
+
+type countingClient struct {
+	dockerexec.Client
+	creates int
+}
+
+func (c *countingClient) ContainerCreate(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, platform *ocispec.Platform, containerName string) (container.CreateResponse, error) {
+	c.creates++
+	return c.Client.ContainerCreate(ctx, config, hostConfig, networkingConfig, platform, containerName)
+}
+
+func TestWrappedClient(t *testing.T) {
+	cli := &countingClient{Client: dockerClient}
+	cmd := dockerexec.Command(cli, testImage, "true")
+	err := cmd.Run()
+	require.NoError(t, err)
+	assert.Equal(t, 1, cli.creates)
+}
+
+// coreClient hides the optional interfaces implemented by the Client it embeds.
+type coreClient struct {
+	dockerexec.Client
+}
+
+func TestClientUnsupported(t *testing.T) {
+	cmd := dockerexec.Command(coreClient{dockerClient}, testImage, "sleep", "10")
+	require.NoError(t, cmd.Start())
+
+	_, err := cmd.Stats()
+	assert.ErrorIs(t, err, errors.ErrUnsupported)
+	assert.ErrorContains(t, err, "client does not support ContainerStats")
+
+	require.NoError(t, cmd.Kill("SIGKILL"))
+	_ = cmd.Wait()
+}
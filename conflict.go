@@ -0,0 +1,199 @@
+package dockerexec
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// NameConflictStrategy determines what Start does when Cmd.ContainerName is set and a container
+// with that name already exists, see Cmd.OnNameConflict.
+type NameConflictStrategy int
+
+const (
+	// NameConflictFail makes Start fail with a *NameConflictError.
+	NameConflictFail NameConflictStrategy = iota
+
+	// NameConflictReuse adopts the existing container instead of creating a new one, attaching
+	// to it and starting it if it's not running. The configuration of the Cmd is ignored in favor
+	// of that of the existing container.
+	NameConflictReuse
+
+	// NameConflictReplace force-removes the existing container, along with its anonymous volumes,
+	// and creates a new one in its place.
+	NameConflictReplace
+
+	// NameConflictIdempotent joins the existing container if it was created with the same
+	// configuration, as recorded in its LabelConfigHash label, instead of creating a new one, and
+	// fails with a *NameConflictError otherwise. A joined container isn't started or attached to,
+	// Wait just waits for it to exit, or returns its outcome if it already exited, and its output
+	// isn't captured.
+	//
+	// This makes runs idempotent, e.g. at-most-once jobs driven by several replicas using the same
+	// ContainerName. Set HostConfig.AutoRemove to false for it to also cover runs that come after
+	// the container exited.
+	NameConflictIdempotent
+)
+
+func (s NameConflictStrategy) String() string {
+	switch s {
+	case NameConflictFail:
+		return "fail"
+	case NameConflictReuse:
+		return "reuse"
+	case NameConflictReplace:
+		return "replace"
+	case NameConflictIdempotent:
+		return "idempotent"
+	default:
+		return fmt.Sprintf("NameConflictStrategy(%d)", int(s))
+	}
+}
+
+// NameConflictError is returned by Start when Cmd.ContainerName is already in use by another
+// container and Cmd.OnNameConflict is NameConflictFail, or NameConflictIdempotent and the existing
+// container was created with a different configuration.
+type NameConflictError struct {
+	Name        string
+	ContainerID string // The ID of the existing container, if known
+	Err         error  // The error returned by the daemon
+
+	// ConfigMismatch is set when Cmd.OnNameConflict is NameConflictIdempotent and the existing
+	// container was created with a different configuration.
+	ConfigMismatch bool
+}
+
+func (e *NameConflictError) Error() string {
+	s := fmt.Sprintf("dockerexec: container name %q is already in use", e.Name)
+	if e.ContainerID != "" {
+		s += fmt.Sprintf(" by container %.12s", e.ContainerID)
+	}
+	if e.ConfigMismatch {
+		s += " with a different configuration"
+	}
+	return s
+}
+
+func (e *NameConflictError) Unwrap() error {
+	return e.Err
+}
+
+// resolveNameConflict handles createErr, a conflict returned by ContainerCreate, according to
+// OnNameConflict. reused is true if the existing container was adopted.
+func (c *Cmd) resolveNameConflict(ctx context.Context, platform *ocispec.Platform, createErr error) (cont container.CreateResponse, reused bool, err error) {
+	// The conflict might also be about something other than the name, in which case the lookup
+	// fails or finds nothing and the original error is returned
+	inspect, err := c.cli.ContainerInspect(ctx, c.ContainerName)
+	if err != nil {
+		return cont, false, wrapError("create container", createErr)
+	}
+
+	switch c.OnNameConflict {
+	case NameConflictReuse:
+		if inspect.Config != nil {
+			c.Config = inspect.Config
+		}
+		if inspect.HostConfig != nil {
+			c.HostConfig = inspect.HostConfig
+		}
+		return container.CreateResponse{ID: inspect.ID}, true, nil
+
+	case NameConflictReplace:
+		err := c.cli.ContainerRemove(ctx, inspect.ID, container.RemoveOptions{
+			RemoveVolumes: true,
+			Force:         true,
+		})
+		if err != nil {
+			return cont, false, wrapError("remove conflicting container", err)
+		}
+
+		cont, err = c.cli.ContainerCreate(ctx, c.Config, c.HostConfig, c.Networkingconfig, platform, c.ContainerName)
+		if err != nil {
+			return cont, false, wrapError("create container", err)
+		}
+		return cont, false, nil
+
+	case NameConflictIdempotent:
+		if inspect.Config == nil || inspect.Config.Labels[LabelConfigHash] != c.Config.Labels[LabelConfigHash] {
+			return cont, false, &NameConflictError{
+				Name:           c.ContainerName,
+				ContainerID:    inspect.ID,
+				Err:            createErr,
+				ConfigMismatch: true,
+			}
+		}
+
+		c.Config = inspect.Config
+		if inspect.HostConfig != nil {
+			c.HostConfig = inspect.HostConfig
+		}
+		c.joinState = "unknown"
+		if inspect.State != nil {
+			c.joinState = inspect.State.Status
+		}
+		return container.CreateResponse{ID: inspect.ID}, true, nil
+
+	default:
+		return cont, false, &NameConflictError{
+			Name:        c.ContainerName,
+			ContainerID: inspect.ID,
+			Err:         createErr,
+		}
+	}
+}
+
+// configHash returns a hash of the configuration of the container for LabelConfigHash, ignoring
+// the labels set by this package, which vary between runs.
+func (c *Cmd) configHash() string {
+	sum := sha256.Sum256(c.configJSON())
+	return hex.EncodeToString(sum[:])
+}
+
+// configJSON returns the configuration of the container as JSON, without the labels managed by
+// this package, which is stable for identical configurations.
+func (c *Cmd) configJSON() []byte {
+	config := *c.Config
+	config.Labels = make(map[string]string, len(c.Config.Labels))
+	for k, v := range c.Config.Labels {
+		if k != LabelManaged && !strings.HasPrefix(k, LabelManaged+".") {
+			config.Labels[k] = v
+		}
+	}
+
+	// Marshaling these can't fail, and map keys are sorted, making the result stable
+	b, _ := json.Marshal(struct {
+		Config           *container.Config
+		HostConfig       *container.HostConfig
+		NetworkingConfig *network.NetworkingConfig
+		Platform         *ocispec.Platform
+	}{&config, c.HostConfig, c.Networkingconfig, c.Platform})
+	return b
+}
+
+// join makes the Cmd wait for the existing container id, joined by NameConflictIdempotent,
+// instead of starting it.
+func (c *Cmd) join(ctx context.Context, id string) {
+	// A created container is presumably about to be started by whoever created it, while waiting
+	// for any other container to stop returns immediately if it already exited
+	condition := container.WaitConditionNotRunning
+	if c.joinState == "created" {
+		condition = container.WaitConditionNextExit
+	}
+	c.waitCh, c.waitErrCh = c.cli.ContainerWait(ctx, id, condition)
+
+	// There's no I/O to copy
+	c.closeDescriptors(c.closeAfterStdin)
+	c.closeDescriptors(c.closeAfterOutput)
+
+	c.ContainerID = id
+	c.setStartedState()
+	c.transition(StateStarted, -1)
+	c.watchingRemoval = c.watchRemoval(id)
+}
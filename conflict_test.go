@@ -0,0 +1,116 @@
+package dockerexec_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func createNamed(t *testing.T, name string, arg ...string) *dockerexec.Cmd {
+	t.Helper()
+
+	cmd := dockerexec.Command(dockerClient, testImage, arg[0], arg[1:]...)
+	cmd.ContainerName = name
+	cmd.HostConfig.AutoRemove = false
+	err := cmd.Run()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = dockerClient.ContainerRemove(context.Background(), name, container.RemoveOptions{Force: true})
+	})
+	return cmd
+}
+
+func TestNameConflictFail(t *testing.T) {
+	existing := createNamed(t, "dockerexec-test-conflict-fail", "true")
+
+	cmd := dockerexec.Command(dockerClient, testImage, "true")
+	cmd.ContainerName = "dockerexec-test-conflict-fail"
+	err := cmd.Run()
+
+	var conflictErr *dockerexec.NameConflictError
+	require.ErrorAs(t, err, &conflictErr)
+	assert.Equal(t, "dockerexec-test-conflict-fail", conflictErr.Name)
+	assert.Equal(t, existing.ContainerID, conflictErr.ContainerID)
+}
+
+func TestNameConflictReuse(t *testing.T) {
+	existing := createNamed(t, "dockerexec-test-conflict-reuse", "echo", "existing")
+
+	cmd := dockerexec.Command(dockerClient, testImage, "echo", "new")
+	cmd.ContainerName = "dockerexec-test-conflict-reuse"
+	cmd.OnNameConflict = dockerexec.NameConflictReuse
+	output, err := cmd.Output()
+	require.NoError(t, err)
+	assert.Equal(t, existing.ContainerID, cmd.ContainerID)
+	assert.Equal(t, "existing\n", string(output))
+}
+
+func TestNameConflictReplace(t *testing.T) {
+	existing := createNamed(t, "dockerexec-test-conflict-replace", "echo", "existing")
+
+	cmd := dockerexec.Command(dockerClient, testImage, "echo", "new")
+	cmd.ContainerName = "dockerexec-test-conflict-replace"
+	cmd.OnNameConflict = dockerexec.NameConflictReplace
+	output, err := cmd.Output()
+	require.NoError(t, err)
+	assert.NotEqual(t, existing.ContainerID, cmd.ContainerID)
+	assert.Equal(t, "new\n", string(output))
+}
+
+func idempotentCmd(name string, arg ...string) *dockerexec.Cmd {
+	cmd := dockerexec.Command(dockerClient, testImage, arg[0], arg[1:]...)
+	cmd.ContainerName = name
+	cmd.OnNameConflict = dockerexec.NameConflictIdempotent
+	cmd.HostConfig.AutoRemove = false
+	return cmd
+}
+
+func TestNameConflictIdempotent(t *testing.T) {
+	t.Cleanup(func() {
+		_ = dockerClient.ContainerRemove(context.Background(), "dockerexec-test-conflict-idempotent", container.RemoveOptions{Force: true})
+	})
+
+	first := idempotentCmd("dockerexec-test-conflict-idempotent", "sh", "-c", "sleep 1; exit 3")
+	err := first.Start()
+	require.NoError(t, err)
+
+	second := idempotentCmd("dockerexec-test-conflict-idempotent", "sh", "-c", "sleep 1; exit 3")
+	err = second.Run()
+	var exitErr *dockerexec.ExitError
+	require.ErrorAs(t, err, &exitErr)
+	assert.EqualValues(t, 3, exitErr.StatusCode)
+	assert.Equal(t, first.ContainerID, second.ContainerID)
+
+	err = first.Wait()
+	require.ErrorAs(t, err, &exitErr)
+	assert.EqualValues(t, 3, exitErr.StatusCode)
+
+	// Joining after the container exited returns its outcome
+	third := idempotentCmd("dockerexec-test-conflict-idempotent", "sh", "-c", "sleep 1; exit 3")
+	err = third.Run()
+	require.ErrorAs(t, err, &exitErr)
+	assert.EqualValues(t, 3, exitErr.StatusCode)
+	assert.Equal(t, first.ContainerID, third.ContainerID)
+}
+
+func TestNameConflictIdempotentMismatch(t *testing.T) {
+	t.Cleanup(func() {
+		_ = dockerClient.ContainerRemove(context.Background(), "dockerexec-test-conflict-mismatch", container.RemoveOptions{Force: true})
+	})
+
+	first := idempotentCmd("dockerexec-test-conflict-mismatch", "true")
+	err := first.Run()
+	require.NoError(t, err)
+
+	second := idempotentCmd("dockerexec-test-conflict-mismatch", "false")
+	err = second.Run()
+	var conflictErr *dockerexec.NameConflictError
+	require.ErrorAs(t, err, &conflictErr)
+	assert.True(t, conflictErr.ConfigMismatch)
+	assert.Equal(t, first.ContainerID, conflictErr.ContainerID)
+}
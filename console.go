@@ -0,0 +1,32 @@
+package dockerexec
+
+import (
+	"github.com/docker/docker/api/types/container"
+	"github.com/moby/term"
+)
+
+// applyConsoleSize sets HostConfig.ConsoleSize to the size of the terminal of Stdout, when using
+// Config.Tty and Stdout is a terminal, unless already set, so that programs that format their
+// output according to the terminal width render correctly from the start.
+func (c *Cmd) applyConsoleSize() {
+	if !c.Config.Tty || c.Stdout == nil {
+		return
+	}
+	if c.HostConfig != nil && c.HostConfig.ConsoleSize != [2]uint{} {
+		return
+	}
+
+	fd, isTerminal := term.GetFdInfo(c.Stdout)
+	if !isTerminal {
+		return
+	}
+	ws, err := term.GetWinsize(fd)
+	if err != nil || ws.Height == 0 || ws.Width == 0 {
+		return
+	}
+
+	if c.HostConfig == nil {
+		c.HostConfig = &container.HostConfig{}
+	}
+	c.HostConfig.ConsoleSize = [2]uint{uint(ws.Height), uint(ws.Width)}
+}
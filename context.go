@@ -0,0 +1,44 @@
+package dockerexec
+
+import (
+	"context"
+	"errors"
+)
+
+// setContext sets the context of a Cmd created by Command, as if it was created by CommandContext.
+func (c *Cmd) setContext(ctx context.Context) error {
+	if ctx == nil {
+		panic("nil Context")
+	}
+	if c.ctx != nil {
+		return errors.New("dockerexec: Cmd already has a context")
+	}
+	c.ctx = ctx
+	return nil
+}
+
+// RunContext is like Run but uses ctx like CommandContext does, killing the container if ctx
+// becomes done before it completes, so that a deadline can be applied at call time. The Cmd must
+// have been created by Command rather than CommandContext.
+func (c *Cmd) RunContext(ctx context.Context) error {
+	if err := c.setContext(ctx); err != nil {
+		return err
+	}
+	return c.Run()
+}
+
+// OutputContext is like Output but uses ctx, see RunContext.
+func (c *Cmd) OutputContext(ctx context.Context) ([]byte, error) {
+	if err := c.setContext(ctx); err != nil {
+		return nil, err
+	}
+	return c.Output()
+}
+
+// CombinedOutputContext is like CombinedOutput but uses ctx, see RunContext.
+func (c *Cmd) CombinedOutputContext(ctx context.Context) ([]byte, error) {
+	if err := c.setContext(ctx); err != nil {
+		return nil, err
+	}
+	return c.CombinedOutput()
+}
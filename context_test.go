@@ -0,0 +1,43 @@
+package dockerexec_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestOutputContext(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "echo", "hello")
+	output, err := cmd.OutputContext(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "hello\n", string(output))
+}
+
+func TestCombinedOutputContext(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "sh", "-c", "echo out; echo err >&2")
+	output, err := cmd.CombinedOutputContext(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "out\nerr\n", string(output))
+}
+
+func TestRunContextTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	cmd := dockerexec.Command(dockerClient, testImage, "sleep", "120")
+	start := time.Now()
+	err := cmd.RunContext(ctx)
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 30*time.Second)
+}
+
+func TestRunContextAlreadySet(t *testing.T) {
+	cmd := dockerexec.CommandContext(context.Background(), dockerClient, testImage, "true")
+	err := cmd.RunContext(context.Background())
+	assert.EqualError(t, err, "dockerexec: Cmd already has a context")
+}
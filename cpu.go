@@ -0,0 +1,86 @@
+package dockerexec
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseCPUs parses a number of CPUs, such as "1.5", "1.5 CPUs", "2 cpu" or "500m" (milli-CPUs),
+// into units of 1e-9 CPUs, as used by HostConfig.Resources.NanoCPUs.
+func ParseCPUs(s string) (int64, error) {
+	str := strings.ToLower(strings.TrimSpace(s))
+
+	scale := 1e9
+	if trimmed, ok := strings.CutSuffix(str, "m"); ok {
+		str = trimmed
+		scale = 1e6
+	} else {
+		for _, suffix := range []string{"cpus", "cpu"} {
+			if trimmed, ok := strings.CutSuffix(str, suffix); ok {
+				str = strings.TrimSpace(trimmed)
+				break
+			}
+		}
+	}
+
+	cpus, err := strconv.ParseFloat(str, 64)
+	if err != nil || cpus <= 0 || math.IsInf(cpus, 0) || math.IsNaN(cpus) {
+		return 0, fmt.Errorf("dockerexec: invalid number of CPUs %q", s)
+	}
+	return int64(math.Round(cpus * scale)), nil
+}
+
+// SetCPUs limits the container to the given number of CPUs, see ParseCPUs, by setting
+// HostConfig.Resources.NanoCPUs. It can't be combined with SetCPUQuota.
+func (c *Cmd) SetCPUs(cpus string) error {
+	nanoCPUs, err := ParseCPUs(cpus)
+	if err != nil {
+		return err
+	}
+	c.hostConfig().NanoCPUs = nanoCPUs
+	return nil
+}
+
+// SetCPUQuota limits the container to quota of CPU time every period, by setting
+// HostConfig.Resources.CPUQuota and CPUPeriod, e.g. 50ms every 100ms is half a CPU. A zero period
+// uses the default of the daemon, which is 100ms. It can't be combined with SetCPUs.
+func (c *Cmd) SetCPUQuota(quota, period time.Duration) {
+	hc := c.hostConfig()
+	hc.CPUQuota = quota.Microseconds()
+	hc.CPUPeriod = period.Microseconds()
+}
+
+// SetCPUSet pins the container to the given CPUs, by setting HostConfig.Resources.CpusetCpus, in
+// the format of a list of CPUs and ranges of CPUs, e.g. "0-3" or "0,2,4-7".
+func (c *Cmd) SetCPUSet(cpus string) error {
+	if err := validateCPUSet(cpus); err != nil {
+		return err
+	}
+	c.hostConfig().CpusetCpus = cpus
+	return nil
+}
+
+// validateCPUSet checks that cpus is a valid list of CPUs and ranges of CPUs.
+func validateCPUSet(cpus string) error {
+	for _, part := range strings.Split(cpus, ",") {
+		low, high, isRange := strings.Cut(part, "-")
+		first, err1 := strconv.ParseUint(low, 10, 32)
+		last, err2 := first, error(nil)
+		if isRange {
+			last, err2 = strconv.ParseUint(high, 10, 32)
+		}
+		if err1 != nil || err2 != nil || last < first {
+			return fmt.Errorf("dockerexec: invalid CPU set %q", cpus)
+		}
+	}
+	return nil
+}
+
+// SetCPUShares sets the relative weight of the container in CPU contention, by setting
+// HostConfig.Resources.CPUShares. The default weight is 1024.
+func (c *Cmd) SetCPUShares(shares int64) {
+	c.hostConfig().CPUShares = shares
+}
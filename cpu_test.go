@@ -0,0 +1,72 @@
+package dockerexec_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestParseCPUs(t *testing.T) {
+	for s, expected := range map[string]int64{
+		"1":         1e9,
+		"1.5":       15e8,
+		"1.5 CPUs":  15e8,
+		"2 cpu":     2e9,
+		"0.25cpus":  25e7,
+		"500m":      5e8,
+		" 1.5 CPUS": 15e8,
+	} {
+		nanoCPUs, err := dockerexec.ParseCPUs(s)
+		if assert.NoError(t, err, s) {
+			assert.Equal(t, expected, nanoCPUs, s)
+		}
+	}
+
+	for _, s := range []string{"", "0", "-1", "abc", "1.5 cores", "NaN"} {
+		_, err := dockerexec.ParseCPUs(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestCPUSettings(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "true")
+	require.NoError(t, cmd.SetCPUs("1.5 CPUs"))
+	require.NoError(t, cmd.SetCPUSet("0"))
+	cmd.SetCPUShares(512)
+	cmd.HostConfig.AutoRemove = false
+	err := cmd.Run()
+	require.NoError(t, err)
+	defer func() {
+		_ = dockerClient.ContainerRemove(context.Background(), cmd.ContainerID, container.RemoveOptions{Force: true})
+	}()
+
+	inspect, err := dockerClient.ContainerInspect(context.Background(), cmd.ContainerID)
+	require.NoError(t, err)
+	assert.EqualValues(t, 15e8, inspect.HostConfig.NanoCPUs)
+	assert.Equal(t, "0", inspect.HostConfig.CpusetCpus)
+	assert.EqualValues(t, 512, inspect.HostConfig.CPUShares)
+}
+
+func TestSetCPUQuota(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "true")
+	cmd.SetCPUQuota(50*time.Millisecond, 100*time.Millisecond)
+	assert.EqualValues(t, 50000, cmd.HostConfig.CPUQuota)
+	assert.EqualValues(t, 100000, cmd.HostConfig.CPUPeriod)
+
+	err := cmd.Run()
+	assert.NoError(t, err)
+}
+
+func TestSetCPUSetInvalid(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "true")
+	for _, cpus := range []string{"", "a", "3-1", "0,", "-1"} {
+		assert.Error(t, cmd.SetCPUSet(cpus), cpus)
+	}
+	assert.NoError(t, cmd.SetCPUSet("0,2,4-7"))
+}
@@ -0,0 +1,104 @@
+package dockerexec
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// debugLogTail is the number of lines of logs captured in a DebugError.
+const debugLogTail = "100"
+
+// DebugError is returned by Wait, wrapping the error of a failed run, when Cmd.DebugOnFailure is
+// set. The container is kept for manual inspection, and its state at the time of the failure is
+// captured.
+type DebugError struct {
+	Err         error
+	ContainerID string
+
+	// Inspect is the inspect data of the container.
+	Inspect types.ContainerJSON
+
+	// Logs is the tail of the combined output of the container, as retained by its log driver.
+	Logs []byte
+
+	// Changes are the changes to the filesystem of the container, like "docker diff".
+	Changes []container.FilesystemChange
+}
+
+func (e *DebugError) Error() string {
+	id := e.ContainerID
+	return fmt.Sprintf("%v (container %s kept for debugging, see \"docker inspect %s\", \"docker logs %s\" and \"docker diff %s\", remove it using \"docker rm -f %s\")",
+		e.Err, id, id, id, id, id)
+}
+
+func (e *DebugError) Unwrap() error {
+	return e.Err
+}
+
+// applyDebugOnFailure disables HostConfig.AutoRemove when DebugOnFailure is set, remembering to
+// remove the container when it succeeds instead.
+func (c *Cmd) applyDebugOnFailure() {
+	if c.DebugOnFailure && c.HostConfig != nil && c.HostConfig.AutoRemove {
+		c.HostConfig.AutoRemove = false
+		c.removeOnSuccess = true
+	}
+}
+
+// debugOnFailure returns a *DebugError wrapping err if the run failed and DebugOnFailure is set,
+// and removes the container if it succeeded and is to be removed.
+func (c *Cmd) debugOnFailure(err error) error {
+	if !c.DebugOnFailure {
+		return err
+	}
+
+	ctx := context.Background()
+	if err == nil {
+		if c.removeOnSuccess {
+			_ = c.cli.ContainerRemove(ctx, c.ContainerID, container.RemoveOptions{RemoveVolumes: true})
+		}
+		return nil
+	}
+	if errors.Is(err, ErrContainerRemoved) {
+		return err
+	}
+
+	debugErr := &DebugError{Err: err, ContainerID: c.ContainerID}
+	debugErr.Inspect, _ = c.cli.ContainerInspect(ctx, c.ContainerID)
+	debugErr.Changes, _ = containerDiff(ctx, c.cli, c.ContainerID)
+	debugErr.Logs, _ = c.tailLogs(ctx, debugLogTail)
+	return debugErr
+}
+
+// tailLogs returns the last lines of the combined output of the container from its logs.
+func (c *Cmd) tailLogs(ctx context.Context, tail string) ([]byte, error) {
+	if c.HostConfig != nil {
+		if err := checkLogsReadable(c.HostConfig.LogConfig); err != nil {
+			return nil, err
+		}
+	}
+
+	logs, err := c.cli.ContainerLogs(ctx, c.ContainerID, container.LogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Tail:       tail,
+	})
+	if err != nil {
+		return nil, wrapError("container logs", err)
+	}
+	defer logs.Close()
+
+	var buf bytes.Buffer
+	if c.Config.Tty {
+		_, err = io.Copy(&buf, logs)
+	} else {
+		_, err = stdcopy.StdCopy(&buf, &buf, logs)
+	}
+	return buf.Bytes(), err
+}
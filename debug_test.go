@@ -0,0 +1,49 @@
+package dockerexec_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestDebugOnFailure(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "sh", "-c", "echo oops; touch /created; exit 1")
+	cmd.DebugOnFailure = true
+	_, err := cmd.Output()
+
+	var debugErr *dockerexec.DebugError
+	require.ErrorAs(t, err, &debugErr)
+	defer func() {
+		_ = dockerClient.ContainerRemove(context.Background(), cmd.ContainerID, container.RemoveOptions{Force: true})
+	}()
+
+	var exitErr *dockerexec.ExitError
+	require.ErrorAs(t, err, &exitErr)
+	assert.EqualValues(t, 1, exitErr.StatusCode)
+
+	assert.Equal(t, cmd.ContainerID, debugErr.ContainerID)
+	assert.Equal(t, cmd.ContainerID, debugErr.Inspect.ID)
+	assert.Equal(t, "oops\n", string(debugErr.Logs))
+	assert.Contains(t, debugErr.Changes, container.FilesystemChange{Kind: container.ChangeAdd, Path: "/created"})
+	assert.Contains(t, err.Error(), "docker rm -f "+cmd.ContainerID)
+
+	// The container is kept.
+	_, err = dockerClient.ContainerInspect(context.Background(), cmd.ContainerID)
+	require.NoError(t, err)
+}
+
+func TestDebugOnFailureSuccess(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "true")
+	cmd.DebugOnFailure = true
+	require.NoError(t, cmd.Run())
+
+	// The container is removed.
+	_, err := dockerClient.ContainerInspect(context.Background(), cmd.ContainerID)
+	assert.True(t, client.IsErrNotFound(err))
+}
@@ -0,0 +1,101 @@
+package dockerexec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+)
+
+// dependencyPollInterval is the interval in which dependencies poll their condition.
+const dependencyPollInterval = 100 * time.Millisecond
+
+// ErrContainerUnhealthy is returned by Cmd.WaitHealthy when the container is reported unhealthy
+// by its health check.
+var ErrContainerUnhealthy = errors.New("dockerexec: container is unhealthy")
+
+// A Dependency is a precondition for starting a Cmd, see Cmd.Dependencies. It blocks until the
+// precondition is met, returning nil, or until it can no longer be met or ctx is done, returning
+// an error.
+//
+// Cmd.WaitHealthy and Service.WaitHealthy can be used as dependencies on other containers, and
+// WaitTCP as a dependency on a network endpoint.
+type Dependency func(ctx context.Context) error
+
+// waitDependencies waits for all of c.Dependencies to be met, in order.
+func (c *Cmd) waitDependencies(ctx context.Context) error {
+	for i, dep := range c.Dependencies {
+		if err := dep(ctx); err != nil {
+			return fmt.Errorf("dockerexec: dependency %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// WaitHealthy waits for the container to be reported healthy by its health check, or just to be
+// running if it has none. It returns ErrContainerUnhealthy if it's reported unhealthy instead, and
+// ErrContainerNotRunning if it isn't running. The container must have been started by Start.
+func (c *Cmd) WaitHealthy(ctx context.Context) error {
+	if len(c.ContainerID) == 0 {
+		return errors.New("dockerexec: not started")
+	}
+
+	ticker := time.NewTicker(dependencyPollInterval)
+	defer ticker.Stop()
+
+	for {
+		inspect, err := c.cli.ContainerInspect(ctx, c.ContainerID)
+		if client.IsErrNotFound(err) {
+			return ErrContainerNotRunning
+		} else if err != nil {
+			return wrapError("inspect container", err)
+		}
+
+		if inspect.State == nil || !inspect.State.Running {
+			return ErrContainerNotRunning
+		}
+		if inspect.State.Health == nil {
+			return nil
+		}
+		switch inspect.State.Health.Status {
+		case types.Healthy, types.NoHealthcheck:
+			return nil
+		case types.Unhealthy:
+			return ErrContainerUnhealthy
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// WaitTCP returns a Dependency that waits for a TCP connection to addr, such as "db:5432", to
+// succeed.
+func WaitTCP(addr string) Dependency {
+	return func(ctx context.Context) error {
+		ticker := time.NewTicker(dependencyPollInterval)
+		defer ticker.Stop()
+
+		var dialer net.Dialer
+		for {
+			conn, err := dialer.DialContext(ctx, "tcp", addr)
+			if err == nil {
+				conn.Close()
+				return nil
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return fmt.Errorf("%w: %v", ctx.Err(), err)
+			}
+		}
+	}
+}
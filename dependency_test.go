@@ -0,0 +1,65 @@
+package dockerexec_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestDependencyHealthy(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := dockerexec.CommandContext(ctx, dockerClient, testImage, "sh", "-c", "sleep 1; touch /tmp/ready; sleep 120")
+	server.Config.Healthcheck = &container.HealthConfig{
+		Test:     []string{"CMD", "test", "-e", "/tmp/ready"},
+		Interval: 200 * time.Millisecond,
+	}
+	require.NoError(t, server.Start())
+	defer func() {
+		cancel()
+		_ = server.Wait()
+	}()
+
+	cmd := dockerexec.Command(dockerClient, testImage, "true")
+	cmd.Dependencies = []dockerexec.Dependency{server.WaitHealthy}
+	err := cmd.Run()
+	require.NoError(t, err)
+
+	inspect, err := server.Inspect()
+	require.NoError(t, err)
+	assert.Equal(t, "healthy", inspect.State.Health.Status)
+}
+
+func TestDependencyTCP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	cmd := dockerexec.Command(dockerClient, testImage, "true")
+	cmd.Dependencies = []dockerexec.Dependency{dockerexec.WaitTCP(l.Addr().String())}
+	err = cmd.Run()
+	require.NoError(t, err)
+}
+
+func TestDependencyFailed(t *testing.T) {
+	depErr := errors.New("not ready")
+
+	cmd := dockerexec.Command(dockerClient, testImage, "true")
+	cmd.Dependencies = []dockerexec.Dependency{
+		func(ctx context.Context) error { return nil },
+		func(ctx context.Context) error { return depErr },
+	}
+	err := cmd.Run()
+	assert.ErrorIs(t, err, depErr)
+	assert.EqualError(t, err, "dockerexec: dependency 1: not ready")
+	assert.Empty(t, cmd.ContainerID)
+}
@@ -0,0 +1,102 @@
+package dockerexec
+
+import (
+	"errors"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// Detach stops copying the standard streams of the container and releases the connection
+// attached to it, leaving the container running. The Cmd may later be re-attached to using
+// Attach, and Wait must still be called to wait for the container to exit and release the
+// resources of the Cmd.
+//
+// Pipes returned by StdinPipe, StdoutPipe and similar are closed by Detach. Like Wait, Detach
+// waits for copying from Stdin to finish, so if Stdin isn't a pipe, Detach returns only once the
+// next read from it does.
+//
+// Note that when Config.StdinOnce is set, the default, and Config.Tty isn't, the daemon closes
+// the standard input of the container once the connection attached to it is closed, so unset
+// StdinOnce for containers that should keep reading their standard input after detaching.
+//
+// The returned error is a *CopyError if copying any of the standard streams failed before
+// detaching. Detach and Attach must not be called concurrently with each other or with Wait.
+func (c *Cmd) Detach() error {
+	if len(c.ContainerID) == 0 {
+		return errors.New("dockerexec: not started")
+	}
+	if c.finished {
+		return errors.New("dockerexec: Wait was already called")
+	}
+	if c.detached.Load() {
+		return errors.New("dockerexec: already detached")
+	}
+
+	c.detached.Store(true)
+	c.attachMu.Lock()
+	if c.attachConn != nil {
+		c.attachConn.Close()
+		c.attachConn = nil
+	}
+	c.attachMu.Unlock()
+	c.closeDescriptors(c.closeAfterStdin)
+
+	var copyError error
+	for range c.goroutine {
+		if err := <-c.errch; err != nil && copyError == nil && !isBenignCopyError(err) {
+			copyError = err
+		}
+	}
+	c.goroutine = nil
+	c.errch = nil
+	return copyError
+}
+
+// Detached reports whether the Cmd is detached from its container, see Detach.
+func (c *Cmd) Detached() bool {
+	return c.detached.Load()
+}
+
+// Attach re-attaches to the container after Detach, copying its standard streams to and from
+// Stdin, Stdout and Stderr, which may be replaced while detached. Output written by the container
+// while detached isn't copied, see Logs for retrieving it.
+func (c *Cmd) Attach() error {
+	if len(c.ContainerID) == 0 {
+		return errors.New("dockerexec: not started")
+	}
+	if c.finished {
+		return errors.New("dockerexec: Wait was already called")
+	}
+	if !c.detached.Load() {
+		return errors.New("dockerexec: not detached")
+	}
+
+	stdin := c.Stdin
+	stdout, stderr := c.outputWriters()
+	if stdin == nil && stdout == nil && stderr == nil {
+		c.detached.Store(false)
+		return nil
+	}
+
+	attach, err := c.cli.ContainerAttach(c.context(), c.ContainerID, container.AttachOptions{
+		Stream: true,
+		Stdin:  stdin != nil,
+		Stdout: stdout != nil,
+		Stderr: stderr != nil,
+	})
+	if err != nil {
+		return wrapError("attach container", err)
+	}
+	c.attachConn = attach.Conn
+	c.closeAfterWait = append(c.closeAfterWait, attach.Conn)
+	c.detached.Store(false)
+
+	if stdin != nil {
+		c.stdin(attach, stdin)
+	}
+	if stdout != nil || stderr != nil {
+		c.stdoutStderr(attach, stdout, stderr)
+	}
+	c.startGoroutines()
+	return nil
+}
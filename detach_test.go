@@ -0,0 +1,64 @@
+package dockerexec_test
+
+import (
+	"bufio"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestDetach(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "sh", "-c", "while true; do echo tick; sleep 0.1; done")
+	stdout, err := cmd.StdoutPipe()
+	require.NoError(t, err)
+	require.NoError(t, cmd.Start())
+
+	line, err := bufio.NewReader(stdout).ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "tick\n", line)
+
+	assert.Error(t, cmd.Attach())
+	require.NoError(t, cmd.Detach())
+	assert.True(t, cmd.Detached())
+	assert.Error(t, cmd.Detach())
+
+	inspect, err := cmd.Inspect()
+	require.NoError(t, err)
+	assert.True(t, inspect.State.Running)
+
+	// Re-attach with a new writer.
+	lines := make(chan string, 16)
+	pw := &lineWriter{lines: lines}
+	cmd.Stdout = pw
+	require.NoError(t, cmd.Attach())
+	assert.False(t, cmd.Detached())
+	assert.Equal(t, "tick", <-lines)
+
+	require.NoError(t, cmd.Kill("SIGKILL"))
+	var exitErr *dockerexec.ExitError
+	assert.ErrorAs(t, cmd.Wait(), &exitErr)
+}
+
+// lineWriter sends each line written to it to lines, dropping lines once it's full.
+type lineWriter struct {
+	lines chan string
+	buf   []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	for _, b := range p {
+		if b != '\n' {
+			w.buf = append(w.buf, b)
+			continue
+		}
+		select {
+		case w.lines <- string(w.buf):
+		default:
+		}
+		w.buf = nil
+	}
+	return len(p), nil
+}
@@ -0,0 +1,67 @@
+package dockerexec
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// WithDevice exposes the host device hostPath, such as /dev/kvm or /dev/fuse, in the container at
+// containerPath, by adding it to HostConfig.Devices. An empty containerPath defaults to hostPath,
+// and empty perms default to "rwm", which are any of r (read), w (write) and m (mknod).
+//
+// Invalid perms set Err, so that Start fails. It returns c to allow chaining.
+func (c *Cmd) WithDevice(hostPath, containerPath, perms string) *Cmd {
+	if containerPath == "" {
+		containerPath = hostPath
+	}
+	if perms == "" {
+		perms = "rwm"
+	}
+	if !validDevicePerms(perms) {
+		c.setErr(fmt.Errorf("dockerexec: invalid device permissions %q", perms))
+		return c
+	}
+
+	hc := c.hostConfig()
+	hc.Devices = append(hc.Devices, container.DeviceMapping{
+		PathOnHost:        hostPath,
+		PathInContainer:   containerPath,
+		CgroupPermissions: perms,
+	})
+	return c
+}
+
+func validDevicePerms(perms string) bool {
+	if len(perms) > 3 {
+		return false
+	}
+	for _, r := range perms {
+		if !strings.ContainsRune("rwm", r) || strings.Count(perms, string(r)) > 1 {
+			return false
+		}
+	}
+	return true
+}
+
+// deviceCgroupRuleRegexp matches device cgroup rules, as validated by the docker CLI.
+var deviceCgroupRuleRegexp = regexp.MustCompile(`^[acb] ([0-9]+|\*):([0-9]+|\*) [rwm]{1,3}$`)
+
+// WithDeviceCgroupRule allows the container to access devices matching rule, by adding it to
+// HostConfig.DeviceCgroupRules. A rule has the form "type major:minor perms", e.g. "c 188:* rwm"
+// for USB serial devices, which is useful for devices that come and go while the container runs,
+// as they can't be added using WithDevice.
+//
+// An invalid rule sets Err, so that Start fails. It returns c to allow chaining.
+func (c *Cmd) WithDeviceCgroupRule(rule string) *Cmd {
+	if !deviceCgroupRuleRegexp.MatchString(rule) {
+		c.setErr(fmt.Errorf("dockerexec: invalid device cgroup rule %q", rule))
+		return c
+	}
+
+	hc := c.hostConfig()
+	hc.DeviceCgroupRules = append(hc.DeviceCgroupRules, rule)
+	return c
+}
@@ -0,0 +1,35 @@
+package dockerexec_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestWithDevice(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "sh", "-c", "head -c 4 /dev/test-zero | od -An -tx1")
+	cmd.WithDevice("/dev/zero", "/dev/test-zero", "r")
+	output, err := cmd.Output()
+	require.NoError(t, err)
+	assert.Equal(t, " 00 00 00 00\n", string(output))
+}
+
+func TestWithDeviceInvalid(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "true")
+	cmd.WithDevice("/dev/zero", "", "rx")
+	assert.EqualError(t, cmd.Run(), `dockerexec: invalid device permissions "rx"`)
+}
+
+func TestWithDeviceCgroupRule(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "true")
+	cmd.WithDeviceCgroupRule("c 1:5 rwm")
+	assert.Equal(t, []string{"c 1:5 rwm"}, cmd.HostConfig.DeviceCgroupRules)
+	require.NoError(t, cmd.Run())
+
+	cmd = dockerexec.Command(dockerClient, testImage, "true")
+	cmd.WithDeviceCgroupRule("x 1:5 rwm")
+	assert.EqualError(t, cmd.Run(), `dockerexec: invalid device cgroup rule "x 1:5 rwm"`)
+}
@@ -0,0 +1,141 @@
+package dockerexec
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/docker/docker/client"
+)
+
+// A Dispatcher schedules Cmds onto several Docker daemons, picking the least loaded daemon, by
+// number of running containers, plus the number of picks not yet released, for each Cmd.
+//
+// A Dispatcher is safe for concurrent use.
+type Dispatcher struct {
+	clients []client.APIClient
+
+	mu       sync.Mutex
+	affinity map[string]int // index of the client picked for the affinity key
+	inFlight []int          // picks not yet released, per client
+}
+
+// NewDispatcher returns a Dispatcher scheduling onto the daemons of the given clients.
+func NewDispatcher(clients ...client.APIClient) *Dispatcher {
+	if len(clients) == 0 {
+		panic("dockerexec: no clients")
+	}
+	return &Dispatcher{
+		clients:  clients,
+		affinity: make(map[string]int),
+		inFlight: make([]int, len(clients)),
+	}
+}
+
+// Pick returns the client of the least loaded daemon, skipping daemons that can't be reached.
+//
+// The pick counts towards the load of the daemon until release is called, which should be once
+// the Cmd using the client was started, or won't be, so that concurrent Picks spread out before
+// their containers show up as running. Calling release more than once is a no-op.
+//
+// If affinity is non-empty, Cmds with the same affinity key are scheduled onto the same daemon as
+// long as it's reachable, e.g. so that runs of the same job reuse images and volumes already on
+// that daemon.
+func (d *Dispatcher) Pick(ctx context.Context, affinity string) (cli client.APIClient, release func(), err error) {
+	if affinity != "" {
+		d.mu.Lock()
+		i, ok := d.affinity[affinity]
+		d.mu.Unlock()
+
+		if ok {
+			if _, err := d.clients[i].Ping(ctx); err == nil {
+				return d.clients[i], d.acquire(i), nil
+			}
+		}
+	}
+
+	type load struct {
+		running int
+		err     error
+	}
+	loads := make([]load, len(d.clients))
+
+	var wg sync.WaitGroup
+	for i, cli := range d.clients {
+		wg.Add(1)
+		go func(i int, cli client.APIClient) {
+			defer wg.Done()
+
+			info, err := cli.Info(ctx)
+			loads[i] = load{running: info.ContainersRunning, err: err}
+		}(i, cli)
+	}
+	wg.Wait()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	picked := -1
+	var pickedLoad int
+	var errs []error
+	for i, l := range loads {
+		if l.err != nil {
+			errs = append(errs, wrapError("daemon info", l.err))
+			continue
+		}
+		if load := l.running + d.inFlight[i]; picked == -1 || load < pickedLoad {
+			picked, pickedLoad = i, load
+		}
+	}
+	if picked == -1 {
+		return nil, nil, errors.Join(append([]error{errors.New("dockerexec: no reachable daemon")}, errs...)...)
+	}
+
+	if affinity != "" {
+		d.affinity[affinity] = picked
+	}
+
+	d.inFlight[picked]++
+	return d.clients[picked], d.releaseFunc(picked), nil
+}
+
+// acquire counts a pick of client i, returning the function releasing it.
+func (d *Dispatcher) acquire(i int) func() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.inFlight[i]++
+	return d.releaseFunc(i)
+}
+
+func (d *Dispatcher) releaseFunc(i int) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			d.mu.Lock()
+			defer d.mu.Unlock()
+
+			d.inFlight[i]--
+		})
+	}
+}
+
+// CommandContext is like the package level CommandContext, but runs the Cmd on the daemon picked
+// by Pick without an affinity key. The pick is released once the Cmd is started.
+func (d *Dispatcher) CommandContext(ctx context.Context, image string, name string, arg ...string) (*Cmd, error) {
+	cli, release, err := d.Pick(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	cmd := CommandContext(ctx, cli, image, name, arg...)
+	cmd.dispatched = release
+	return cmd, nil
+}
+
+// releaseDispatched releases the pick of the Dispatcher that created c, if any.
+func (c *Cmd) releaseDispatched() {
+	if c.dispatched != nil {
+		c.dispatched()
+		c.dispatched = nil
+	}
+}
@@ -0,0 +1,71 @@
+package dockerexec_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/docker/docker/api/types/system"
+	"github.com/docker/docker/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestDispatcher(t *testing.T) {
+	unreachableClient, err := client.NewClientWithOpts(client.WithHost("tcp://127.0.0.1:1"))
+	require.NoError(t, err)
+
+	dispatcher := dockerexec.NewDispatcher(unreachableClient, dockerClient)
+
+	cli, release, err := dispatcher.Pick(context.Background(), "job-1")
+	require.NoError(t, err)
+	assert.Same(t, dockerClient, cli)
+	release()
+
+	cmd, err := dispatcher.CommandContext(context.Background(), testImage, "sh", "-c", "echo Hello, World!")
+	require.NoError(t, err)
+
+	output, err := cmd.Output()
+	require.NoError(t, err)
+	assert.Equal(t, "Hello, World!\n", string(output))
+}
+
+func TestDispatcherUnreachable(t *testing.T) {
+	unreachableClient, err := client.NewClientWithOpts(client.WithHost("tcp://127.0.0.1:1"))
+	require.NoError(t, err)
+
+	dispatcher := dockerexec.NewDispatcher(unreachableClient)
+
+	_, _, err = dispatcher.Pick(context.Background(), "")
+	assert.ErrorContains(t, err, "dockerexec: no reachable daemon")
+}
+
+// idleClient reports no running containers, like a daemon that didn't start any picked Cmd yet.
+type idleClient struct {
+	client.APIClient
+	name string
+}
+
+func (idleClient) Info(ctx context.Context) (system.Info, error) {
+	return system.Info{}, nil
+}
+
+func TestDispatcherInFlight(t *testing.T) {
+	cli1, cli2 := idleClient{dockerClient, "daemon-1"}, idleClient{dockerClient, "daemon-2"}
+	dispatcher := dockerexec.NewDispatcher(cli1, cli2)
+
+	picked1, release1, err := dispatcher.Pick(context.Background(), "")
+	require.NoError(t, err)
+	picked2, release2, err := dispatcher.Pick(context.Background(), "")
+	require.NoError(t, err)
+	assert.NotEqual(t, picked1, picked2, "concurrent picks should be spread out")
+
+	release1()
+	release1()
+	picked3, release3, err := dispatcher.Pick(context.Background(), "")
+	require.NoError(t, err)
+	assert.Equal(t, picked1, picked3)
+	release2()
+	release3()
+}
@@ -5,20 +5,25 @@
 package dockerexec
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
+	"net"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/network"
-	"github.com/docker/docker/client"
-	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/go-units"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 )
 
@@ -39,6 +44,31 @@ type Cmd struct {
 	Platform         *ocispec.Platform
 	ContainerName    string
 
+	// OnNameConflict determines what Start does when ContainerName is set and a container with that
+	// name already exists. Defaults to NameConflictFail.
+	OnNameConflict NameConflictStrategy
+
+	// Hostname specifies the hostname of the container. If empty, Config.Hostname is used as is,
+	// which means Docker will default to the short container ID.
+	Hostname string
+
+	// EnsurePasswd adds entries for the UID and GID of Config.User to /etc/passwd and /etc/group
+	// in the container when it's numeric, such as "1000:1000", and they are missing, so that
+	// programs looking up the current user, its name or home directory, don't break. The entries
+	// are named "user", with /tmp as the home directory. The entries can't be added with
+	// HostConfig.ReadonlyRootfs, in which case Start fails.
+	EnsurePasswd bool
+
+	// DebugOnFailure keeps the container when the run fails, instead of removing it per
+	// HostConfig.AutoRemove, and makes Wait return a *DebugError capturing its inspect data, logs
+	// and filesystem changes, with instructions for inspecting it manually. The container is
+	// still removed when the run succeeds.
+	DebugOnFailure bool
+
+	// Owner is recorded in the LabelOwner label of the container, allowing ListManaged to tell
+	// which program created it. Defaults to the base name of the running executable.
+	Owner string
+
 	// Stdin specifies the container's standard input.
 	//
 	// If Stdin is nil, the container will receive no input.
@@ -49,12 +79,19 @@ type Cmd struct {
 	// because writing to the container.
 	Stdin io.Reader
 
+	// KeepStdinOpen keeps the container's standard input open after reaching the end of Stdin,
+	// instead of closing it, so that the container doesn't see EOF. It is only closed once the
+	// container exits.
+	//
+	// This is useful for programs that poll their standard input and exit on EOF.
+	KeepStdinOpen bool
+
 	// Stdout and Stderr specify the container's standard output and error.
 	//
 	// If either is nil, the corresponding output will be discarded.
 	//
 	// While using Config.Tty, only a single stream of output is available in Stdout. Setting Stderr
-	// will panic.
+	// will panic. If Stdout is a terminal, HostConfig.ConsoleSize defaults to its size.
 	//
 	// During the execution of the container a separate goroutine reads from the container and
 	// delivers that data to the corresponding Writer. In this case, Wait does not complete until
@@ -62,6 +99,139 @@ type Cmd struct {
 	Stdout io.Writer
 	Stderr io.Writer
 
+	// OutputFrames, if set, receives the output of the container frame by frame, as multiplexed by
+	// the daemon, instead of it being demultiplexed to Stdout and Stderr, which must be unset
+	// along with OutputLog. This allows proxies to re-multiplex the output to their own clients
+	// without parsing it twice. See Frame.
+	//
+	// While using Config.Tty, the output isn't multiplexed, so each read of it is delivered as a
+	// frame of StreamStdout. An error returned by OutputFrames fails copying the output.
+	OutputFrames func(Frame) error
+
+	// OutputLog, if set, receives a copy of the output of the container, in addition to Stdout and
+	// Stderr. It is not closed by the Cmd, so it may be shared between several Cmds.
+	OutputLog *LogFile
+
+	// OrderedOutput, if set, receives the combined output of the container ordered by the
+	// timestamps the daemon recorded for it, unlike setting Stdout and Stderr to the same writer,
+	// where the order between the two streams isn't preserved. The daemon timestamps output as it
+	// reads it from the container, so writes to the two streams in quick succession may still be
+	// reordered, while the order within each stream is always preserved. Rather than attaching,
+	// the output is read back from the logs of the container once it exits, so it's only written
+	// during Wait, and requires a log driver whose logs can be read back, see LogsReadable. It
+	// can't be combined with Stdout, Stderr or OutputFrames. See CombinedOutputOrdered.
+	OrderedOutput io.Writer
+
+	// HashOutput, if set, such as to sha256.New, is used to hash the standard output and standard
+	// error of the container as they're copied, setting StdoutDigest and StderrDigest, e.g. to
+	// verify artifacts written to Stdout without reading them back. Output discarded because
+	// Stdout or Stderr is nil is hashed too, but output written while detached, see Detach, isn't
+	// copied, and so isn't hashed. It's ignored when OutputFrames is set.
+	HashOutput func() hash.Hash
+
+	// Artifacts are paths copied out of the container once it exits, whether it succeeded or not,
+	// e.g. to collect build outputs and test reports. Failures are recorded in ArtifactErrors,
+	// and make Wait fail with them if the run otherwise succeeded.
+	Artifacts []ArtifactSpec
+
+	// Inputs are paths copied into the container after it's created, before it starts, e.g. to
+	// feed it data without bind mounts, which don't work with remote daemons. Start fails with an
+	// *InputError if any can't be copied.
+	//
+	// With HostConfig.ReadonlyRootfs, such as set by ProfileSecure, inputs can only be copied into
+	// volumes and bind mounts, as tmpfs mounts, such as /tmp, aren't mounted before the container
+	// starts. Start also fails if OnNameConflict reuses an existing container, as it can't be
+	// copied into.
+	Inputs []InputSpec
+
+	// MaxRuntime, if non-zero, bounds the wall-clock runtime of the container. Once it elapses, the
+	// container is stopped using ContainerStop, which sends it its stop signal, and kills it if it
+	// doesn't exit within its stop timeout, and Wait returns ErrMaxRuntimeExceeded.
+	//
+	// The deadline is also recorded in the LabelDeadline label of the container, so that a
+	// supervisor can find and stop containers that outlived it (See ListManaged) in case this
+	// process crashes before stopping it, as Docker has no way to schedule a stop on its own.
+	MaxRuntime time.Duration
+
+	// PidsLimit, if non-zero, limits the number of processes in the container, overriding
+	// HostConfig.PidsLimit, to protect against fork bombs in untrusted commands. -1 means
+	// unlimited. See SecurePidsLimit for a sensible limit, which ProfileSecure applies.
+	PidsLimit int64
+
+	// ShmSize, if set, is the size of /dev/shm in the container, overriding HostConfig.ShmSize,
+	// such as "512m" or "2g". The default of 64MB is too small for e.g. browsers and some
+	// scientific workloads.
+	ShmSize string
+
+	// CollectUsage enables collecting a summary of the resources used by the container over its
+	// run into Usage.
+	CollectUsage bool
+
+	// DaemonRestartTolerance is how long Wait keeps trying to re-establish waiting for the
+	// container, and re-attaching to its output, after losing the connection to the daemon, such
+	// as when it restarts with live-restore enabled. Output written while detached, and Stdin, are
+	// lost. Zero disables it.
+	DaemonRestartTolerance time.Duration
+
+	// Dependencies are preconditions that Start waits for, in order, before creating the
+	// container, such as another container being healthy (See Cmd.WaitHealthy) or a TCP endpoint
+	// accepting connections (See WaitTCP), giving ordered bring-up of several containers. Start
+	// fails if any of them fails.
+	Dependencies []Dependency
+
+	// Pull, if set, makes Start pull the image using it when it's missing on the daemon, instead
+	// of failing. See PullConfig for the default implementation.
+	Pull Puller
+
+	// ImageGate, if set, is consulted before creating the container and may veto running it, in
+	// which case Start returns an *ImageRejectedError. Its decision is recorded in GateDecision.
+	ImageGate ImageGate
+
+	// Policy, if set, is called with the final configuration of the container right before it is
+	// created, and may reject it, in which case Start returns a *PolicyError, or mutate it.
+	Policy PolicyFunc
+
+	// Audit, if set, receives an AuditEvent describing the run once it completes, or fails to
+	// start.
+	Audit AuditSink
+
+	// Principal identifies who the run is on behalf of in AuditEvent, e.g. a user or tenant name.
+	Principal string
+
+	// Cache, if set, memoizes successful runs: Start looks the run up by a hash of the ID of the
+	// image, the whole configuration of the container, including Config, HostConfig,
+	// Networkingconfig and Platform, the Hostname, PidsLimit, ShmSize and EnsurePasswd fields
+	// affecting it, and Stdin, which is read fully first, and if an identical run already
+	// succeeded, skips running the container, replaying its output instead. See Cached.
+	// It's ignored when OutputFrames, OrderedOutput, Inputs or Artifacts are set, as a cached run
+	// has no container to copy them from or to, and Start fails if Stdin is a pipe, such as from
+	// StdinPipe, as it can only be written to once Start returns.
+	//
+	// Only use it for deterministic commands, whose outcome depends on nothing else.
+	Cache ResultCache
+
+	// Limiter, if set, limits the number of containers running concurrently, making Start block
+	// or fail while it is saturated. Defaults to DefaultLimiter.
+	Limiter *Limiter
+
+	// Quota, if set, enforces the quotas of QuotaKeys on the resources reserved by the container,
+	// making Start fail with a *QuotaExceededError if any would be exceeded.
+	Quota     *QuotaManager
+	QuotaKeys []string
+
+	// OnWarning, if set, is called with each warning as it's added to Warnings, e.g. to route them
+	// to structured logs.
+	OnWarning func(Warning)
+
+	// Trace, if set, records every Docker API call made on behalf of the Cmd from Start onwards,
+	// including by its execs, e.g. to dump when a run against a remote daemon misbehaves.
+	Trace *Trace
+
+	// Err holds an error found while constructing the Cmd, such as an empty image or command
+	// name, which Start then returns instead of starting the container. Like in os/exec, callers
+	// may also set it to defer reporting their own configuration errors to Start.
+	Err error
+
 	// TODO Add callback BeforeStart (For users that want to start stats or event monitoring)
 
 	// TODO "os/exec" has an os.Process object, which also has methods to Kill & Wait, etc.
@@ -73,14 +243,31 @@ type Cmd struct {
 
 	// Warnings contains any warnings from creating the container.
 	//
-	// You should consider logging these.
-	Warnings []string
+	// You should consider logging these, or set OnWarning.
+	Warnings []Warning
 
 	// StatusCode contains the status code of the container, available after a call to Wait or Run.
 	StatusCode int64
 
+	// GateDecision contains the decision of ImageGate, available after a call to Start or Run.
+	GateDecision *GateDecision
+
+	// Usage contains a summary of the resources used by the container when CollectUsage is set,
+	// available after a call to Wait or Run.
+	Usage *ResourceUsage
+
+	// StdoutDigest and StderrDigest contain the digests of the standard output and standard error
+	// of the container when HashOutput is set, available after a call to Wait or Run. StderrDigest
+	// isn't set when using Config.Tty.
+	StdoutDigest []byte
+	StderrDigest []byte
+
+	// ArtifactErrors contains the failures to copy Artifacts, available after a call to Wait or
+	// Run.
+	ArtifactErrors []*ArtifactError
+
 	ctx              context.Context // nil means None
-	cli              client.APIClient
+	cli              Client
 	finished         bool // when Wait was called
 	closeAfterWait   []io.Closer
 	closeAfterStdin  []io.Closer
@@ -90,12 +277,43 @@ type Cmd struct {
 	waitCh           <-chan container.WaitResponse
 	waitErrCh        <-chan error
 	waitDone         chan struct{}
+	adopted          bool   // created by FromContainer
+	joinState        string // state of the container joined by NameConflictIdempotent
+	deadlineTimer    *time.Timer
+	deadlineExceeded atomic.Bool
+	usageCancel      context.CancelFunc
+	usageDone        chan struct{}
+	startTime        time.Time
+	limiterAcquired  bool
+	quotaReserved    *QuotaUsage
+	quotaKeys        []string // the unique QuotaKeys reserved under
+	imageID          string
+	imageDigest      string
+	createdID        string // the container, once created, even if it then failed to start
+	lastState        *types.ContainerState
+	standby          bool   // being created by a StandbyPool
+	standbyID        string // created ahead of time by a StandbyPool
+	dispatched       func() // releases the pick of the Dispatcher that created the Cmd
+	lifecycle        lifecycle
+	watchingRemoval  bool
+	attachMu         sync.Mutex
+	attachConn       net.Conn // guarded by attachMu, closed by Detach
+	detached         atomic.Bool
+	execs            execGroup
+	removeOnSuccess  bool // AutoRemove was disabled by DebugOnFailure
+	removeAfterWait  bool // AutoRemove was disabled by OrderedOutput or Artifacts
+	stdoutHash       hash.Hash
+	stderrHash       hash.Hash
+	cache            *cacheState
 }
 
 // Command returns the Cmd struct to execute the named program inside the given image with the given
 // arguments.
-func Command(cli client.APIClient, image string, name string, arg ...string) *Cmd {
-	return &Cmd{
+//
+// If cli is nil, DefaultClient is used, created on first use, with any error creating it returned
+// by Start.
+func Command(cli Client, image string, name string, arg ...string) *Cmd {
+	cmd := &Cmd{
 		Config: &container.Config{
 			Image:     image,
 			Cmd:       append([]string{name}, arg...),
@@ -105,10 +323,21 @@ func Command(cli client.APIClient, image string, name string, arg ...string) *Cm
 			AutoRemove: true,
 		},
 
+		Owner:   defaultOwner,
+		Limiter: DefaultLimiter,
+
 		StatusCode: -1,
 
 		cli: cli,
 	}
+
+	if image == "" {
+		cmd.Err = errors.New("dockerexec: no image specified")
+	} else if name == "" {
+		cmd.Err = errors.New("dockerexec: no command specified")
+	}
+
+	return cmd
 }
 
 // CommandContext is like Command but includes a context.
@@ -116,7 +345,7 @@ func Command(cli client.APIClient, image string, name string, arg ...string) *Cm
 // The provided context is used to kill the container (by calling
 // ContainerKill) if the context becomes done before the container
 // completes on its own.
-func CommandContext(ctx context.Context, cli client.APIClient, image string, name string, arg ...string) *Cmd {
+func CommandContext(ctx context.Context, cli Client, image string, name string, arg ...string) *Cmd {
 	if ctx == nil {
 		panic("nil Context")
 	}
@@ -125,23 +354,101 @@ func CommandContext(ctx context.Context, cli client.APIClient, image string, nam
 	return cmd
 }
 
-// String returns a human-readable description of c.
+// FromContainer returns a Cmd wrapping an already created, and possibly running, container, such as
+// one created by another tool (e.g. Docker Compose).
+//
+// The returned Cmd is considered started, its Config and HostConfig are populated from the
+// container's inspect data, and it can be used with Wait, Kill, Inspect and Logs. Its Stdin,
+// Stdout and Stderr fields are ignored since the container is not attached to.
+func FromContainer(cli Client, id string) (*Cmd, error) {
+	inspect, err := cli.ContainerInspect(context.Background(), id)
+	if err != nil {
+		return nil, wrapError("inspect container", err)
+	}
+
+	config := inspect.Config
+	if config == nil {
+		config = &container.Config{}
+	}
+
+	return &Cmd{
+		Config:        config,
+		HostConfig:    inspect.HostConfig,
+		ContainerName: strings.TrimPrefix(inspect.Name, "/"),
+		Hostname:      config.Hostname,
+		Owner:         config.Labels[LabelOwner],
+		ContainerID:   inspect.ID,
+		StatusCode:    -1,
+
+		cli:       cli,
+		adopted:   true,
+		lastState: inspect.State,
+		lifecycle: lifecycle{state: stateFromStatus(inspect.State)},
+	}, nil
+}
+
+// HostGateway is a special IP value for AddHost which the daemon resolves to the IP of the host's
+// gateway, allowing the container to reach services listening on the host.
+const HostGateway = "host-gateway"
+
+// AddHost adds a custom host-to-IP mapping (host:ip) to HostConfig.ExtraHosts, making it
+// available in the container's /etc/hosts.
+//
+// Pass HostGateway as ip to map host to the host's gateway IP.
+func (c *Cmd) AddHost(host, ip string) {
+	if c.HostConfig == nil {
+		c.HostConfig = &container.HostConfig{}
+	}
+	c.HostConfig.ExtraHosts = append(c.HostConfig.ExtraHosts, host+":"+ip)
+}
+
+// String returns a human-readable description of c, with arguments quoted by ShellQuote.
 // It is intended only for debugging.
 func (c *Cmd) String() string {
-	b := new(strings.Builder)
-	for _, a := range c.Config.Entrypoint {
-		if b.Len() > 0 {
-			b.WriteByte(' ')
-		}
-		b.WriteString(a)
+	command := make([]string, 0, len(c.Config.Entrypoint)+len(c.Config.Cmd))
+	command = append(command, c.Config.Entrypoint...)
+	command = append(command, c.Config.Cmd...)
+	return ShellJoin(command...)
+}
+
+// context returns the context of c, or context.Background if there is none.
+func (c *Cmd) context() context.Context {
+	if c.ctx != nil {
+		return c.ctx
+	}
+	return context.Background()
+}
+
+// EffectiveCommand returns the command the container will actually run, which is its entrypoint
+// followed by its command, after merging the defaults of the image with the overrides in Config,
+// the same way the daemon does.
+//
+// The image must be available on the daemon.
+func (c *Cmd) EffectiveCommand(ctx context.Context) ([]string, error) {
+	if err := c.ensureClient(); err != nil {
+		return nil, err
+	}
+
+	img, _, err := c.cli.ImageInspectWithRaw(ctx, c.Config.Image)
+	if err != nil {
+		return nil, wrapError("inspect image", err)
 	}
-	for _, a := range c.Config.Cmd {
-		if b.Len() > 0 {
-			b.WriteByte(' ')
+
+	entrypoint, cmd := c.Config.Entrypoint, c.Config.Cmd
+	if len(entrypoint) == 0 && img.Config != nil {
+		if len(cmd) == 0 {
+			cmd = img.Config.Cmd
+		}
+		// An empty non-nil entrypoint clears the entrypoint of the image.
+		if entrypoint == nil {
+			entrypoint = img.Config.Entrypoint
 		}
-		b.WriteString(a)
 	}
-	return b.String()
+
+	command := make([]string, 0, len(entrypoint)+len(cmd))
+	command = append(command, entrypoint...)
+	command = append(command, cmd...)
+	return command, nil
 }
 
 func (c *Cmd) closeDescriptors(closers []io.Closer) {
@@ -165,42 +472,104 @@ func (c *Cmd) Run() error {
 	return c.Wait()
 }
 
-func (c *Cmd) stdin(attach types.HijackedResponse) {
+func (c *Cmd) stdin(attach types.HijackedResponse, stdin io.Reader) {
 	c.goroutine = append(c.goroutine, func() error {
-		_, err := io.Copy(attach.Conn, c.Stdin)
-		if err1 := attach.CloseWrite(); err == nil {
-			err = err1
+		_, err := io.Copy(attach.Conn, stdin)
+		if !c.KeepStdinOpen && !c.detached.Load() {
+			if err1 := attach.CloseWrite(); err == nil {
+				err = err1
+			}
 		}
 		c.closeDescriptors(c.closeAfterStdin)
-		return err
+		return copyError(StreamStdin, err)
 	})
 }
 
-func (c *Cmd) stdoutStderr(attach types.HijackedResponse) {
-	c.goroutine = append(c.goroutine, func() error {
-		stdout := c.Stdout
-		if stdout == nil {
-			stdout = io.Discard
+// outputWriters returns the writers the output of the container should be copied to, which is
+// Stdout and Stderr, teed to the hashes of HashOutput, OutputLog if set, and to the recording of
+// the run for Cache, or a frameWriter for OutputFrames. Either is nil if the corresponding output
+// is to be discarded.
+func (c *Cmd) outputWriters() (stdout, stderr io.Writer) {
+	if c.OutputFrames != nil {
+		w := &frameWriter{fn: c.OutputFrames}
+		if c.Config.Tty {
+			return w, nil
 		}
+		return w, w
+	}
 
-		stderr := c.Stderr
-		if stderr == nil {
-			stderr = io.Discard
+	stdout, stderr = c.hashOutput(c.Stdout, c.Stderr)
+	if c.OutputLog != nil {
+		stdout = teeWriter(stdout, c.OutputLog)
+		if !c.Config.Tty {
+			stderr = teeWriter(stderr, c.OutputLog)
 		}
-
-		var err error
-		if c.Config.Tty {
-			_, err = io.Copy(stdout, attach.Reader)
-		} else {
-			_, err = stdcopy.StdCopy(stdout, stderr, attach.Reader)
+	}
+	if c.cache != nil && c.cache.hit == nil {
+		stdout = teeWriter(stdout, &captureWriter{state: c.cache, stream: StreamStdout})
+		if !c.Config.Tty {
+			stderr = teeWriter(stderr, &captureWriter{state: c.cache, stream: StreamStderr})
 		}
+	}
+	return stdout, stderr
+}
 
-		c.closeDescriptors(c.closeAfterOutput)
+func teeWriter(w io.Writer, tee io.Writer) io.Writer {
+	if w == nil {
+		return tee
+	}
+	return io.MultiWriter(w, tee)
+}
+
+func (c *Cmd) stdoutStderr(attach types.HijackedResponse, stdout, stderr io.Writer) {
+	c.goroutine = append(c.goroutine, func() error {
+		err := copyOutput(attach.Reader, c.Config.Tty, stdout, stderr)
+		for {
+			var ce *CopyError
+			if (errors.As(err, &ce) && ce.write) || c.detached.Load() {
+				break
+			}
 
+			var ok bool
+			attach, ok = c.reattach(stdout != nil, stderr != nil)
+			if !ok {
+				break
+			}
+			c.closeAfterWait = append(c.closeAfterWait, attach.Conn)
+			c.setAttachConn(attach.Conn)
+			err = copyOutput(attach.Reader, c.Config.Tty, stdout, stderr)
+		}
+		err = endCopyError(err, func() bool {
+			return c.detached.Load() || !c.running()
+		})
+		if !c.detached.Load() {
+			if flushErr := c.flushOutput(); flushErr != nil && (err == nil || isBenignCopyError(err)) {
+				err = flushErr
+			}
+		}
+		c.closeDescriptors(c.closeAfterOutput)
 		return err
 	})
 }
 
+// setAttachConn records conn, from re-attaching, as the connection to close on Detach, closing it
+// right away if Detach was already called.
+func (c *Cmd) setAttachConn(conn net.Conn) {
+	c.attachMu.Lock()
+	defer c.attachMu.Unlock()
+
+	c.attachConn = conn
+	if c.detached.Load() {
+		conn.Close()
+	}
+}
+
+// running reports whether the container is known to be still running.
+func (c *Cmd) running() bool {
+	inspect, err := c.cli.ContainerInspect(context.Background(), c.ContainerID)
+	return err == nil && inspect.State != nil && inspect.State.Running
+}
+
 // Start starts the specified container but does not wait for it to complete.
 //
 // If Start returns successfully, the c.ContainerID field will be set.
@@ -208,13 +577,152 @@ func (c *Cmd) stdoutStderr(attach types.HijackedResponse) {
 // The Wait method will return the exit code and release associated resources
 // once the container exits.
 func (c *Cmd) Start() error {
+	defer c.releaseDispatched()
+
+	if c.Err != nil {
+		c.closeDescriptors(c.closeAfterStdin)
+		c.closeDescriptors(c.closeAfterOutput)
+		c.closeDescriptors(c.closeAfterWait)
+		return c.Err
+	}
 	if len(c.ContainerID) != 0 {
 		return errors.New("dockerexec: already started")
 	}
+	if err := c.ensureClient(); err != nil {
+		c.closeDescriptors(c.closeAfterStdin)
+		c.closeDescriptors(c.closeAfterOutput)
+		c.closeDescriptors(c.closeAfterWait)
+		return err
+	}
+	c.startTrace()
 	if c.Config.Tty && c.Stderr != nil {
 		return errors.New("dockerexec: can't set both Config.Tty and Stderr")
 	}
+	if c.OutputFrames != nil && (c.Stdout != nil || c.Stderr != nil || c.OutputLog != nil) {
+		return errors.New("dockerexec: can't set OutputFrames with Stdout, Stderr or OutputLog")
+	}
+	if c.OrderedOutput != nil && (c.Stdout != nil || c.Stderr != nil || c.OutputFrames != nil) {
+		return errors.New("dockerexec: can't set OrderedOutput with Stdout, Stderr or OutputFrames")
+	}
+
+	c.startTime = time.Now()
+	err := c.start()
+	if err != nil {
+		c.releaseLimiter()
+		c.releaseQuota()
+		c.audit(err)
+		c.finishLifecycle()
+	}
+	return err
+}
+
+// prepare applies the final adjustments to the configuration of the container, and checks it
+// against Policy.
+func (c *Cmd) prepare() error {
+	if c.Stdin != nil || c.standby {
+		c.Config.OpenStdin = true
+	}
+	if c.Hostname != "" {
+		c.Config.Hostname = c.Hostname
+	}
+	if c.PidsLimit != 0 {
+		pidsLimit := c.PidsLimit
+		c.hostConfig().PidsLimit = &pidsLimit
+	}
+	if c.ShmSize != "" {
+		size, err := units.RAMInBytes(c.ShmSize)
+		if err != nil {
+			return fmt.Errorf("dockerexec: invalid ShmSize: %w", err)
+		}
+		c.hostConfig().ShmSize = size
+	}
+	c.applyConsoleSize()
+	c.applyDebugOnFailure()
+	if err := c.applyOrderedOutput(); err != nil {
+		return err
+	}
+	c.applyArtifacts()
+	c.applyManagedLabels()
+	if c.MaxRuntime > 0 && !c.standby {
+		c.Config.Labels[LabelDeadline] = time.Now().Add(c.MaxRuntime).UTC().Format(time.RFC3339)
+	}
+
+	return c.checkPolicy()
+}
+
+// create creates the container, without starting it.
+func (c *Cmd) create(ctx context.Context) (container.CreateResponse, error) {
+	platform, warnings, err := c.checkAPIFeatures(ctx)
+	if err != nil {
+		return container.CreateResponse{}, err
+	}
+
+	if c.OnNameConflict == NameConflictIdempotent {
+		c.Config.Labels[LabelConfigHash] = c.configHash()
+	}
+
+	cont, err := c.cli.ContainerCreate(
+		ctx,
+		c.Config,
+		c.HostConfig,
+		c.Networkingconfig,
+		platform,
+		c.ContainerName,
+	)
+	if err != nil && c.ContainerName != "" && errdefs.IsConflict(err) {
+		var reused bool
+		cont, reused, err = c.resolveNameConflict(ctx, platform, err)
+		if err != nil {
+			return container.CreateResponse{}, err
+		} else if reused {
+			c.createdID = cont.ID
+			if len(c.Inputs) != 0 {
+				return container.CreateResponse{}, fmt.Errorf("dockerexec: can't copy Inputs into existing container %.12s reused per OnNameConflict", cont.ID)
+			}
+			for _, w := range warnings {
+				c.warn(w)
+			}
+			c.transition(StateCreated, -1)
+			return cont, nil
+		}
+	} else if err != nil {
+		return container.CreateResponse{}, wrapError("create container", err)
+	}
+	c.createdID = cont.ID
+
+	for _, w := range warnings {
+		c.warn(w)
+	}
+	for _, message := range cont.Warnings {
+		c.warn(Warning{Code: WarningDaemon, Message: message})
+	}
+	if c.privileged() {
+		c.warn(Warning{Code: WarningPrivileged, Message: "container runs privileged, with full access to the host"})
+	}
+
+	if c.EnsurePasswd {
+		if err := c.ensurePasswd(ctx, cont.ID); err != nil {
+			_ = c.cli.ContainerRemove(context.Background(), cont.ID, container.RemoveOptions{
+				RemoveVolumes: true,
+				Force:         true,
+			})
+			return container.CreateResponse{}, err
+		}
+	}
+
+	if err := c.copyInputs(ctx, cont.ID); err != nil {
+		_ = c.cli.ContainerRemove(context.Background(), cont.ID, container.RemoveOptions{
+			RemoveVolumes: true,
+			Force:         true,
+		})
+		return container.CreateResponse{}, err
+	}
 
+	c.transition(StateCreated, -1)
+	return cont, nil
+}
+
+func (c *Cmd) start() error {
 	var ctx context.Context
 	if c.ctx != nil {
 		ctx = c.ctx
@@ -231,33 +739,123 @@ func (c *Cmd) Start() error {
 		ctx = context.Background()
 	}
 
-	if c.Stdin != nil {
-		c.Config.OpenStdin = true
+	if err := c.waitDependencies(ctx); err != nil {
+		c.closeDescriptors(c.closeAfterStdin)
+		c.closeDescriptors(c.closeAfterOutput)
+		c.closeDescriptors(c.closeAfterWait)
+		return err
 	}
 
-	cont, err := c.cli.ContainerCreate(
-		ctx,
-		c.Config,
-		c.HostConfig,
-		c.Networkingconfig,
-		c.Platform,
-		c.ContainerName,
-	)
-	if err != nil {
+	if err := c.checkImageGate(ctx); err != nil {
 		c.closeDescriptors(c.closeAfterStdin)
 		c.closeDescriptors(c.closeAfterOutput)
 		c.closeDescriptors(c.closeAfterWait)
 		return err
 	}
 
-	c.Warnings = cont.Warnings
+	if c.standbyID == "" {
+		if err := c.ensureImage(ctx); err != nil {
+			c.closeDescriptors(c.closeAfterStdin)
+			c.closeDescriptors(c.closeAfterOutput)
+			c.closeDescriptors(c.closeAfterWait)
+			return err
+		}
+	}
 
-	attach, err := c.cli.ContainerAttach(ctx, cont.ID, container.AttachOptions{
-		Stream: true,
-		Stdin:  c.Stdin != nil,
-		Stdout: c.Stdout != nil,
-		Stderr: c.Stderr != nil,
-	})
+	if c.Cache != nil && c.OutputFrames == nil && c.OrderedOutput == nil && len(c.Inputs) == 0 && len(c.Artifacts) == 0 && c.standbyID == "" {
+		hit, err := c.checkCache(ctx)
+		if err != nil {
+			c.closeDescriptors(c.closeAfterStdin)
+			c.closeDescriptors(c.closeAfterOutput)
+			c.closeDescriptors(c.closeAfterWait)
+			return err
+		}
+		if hit {
+			return nil
+		}
+	}
+
+	if c.Limiter != nil {
+		if err := c.Limiter.acquire(ctx); err != nil {
+			c.closeDescriptors(c.closeAfterStdin)
+			c.closeDescriptors(c.closeAfterOutput)
+			c.closeDescriptors(c.closeAfterWait)
+			return err
+		}
+		c.limiterAcquired = true
+	}
+
+	if c.standbyID == "" {
+		if err := c.prepare(); err != nil {
+			c.closeDescriptors(c.closeAfterStdin)
+			c.closeDescriptors(c.closeAfterOutput)
+			c.closeDescriptors(c.closeAfterWait)
+			return err
+		}
+	}
+
+	if err := c.reserveQuota(); err != nil {
+		c.closeDescriptors(c.closeAfterStdin)
+		c.closeDescriptors(c.closeAfterOutput)
+		c.closeDescriptors(c.closeAfterWait)
+		return err
+	}
+
+	var cont container.CreateResponse
+	if c.standbyID != "" {
+		cont.ID = c.standbyID
+	} else {
+		var err error
+		cont, err = c.create(ctx)
+		if err != nil {
+			c.closeDescriptors(c.closeAfterStdin)
+			c.closeDescriptors(c.closeAfterOutput)
+			c.closeDescriptors(c.closeAfterWait)
+			return err
+		}
+	}
+
+	if c.Audit != nil {
+		c.resolveImageDigest(ctx, cont.ID)
+	}
+
+	if c.joinState != "" {
+		c.join(ctx, cont.ID)
+		return nil
+	}
+
+	c.setOutputContainer(cont.ID)
+	stdout, stderr := c.outputWriters()
+
+	// Standby containers are always created with an open stdin, which must be closed if unused.
+	stdin := c.Stdin
+	if stdin == nil && c.standbyID != "" {
+		stdin = strings.NewReader("")
+	}
+
+	// Attaching and registering the wait for the next exit are independent round trips to the
+	// daemon, so overlap them, only starting the container once both are done. There's no need to
+	// attach at all when there's no standard I/O to copy.
+	var attach types.HijackedResponse
+	var err error
+	attached := make(chan struct{})
+	if stdin != nil || stdout != nil || stderr != nil {
+		go func() {
+			defer close(attached)
+			attach, err = c.cli.ContainerAttach(ctx, cont.ID, container.AttachOptions{
+				Stream: true,
+				Stdin:  stdin != nil,
+				Stdout: stdout != nil,
+				Stderr: stderr != nil,
+			})
+		}()
+	} else {
+		close(attached)
+	}
+
+	c.waitCh, c.waitErrCh = c.cli.ContainerWait(ctx, cont.ID, container.WaitConditionNextExit)
+
+	<-attached
 	if err != nil {
 		c.closeDescriptors(c.closeAfterStdin)
 		c.closeDescriptors(c.closeAfterOutput)
@@ -266,19 +864,20 @@ func (c *Cmd) Start() error {
 			RemoveVolumes: true,
 			Force:         true,
 		})
-		return err
+		return wrapError("attach container", err)
 	}
-	c.closeAfterWait = append(c.closeAfterWait, attach.Conn)
-
-	if c.Stdin != nil {
-		c.stdin(attach)
+	if attach.Conn != nil {
+		c.attachConn = attach.Conn
+		c.closeAfterWait = append(c.closeAfterWait, attach.Conn)
 	}
 
-	if c.Stdout != nil || c.Stderr != nil {
-		c.stdoutStderr(attach)
+	if stdin != nil {
+		c.stdin(attach, stdin)
 	}
 
-	c.waitCh, c.waitErrCh = c.cli.ContainerWait(ctx, cont.ID, container.WaitConditionNextExit)
+	if stdout != nil || stderr != nil {
+		c.stdoutStderr(attach, stdout, stderr)
+	}
 
 	err = c.cli.ContainerStart(ctx, cont.ID, container.StartOptions{})
 	if err != nil {
@@ -289,19 +888,25 @@ func (c *Cmd) Start() error {
 			RemoveVolumes: true,
 			Force:         true,
 		})
-		return err
+		return wrapError("start container", err)
 	}
 
 	c.ContainerID = cont.ID
+	c.setStartedState()
+	c.transition(StateStarted, -1)
+	c.watchingRemoval = c.watchRemoval(cont.ID)
 
-	// Don't allocate the channel unless there are goroutines to fire.
-	if len(c.goroutine) > 0 {
-		c.errch = make(chan error, len(c.goroutine))
-		for _, fn := range c.goroutine {
-			go func(fn func() error) {
-				c.errch <- fn()
-			}(fn)
-		}
+	c.startGoroutines()
+
+	if c.CollectUsage {
+		c.collectUsage(cont.ID)
+	}
+
+	if c.MaxRuntime > 0 {
+		c.deadlineTimer = time.AfterFunc(c.MaxRuntime, func() {
+			c.deadlineExceeded.Store(true)
+			_ = c.cli.ContainerStop(context.Background(), cont.ID, container.StopOptions{})
+		})
 	}
 
 	if c.ctx != nil {
@@ -319,6 +924,23 @@ func (c *Cmd) Start() error {
 	return nil
 }
 
+// startGoroutines starts the goroutines copying the standard streams of the container.
+func (c *Cmd) startGoroutines() {
+	// Don't allocate the channel unless there are goroutines to fire.
+	if len(c.goroutine) > 0 {
+		c.errch = make(chan error, len(c.goroutine))
+		for _, fn := range c.goroutine {
+			go func(fn func() error) {
+				c.errch <- fn()
+			}(fn)
+		}
+	}
+}
+
+// ErrMaxRuntimeExceeded is returned by Wait when the container was stopped due to exceeding
+// Cmd.MaxRuntime.
+var ErrMaxRuntimeExceeded = errors.New("dockerexec: max runtime exceeded")
+
 // An ExitError reports an unsuccessful exit by a container.
 type ExitError struct {
 	StatusCode int64
@@ -350,8 +972,10 @@ func (e *ExitError) Error() string {
 // status.
 //
 // If the container fails to run or doesn't complete successfully, the
-// error is of type *ExitError. Other error types may be
-// returned for I/O problems.
+// error is of type *ExitError. If waiting for the container fails, the
+// error is of type *WaitError, and if copying any of its standard streams
+// fails, the error is of type *CopyError. Other error types may be
+// returned for other situations.
 //
 // Wait also waits for the respective I/O loop copying to or from the container to complete.
 //
@@ -359,6 +983,9 @@ func (e *ExitError) Error() string {
 func (c *Cmd) Wait() error {
 	var err error
 
+	if c.Cached() {
+		return c.waitCached()
+	}
 	if len(c.ContainerID) == 0 {
 		return errors.New("dockerexec: not started")
 	}
@@ -367,30 +994,75 @@ func (c *Cmd) Wait() error {
 	}
 	c.finished = true
 
-	select {
-	case waitResult := <-c.waitCh:
-		if waitResult.Error != nil {
-			err = errors.New(waitResult.Error.Message)
-		}
+	if c.adopted {
+		// The container might have already exited, so we can't wait for the next exit.
+		c.waitCh, c.waitErrCh = c.cli.ContainerWait(c.context(), c.ContainerID, container.WaitConditionNotRunning)
+	}
 
-		c.StatusCode = waitResult.StatusCode
-	case err = <-c.waitErrCh:
+	statusCode, err := c.rewait(receiveWait(c.waitCh, c.waitErrCh))
+	if statusCode != -1 {
+		c.StatusCode = statusCode
+	}
+	err = c.checkRemoved(err)
+	if err == nil || errors.Is(err, ErrContainerRemoved) {
+		c.transition(StateExited, c.StatusCode)
+	}
+	if errors.Is(err, ErrContainerRemoved) {
+		c.transition(StateRemoved, -1)
+	}
+	if !c.watchingRemoval {
+		defer c.finishLifecycle()
 	}
 	if c.waitDone != nil {
 		close(c.waitDone)
 	}
+	if c.deadlineTimer != nil {
+		c.deadlineTimer.Stop()
+	}
+	c.releaseLimiter()
+	c.releaseQuota()
+	c.stopCollectingUsage()
 
 	var copyError error
 	for range c.goroutine {
-		if err := <-c.errch; err != nil && copyError == nil {
+		if err := <-c.errch; err != nil && copyError == nil && !isBenignCopyError(err) {
 			copyError = err
 		}
 	}
 
 	c.closeDescriptors(c.closeAfterWait)
+	c.sumOutputHashes()
+
+	var artifactsErr error
+	if !errors.Is(err, ErrContainerRemoved) {
+		if c.OrderedOutput != nil {
+			if err := c.copyOrderedOutput(); err != nil && copyError == nil {
+				copyError = err
+			}
+		}
+		artifactsErr = c.copyArtifacts()
+	}
+	if c.removeAfterWait {
+		_ = c.cli.ContainerRemove(context.Background(), c.ContainerID, container.RemoveOptions{RemoveVolumes: true})
+	}
 
+	err = c.exitError(err, copyError)
+	if err == nil {
+		err = artifactsErr
+	}
+	err = c.debugOnFailure(err)
+	c.storeCached(err)
+	c.audit(err)
+	return err
+}
+
+// exitError returns the error Wait should return given the error from waiting for the container
+// and from copying its I/O.
+func (c *Cmd) exitError(err, copyError error) error {
 	if err != nil {
 		return err
+	} else if c.deadlineExceeded.Load() {
+		return ErrMaxRuntimeExceeded
 	} else if c.StatusCode != 0 {
 		return &ExitError{StatusCode: c.StatusCode}
 	}
@@ -398,6 +1070,83 @@ func (c *Cmd) Wait() error {
 	return copyError
 }
 
+// receiveWait receives the result of a ContainerWait call, returning the status code of the
+// container, or -1 if it's unknown.
+func receiveWait(waitCh <-chan container.WaitResponse, errCh <-chan error) (int64, error) {
+	select {
+	case waitResult := <-waitCh:
+		if waitResult.Error != nil {
+			return waitResult.StatusCode, &WaitError{Err: errors.New(waitResult.Error.Message)}
+		}
+		return waitResult.StatusCode, nil
+	case err := <-errCh:
+		if err == context.Canceled || err == context.DeadlineExceeded {
+			return -1, err
+		}
+		return -1, &WaitError{Err: err}
+	}
+}
+
+// WaitContainer waits for the container with the given ID to exit, returning immediately if it
+// isn't running.
+//
+// The returned error is nil if the container exits with a zero exit status. If the container
+// doesn't complete successfully, the error is of type *ExitError. Other error types may be returned
+// for other situations, just like Cmd.Wait.
+func WaitContainer(ctx context.Context, cli Client, id string) error {
+	waitCh, errCh := cli.ContainerWait(ctx, id, container.WaitConditionNotRunning)
+	statusCode, err := receiveWait(waitCh, errCh)
+	if err != nil {
+		return err
+	} else if statusCode != 0 {
+		return &ExitError{StatusCode: statusCode}
+	}
+	return nil
+}
+
+// Kill sends a signal to the container, e.g. "SIGKILL" or "SIGTERM". An empty signal sends the
+// container's configured stop signal, defaulting to SIGKILL.
+//
+// The container must have been started by Start.
+func (c *Cmd) Kill(signal string) error {
+	if len(c.ContainerID) == 0 {
+		return errors.New("dockerexec: not started")
+	}
+	return wrapError("kill container", c.cli.ContainerKill(c.context(), c.ContainerID, signal))
+}
+
+// Inspect returns the low-level information on the container from the daemon.
+//
+// The container must have been started by Start.
+func (c *Cmd) Inspect() (types.ContainerJSON, error) {
+	if len(c.ContainerID) == 0 {
+		return types.ContainerJSON{}, errors.New("dockerexec: not started")
+	}
+	inspect, err := c.cli.ContainerInspect(c.context(), c.ContainerID)
+	return inspect, wrapError("inspect container", err)
+}
+
+// Logs returns the logs of the container as retained by the daemon's log driver.
+//
+// Unless Config.Tty is set, the stream is multiplexed and should be demultiplexed using
+// stdcopy.StdCopy.
+//
+// The container must have been started by Start. Note that by default, the container is removed
+// once it exits, see HostConfig.AutoRemove. If the log driver of the container doesn't support
+// reading logs back, a *LogDriverError is returned.
+func (c *Cmd) Logs(options container.LogsOptions) (io.ReadCloser, error) {
+	if len(c.ContainerID) == 0 {
+		return nil, errors.New("dockerexec: not started")
+	}
+	if c.HostConfig != nil {
+		if err := checkLogsReadable(c.HostConfig.LogConfig); err != nil {
+			return nil, err
+		}
+	}
+	logs, err := c.cli.ContainerLogs(c.context(), c.ContainerID, options)
+	return logs, wrapError("container logs", err)
+}
+
 // Output runs the container and returns its standard output.
 // Any returned error will usually be of type *ExitError.
 // If c.Stderr was nil, Output populates ExitError.Stderr.
@@ -415,7 +1164,9 @@ func (c *Cmd) Output() ([]byte, error) {
 
 	err := c.Run()
 	if err != nil && captureErr {
-		if ee, ok := err.(*ExitError); ok {
+		// The ExitError might be wrapped, such as by a DebugError.
+		var ee *ExitError
+		if errors.As(err, &ee) {
 			ee.Stderr = c.Stderr.(*prefixSuffixSaver).Bytes()
 		}
 	}
@@ -440,6 +1191,139 @@ func (c *Cmd) CombinedOutput() ([]byte, error) {
 	return b.Bytes(), err
 }
 
+// CombinedOutputFunc is like CombinedOutput but also calls fn with each chunk of the combined
+// output as it arrives, e.g. to display progress for long running commands. fn must not retain
+// the chunk.
+func (c *Cmd) CombinedOutputFunc(fn func(chunk []byte)) ([]byte, error) {
+	if c.Stdout != nil {
+		return nil, errors.New("dockerexec: Stdout already set")
+	}
+	if c.Stderr != nil {
+		return nil, errors.New("dockerexec: Stderr already set")
+	}
+	w := &chunkWriter{fn: fn}
+	c.Stdout = w
+	if !c.Config.Tty {
+		c.Stderr = w
+	}
+	err := c.Run()
+	return w.buf.Bytes(), err
+}
+
+// chunkWriter collects the output written to it, passing each write to fn.
+type chunkWriter struct {
+	buf bytes.Buffer
+	fn  func(chunk []byte)
+}
+
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+	w.fn(p)
+	return len(p), nil
+}
+
+// Stream identifies one of the standard streams of a container.
+type Stream int
+
+// Standard streams of a container.
+const (
+	StreamStdin  Stream = 0
+	StreamStdout Stream = 1
+	StreamStderr Stream = 2
+)
+
+func (s Stream) String() string {
+	switch s {
+	case StreamStdin:
+		return "stdin"
+	case StreamStdout:
+		return "stdout"
+	case StreamStderr:
+		return "stderr"
+	default:
+		return "Stream(" + strconv.Itoa(int(s)) + ")"
+	}
+}
+
+// OutputChunk is a chunk of output received from one of the output streams of a container.
+type OutputChunk struct {
+	Stream Stream
+	Data   []byte
+}
+
+// TaggedOutput is the output of a container as a sequence of chunks from its output streams, in
+// the order they were received.
+type TaggedOutput []OutputChunk
+
+// Bytes returns the output of the given stream.
+func (o TaggedOutput) Bytes(stream Stream) []byte {
+	var b bytes.Buffer
+	for _, chunk := range o {
+		if chunk.Stream == stream {
+			b.Write(chunk.Data)
+		}
+	}
+	return b.Bytes()
+}
+
+// Combined returns the combined output of all streams, like CombinedOutput.
+func (o TaggedOutput) Combined() []byte {
+	var b bytes.Buffer
+	for _, chunk := range o {
+		b.Write(chunk.Data)
+	}
+	return b.Bytes()
+}
+
+// taggedOutputRecorder records writes to its streams into a TaggedOutput.
+type taggedOutputRecorder struct {
+	mu     sync.Mutex
+	output TaggedOutput
+}
+
+func (r *taggedOutputRecorder) writer(stream Stream) io.Writer {
+	return taggedOutputWriter{r: r, stream: stream}
+}
+
+type taggedOutputWriter struct {
+	r      *taggedOutputRecorder
+	stream Stream
+}
+
+func (w taggedOutputWriter) Write(p []byte) (int, error) {
+	w.r.mu.Lock()
+	defer w.r.mu.Unlock()
+
+	// Coalesce consecutive writes to the same stream.
+	if n := len(w.r.output); n > 0 && w.r.output[n-1].Stream == w.stream {
+		w.r.output[n-1].Data = append(w.r.output[n-1].Data, p...)
+	} else {
+		w.r.output = append(w.r.output, OutputChunk{Stream: w.stream, Data: bytes.Clone(p)})
+	}
+	return len(p), nil
+}
+
+// TaggedOutput runs the container and returns its standard output and standard error as a single
+// sequence of chunks tagged with the stream they came from, preserving the order in which they
+// were received.
+//
+// When using Config.Tty, all output is received on StreamStdout.
+func (c *Cmd) TaggedOutput() (TaggedOutput, error) {
+	if c.Stdout != nil {
+		return nil, errors.New("dockerexec: Stdout already set")
+	}
+	if c.Stderr != nil {
+		return nil, errors.New("dockerexec: Stderr already set")
+	}
+	var r taggedOutputRecorder
+	c.Stdout = r.writer(StreamStdout)
+	if !c.Config.Tty {
+		c.Stderr = r.writer(StreamStderr)
+	}
+	err := c.Run()
+	return r.output, err
+}
+
 // StdinPipe returns a pipe that will be connected to the container's
 // standard input when the container starts.
 // The pipe will be closed automatically after Wait sees the container exit.
@@ -521,6 +1405,72 @@ func (c *Cmd) StderrPipe() (io.ReadCloser, error) {
 	return pr, nil
 }
 
+// linesBufferSize is the number of lines buffered by the channels returned by StdoutLines and
+// StderrLines before applying backpressure to the container.
+const linesBufferSize = 64
+
+// StdoutLines returns a channel that will receive the lines of the container's standard output,
+// without their line endings, when the container starts. The channel is closed once the output
+// reaches EOF.
+//
+// The channel is buffered, but once the buffer is full, copying of the output from the container
+// blocks until lines are received. Wait does not complete until all output has been copied, so
+// callers must keep receiving from the channel until it's closed before or while calling Wait.
+// For the same reason, it is incorrect to call Run when using StdoutLines.
+func (c *Cmd) StdoutLines() (<-chan string, error) {
+	if c.Stdout != nil {
+		return nil, errors.New("dockerexec: Stdout already set")
+	}
+	if len(c.ContainerID) != 0 {
+		return nil, errors.New("dockerexec: StdoutLines after container started")
+	}
+	pr, pw := io.Pipe()
+	c.Stdout = pw
+	c.closeAfterOutput = append(c.closeAfterOutput, pw)
+	c.closeAfterWait = append(c.closeAfterWait, pr)
+	return readLines(pr), nil
+}
+
+// StderrLines returns a channel that will receive the lines of the container's standard error,
+// without their line endings, when the container starts. The channel is closed once the output
+// reaches EOF.
+//
+// See StdoutLines for the blocking behavior.
+func (c *Cmd) StderrLines() (<-chan string, error) {
+	if c.Stderr != nil {
+		return nil, errors.New("dockerexec: Stderr already set")
+	}
+	if len(c.ContainerID) != 0 {
+		return nil, errors.New("dockerexec: StderrLines after container started")
+	}
+	pr, pw := io.Pipe()
+	c.Stderr = pw
+	c.closeAfterOutput = append(c.closeAfterOutput, pw)
+	c.closeAfterWait = append(c.closeAfterWait, pr)
+	return readLines(pr), nil
+}
+
+func readLines(r io.Reader) <-chan string {
+	ch := make(chan string, linesBufferSize)
+	go func() {
+		defer close(ch)
+
+		br := bufio.NewReader(r)
+		for {
+			line, err := br.ReadString('\n')
+			if len(line) > 0 {
+				line = strings.TrimSuffix(line, "\n")
+				line = strings.TrimSuffix(line, "\r")
+				ch <- line
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return ch
+}
+
 // prefixSuffixSaver is an io.Writer which retains the first N bytes
 // and the last N bytes written to it. The Bytes() methods reconstructs
 // it with a pretty error message.
@@ -4,15 +4,21 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"strings"
+	"sync/atomic"
+	"syscall"
 	"testing"
+	"time"
 
+	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/client"
 	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -106,6 +112,17 @@ func TestCatGoodAndBadFile(t *testing.T) {
 	assert.Contains(t, body, "Ubuntu")
 }
 
+func TestCombinedOutputFunc(t *testing.T) {
+	var chunks []string
+	bs, err := dockerexec.Command(dockerClient, testImage, "sh", "-c", "echo one; sleep 1; echo two >&2").CombinedOutputFunc(func(chunk []byte) {
+		chunks = append(chunks, string(chunk))
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "one\ntwo\n", string(bs))
+	assert.Equal(t, string(bs), strings.Join(chunks, ""))
+	assert.GreaterOrEqual(t, len(chunks), 2)
+}
+
 func TestNoExistExecutable(t *testing.T) {
 	// Can't run a non-existent executable
 	err := dockerexec.Command(dockerClient, testImage, "/no-exist-executable").Run()
@@ -214,7 +231,7 @@ func TestStartCancelledContext(t *testing.T) {
 
 func TestCmdString(t *testing.T) {
 	cmd := dockerexec.Command(dockerClient, testImage, "sh", "-c", "echo Hello, World!")
-	assert.Equal(t, "sh -c echo Hello, World!", cmd.String())
+	assert.Equal(t, "sh -c 'echo Hello, World!'", cmd.String())
 }
 
 func TestStdoutStartWait(t *testing.T) {
@@ -321,3 +338,402 @@ func TestWaitTwice(t *testing.T) {
 	err = cmd.Wait()
 	assert.EqualError(t, err, "dockerexec: Wait was already called")
 }
+
+func TestHostname(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "hostname")
+	cmd.Hostname = "dockerexec-test"
+
+	output, err := cmd.Output()
+	require.NoError(t, err)
+	assert.Equal(t, "dockerexec-test\n", string(output))
+}
+
+func TestAddHost(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "getent", "hosts", "example.internal", "host.docker.internal")
+	cmd.AddHost("example.internal", "10.1.2.3")
+	cmd.AddHost("host.docker.internal", dockerexec.HostGateway)
+
+	output, err := cmd.Output()
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	require.Len(t, lines, 2)
+	assert.Equal(t, []string{"10.1.2.3", "example.internal"}, strings.Fields(lines[0]))
+	assert.Contains(t, lines[1], "host.docker.internal")
+}
+
+func TestFromContainer(t *testing.T) {
+	cont, err := dockerClient.ContainerCreate(context.Background(), &container.Config{
+		Image: testImage,
+		Cmd:   []string{"sh", "-c", "echo Hello, World!; exit 3"},
+	}, &container.HostConfig{AutoRemove: true}, nil, nil, "")
+	require.NoError(t, err)
+
+	err = dockerClient.ContainerStart(context.Background(), cont.ID, container.StartOptions{})
+	require.NoError(t, err)
+
+	cmd, err := dockerexec.FromContainer(dockerClient, cont.ID)
+	require.NoError(t, err)
+	assert.Equal(t, cont.ID, cmd.ContainerID)
+	assert.Equal(t, "sh -c 'echo Hello, World!; exit 3'", cmd.String())
+
+	err = cmd.Start()
+	assert.EqualError(t, err, "dockerexec: already started")
+
+	err = cmd.Wait()
+	if err, ok := err.(*dockerexec.ExitError); ok {
+		assert.Equal(t, int64(3), err.StatusCode)
+	} else {
+		t.Fail()
+	}
+	assert.Equal(t, int64(3), cmd.StatusCode)
+}
+
+func TestKill(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "sleep", "120")
+
+	err := cmd.Kill("SIGKILL")
+	assert.EqualError(t, err, "dockerexec: not started")
+
+	err = cmd.Start()
+	require.NoError(t, err)
+
+	inspect, err := cmd.Inspect()
+	require.NoError(t, err)
+	assert.True(t, inspect.State.Running)
+
+	err = cmd.Kill("SIGKILL")
+	require.NoError(t, err)
+
+	err = cmd.Wait()
+	assert.IsType(t, &dockerexec.ExitError{}, err)
+	assert.Equal(t, int64(137), cmd.StatusCode)
+}
+
+func TestLogs(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "sh", "-c", "echo stdout; echo stderr >&2")
+	cmd.HostConfig.AutoRemove = false
+	defer func() {
+		_ = dockerClient.ContainerRemove(context.Background(), cmd.ContainerID, container.RemoveOptions{Force: true})
+	}()
+
+	err := cmd.Run()
+	require.NoError(t, err)
+
+	logs, err := cmd.Logs(container.LogsOptions{ShowStdout: true, ShowStderr: true})
+	require.NoError(t, err)
+	defer logs.Close()
+
+	var stdout, stderr bytes.Buffer
+	_, err = stdcopy.StdCopy(&stdout, &stderr, logs)
+	require.NoError(t, err)
+	assert.Equal(t, "stdout\n", stdout.String())
+	assert.Equal(t, "stderr\n", stderr.String())
+}
+
+func TestWaitContainer(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "sh", "-c", "sleep 1; exit 42")
+	err := cmd.Start()
+	require.NoError(t, err)
+	defer cmd.Wait() //nolint:errcheck
+
+	err = dockerexec.WaitContainer(context.Background(), dockerClient, cmd.ContainerID)
+	if err, ok := err.(*dockerexec.ExitError); ok {
+		assert.EqualError(t, err, "exit status 42")
+		assert.Equal(t, int64(42), err.StatusCode)
+	} else {
+		t.Fail()
+	}
+}
+
+func TestKeepStdinOpen(t *testing.T) {
+	// Without KeepStdinOpen, the second read would see EOF instead of timing out.
+	cmd := dockerexec.Command(dockerClient, testImage, "bash", "-c", "read -r line; echo $line; read -r -t 2 line; echo $?")
+	cmd.Stdin = strings.NewReader("input\n")
+	cmd.KeepStdinOpen = true
+
+	output, err := cmd.Output()
+	require.NoError(t, err)
+
+	// read -t exits with a status greater than 128 on timeout, as opposed to 1 on EOF.
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	require.Len(t, lines, 2)
+	assert.Equal(t, "input", lines[0])
+	assert.NotEqual(t, "1", lines[1])
+}
+
+func TestMaxRuntime(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "sleep", "120")
+	cmd.MaxRuntime = time.Second
+	// sleep ignores SIGTERM as PID 1, so this also makes sure the container is eventually killed.
+	cmd.Config.StopTimeout = new(int)
+
+	start := time.Now()
+	err := cmd.Run()
+	assert.ErrorIs(t, err, dockerexec.ErrMaxRuntimeExceeded)
+	assert.Less(t, time.Since(start), 30*time.Second)
+	assert.NotEqual(t, int64(0), cmd.StatusCode)
+}
+
+func TestLines(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "sh", "-c", "echo line 1; echo error >&2; echo line 2; printf partial")
+
+	stdout, err := cmd.StdoutLines()
+	require.NoError(t, err)
+	stderr, err := cmd.StderrLines()
+	require.NoError(t, err)
+
+	err = cmd.Start()
+	require.NoError(t, err)
+
+	var stdoutLines, stderrLines []string
+	for line := range stdout {
+		stdoutLines = append(stdoutLines, line)
+	}
+	for line := range stderr {
+		stderrLines = append(stderrLines, line)
+	}
+
+	err = cmd.Wait()
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"line 1", "line 2", "partial"}, stdoutLines)
+	assert.Equal(t, []string{"error"}, stderrLines)
+}
+
+func TestTaggedOutput(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "sh", "-c", "echo out 1; sleep 0.5; echo err 1 >&2; sleep 0.5; echo out 2")
+
+	output, err := cmd.TaggedOutput()
+	require.NoError(t, err)
+
+	assert.Equal(t, dockerexec.TaggedOutput{
+		{Stream: dockerexec.StreamStdout, Data: []byte("out 1\n")},
+		{Stream: dockerexec.StreamStderr, Data: []byte("err 1\n")},
+		{Stream: dockerexec.StreamStdout, Data: []byte("out 2\n")},
+	}, output)
+	assert.Equal(t, "out 1\nout 2\n", string(output.Bytes(dockerexec.StreamStdout)))
+	assert.Equal(t, "err 1\n", string(output.Bytes(dockerexec.StreamStderr)))
+	assert.Equal(t, "out 1\nerr 1\nout 2\n", string(output.Combined()))
+}
+
+func TestEffectiveCommand(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "sh", "-c", "echo Hello, World!")
+	command, err := cmd.EffectiveCommand(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sh", "-c", "echo Hello, World!"}, command)
+
+	// Falls back to the image's command.
+	cmd.Config.Cmd = nil
+	command, err = cmd.EffectiveCommand(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/bin/bash"}, command)
+
+	cmd.Config.Entrypoint = []string{"/bin/sh", "-c"}
+	cmd.Config.Cmd = []string{"true"}
+	command, err = cmd.EffectiveCommand(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/bin/sh", "-c", "true"}, command)
+}
+
+func TestErrorWrapping(t *testing.T) {
+	_, err := dockerexec.FromContainer(dockerClient, "dockerexec-does-not-exist")
+	assert.True(t, client.IsErrNotFound(err))
+	assert.ErrorContains(t, err, "dockerexec: inspect container: ")
+
+	cmd := dockerexec.Command(dockerClient, "dockerexec-does-not-exist:latest", "true")
+	err = cmd.Run()
+	assert.True(t, client.IsErrNotFound(err))
+	assert.ErrorContains(t, err, "dockerexec: create container: ")
+}
+
+func TestErr(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, "", "true")
+	assert.EqualError(t, cmd.Err, "dockerexec: no image specified")
+	assert.Equal(t, cmd.Err, cmd.Run())
+
+	cmd = dockerexec.Command(dockerClient, testImage, "true")
+	cmd.Err = errors.New("bad configuration")
+	assert.EqualError(t, cmd.Start(), "bad configuration")
+	assert.Empty(t, cmd.ContainerID)
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func TestCopyError(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "sh", "-c", "echo stdout; echo stderr >&2")
+	cmd.Stdout = io.Discard
+	cmd.Stderr = failingWriter{}
+	err := cmd.Run()
+
+	var copyErr *dockerexec.CopyError
+	require.ErrorAs(t, err, &copyErr)
+	assert.Equal(t, dockerexec.StreamStderr, copyErr.Stream)
+	assert.EqualError(t, err, "dockerexec: copy stderr: write failed")
+	assert.EqualValues(t, 0, cmd.StatusCode)
+}
+
+type epipeWriter struct{}
+
+func (epipeWriter) Write(p []byte) (int, error) {
+	return 0, syscall.EPIPE
+}
+
+func TestCopyErrorBrokenPipe(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "echo", "hello")
+	cmd.Stdout = epipeWriter{}
+	err := cmd.Run()
+
+	var copyErr *dockerexec.CopyError
+	require.ErrorAs(t, err, &copyErr)
+	assert.ErrorIs(t, err, syscall.EPIPE)
+}
+
+func TestCopyErrorConnectionReset(t *testing.T) {
+	var reads atomic.Int32
+	cli := dockerexec.WithFaults(dockerClient, dockerexec.FaultInjectorFunc(func(ctx context.Context, stage dockerexec.FaultStage, id string) error {
+		if stage == dockerexec.FaultCopy && reads.Add(1) > 1 {
+			return syscall.ECONNRESET
+		}
+		return nil
+	}))
+	cmd := dockerexec.Command(cli, testImage, "sh", "-c", "echo hello; sleep 2; echo world")
+	_, err := cmd.Output()
+
+	// The connection was reset while the container was still running, truncating its output
+	var copyErr *dockerexec.CopyError
+	require.ErrorAs(t, err, &copyErr)
+	assert.ErrorIs(t, err, syscall.ECONNRESET)
+}
+
+func TestStdinAfterExit(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "true")
+	cmd.Stdin = bytes.NewReader(make([]byte, 64<<20))
+	assert.NoError(t, cmd.Run())
+}
+
+func TestContainerRemoved(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "sleep", "120")
+	cmd.HostConfig.AutoRemove = false
+	require.NoError(t, cmd.Start())
+
+	err := dockerClient.ContainerRemove(context.Background(), cmd.ContainerID, container.RemoveOptions{Force: true})
+	require.NoError(t, err)
+
+	err = cmd.Wait()
+	require.ErrorIs(t, err, dockerexec.ErrContainerRemoved)
+	var removedErr *dockerexec.ContainerRemovedError
+	require.ErrorAs(t, err, &removedErr)
+	assert.Equal(t, cmd.ContainerID, removedErr.ContainerID)
+	assert.False(t, removedErr.State.Running)
+}
+
+func TestDaemonRestartTolerance(t *testing.T) {
+	// Simulate losing the connection to the daemon mid-run, failing the first wait, and resetting
+	// the attach connection after the first output was read.
+	var waits, reads atomic.Int32
+	cli := dockerexec.WithFaults(dockerClient, dockerexec.FaultInjectorFunc(func(ctx context.Context, stage dockerexec.FaultStage, id string) error {
+		switch {
+		case stage == dockerexec.FaultWait && waits.Add(1) == 1:
+			return syscall.ECONNRESET
+		case stage == dockerexec.FaultCopy && reads.Add(1) == 2:
+			return syscall.ECONNRESET
+		}
+		return nil
+	}))
+
+	cmd := dockerexec.Command(cli, testImage, "sh", "-c", "echo before; sleep 2; echo after; exit 3")
+	cmd.DaemonRestartTolerance = 10 * time.Second
+	output, err := cmd.Output()
+	var exitErr *dockerexec.ExitError
+	require.ErrorAs(t, err, &exitErr)
+	assert.EqualValues(t, 3, exitErr.StatusCode)
+	assert.Equal(t, "before\nafter\n", string(output))
+	assert.GreaterOrEqual(t, waits.Load(), int32(2), "should have waited again")
+	assert.Greater(t, reads.Load(), int32(2), "should have re-attached")
+}
+
+func TestDaemonRestartToleranceDetach(t *testing.T) {
+	// Reset the attach connection after the first output was read, so Detach has to close the
+	// connection re-attached with.
+	var reads atomic.Int32
+	cli := dockerexec.WithFaults(dockerClient, dockerexec.FaultInjectorFunc(func(ctx context.Context, stage dockerexec.FaultStage, id string) error {
+		if stage == dockerexec.FaultCopy && reads.Add(1) == 2 {
+			return syscall.ECONNRESET
+		}
+		return nil
+	}))
+
+	cmd := dockerexec.Command(cli, testImage, "sh", "-c", "echo before; sleep 30")
+	cmd.DaemonRestartTolerance = 10 * time.Second
+	cmd.Stdout = &bytes.Buffer{}
+	require.NoError(t, cmd.Start())
+	defer func() {
+		_ = cmd.Kill("SIGKILL")
+		_ = cmd.Wait()
+	}()
+
+	require.Eventually(t, func() bool {
+		return reads.Load() > 2
+	}, 10*time.Second, 50*time.Millisecond, "should have re-attached")
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Detach()
+	}()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("Detach blocked on the re-attached connection")
+	}
+}
+
+func TestDaemonRestartToleranceNormalExit(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "sh", "-c", "echo Hello, World!; exit 3")
+	cmd.DaemonRestartTolerance = 10 * time.Second
+	output, err := cmd.Output()
+	var exitErr *dockerexec.ExitError
+	require.ErrorAs(t, err, &exitErr)
+	assert.EqualValues(t, 3, exitErr.StatusCode)
+	assert.Equal(t, "Hello, World!\n", string(output))
+}
+
+func TestPidsLimit(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "sh", "-c", "for i in $(seq 20); do sleep 5 & done; wait")
+	cmd.PidsLimit = 5
+	cmd.HostConfig.AutoRemove = false
+	output, err := cmd.CombinedOutput()
+	require.NotEmpty(t, cmd.ContainerID)
+	defer func() {
+		_ = dockerClient.ContainerRemove(context.Background(), cmd.ContainerID, container.RemoveOptions{Force: true})
+	}()
+
+	var exitErr *dockerexec.ExitError
+	require.ErrorAs(t, err, &exitErr, string(output))
+	assert.Contains(t, string(output), "fork")
+
+	inspect, err := dockerClient.ContainerInspect(context.Background(), cmd.ContainerID)
+	require.NoError(t, err)
+	require.NotNil(t, inspect.HostConfig.PidsLimit)
+	assert.EqualValues(t, 5, *inspect.HostConfig.PidsLimit)
+}
+
+func TestShmSize(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "sh", "-c", "df -k /dev/shm | tail -n 1 | awk '{print $2}'")
+	cmd.ShmSize = "128m"
+	output, err := cmd.Output()
+	require.NoError(t, err)
+	assert.Equal(t, "131072\n", string(output))
+}
+
+func TestShmSizeInvalid(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "true")
+	cmd.ShmSize = "lots"
+	err := cmd.Run()
+	assert.ErrorContains(t, err, "dockerexec: invalid ShmSize")
+}
@@ -0,0 +1,55 @@
+package dockerexec
+
+import (
+	"context"
+	"errors"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// DryRun validates c as far as creating its container, and then removes the container without
+// ever starting it, e.g. to cheaply validate job specs. This checks that the image is resolvable
+// (pulling it according to Pull), that the platform is supported, that mounts are well-formed,
+// that ContainerName is free, that Inputs can be copied, and ImageGate and Policy.
+//
+// c itself is left unchanged, and can still be started afterwards. Dependencies, Limiter, Quota
+// and Cache aren't used, and a ContainerName already in use is an error regardless of
+// OnNameConflict.
+func (c *Cmd) DryRun(ctx context.Context) error {
+	if c.Err != nil {
+		return c.Err
+	}
+	if len(c.ContainerID) != 0 {
+		return errors.New("dockerexec: already started")
+	}
+	if err := c.ensureClient(); err != nil {
+		return err
+	}
+	if c.Config.Tty && c.Stderr != nil {
+		return errors.New("dockerexec: can't set both Config.Tty and Stderr")
+	}
+
+	dry := c.clone()
+	dry.OnNameConflict = NameConflictFail
+	dry.startTrace()
+
+	if err := dry.checkImageGate(ctx); err != nil {
+		return err
+	}
+	if err := dry.ensureImage(ctx); err != nil {
+		return err
+	}
+	if err := dry.prepare(); err != nil {
+		return err
+	}
+
+	cont, err := dry.create(ctx)
+	if err != nil {
+		return err
+	}
+
+	return wrapError("remove container", dry.cli.ContainerRemove(context.WithoutCancel(ctx), cont.ID, container.RemoveOptions{
+		RemoveVolumes: true,
+		Force:         true,
+	}))
+}
@@ -0,0 +1,65 @@
+package dockerexec_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestDryRun(t *testing.T) {
+	var trace dockerexec.Trace
+	cmd := dockerexec.Command(dockerClient, testImage, "echo", "hello")
+	cmd.Trace = &trace
+	err := cmd.DryRun(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, cmd.ContainerID)
+
+	var created string
+	for _, call := range trace.Calls() {
+		assert.NotEqual(t, "ContainerStart", call.Method)
+		if call.Method == "ContainerCreate" {
+			created = call.ID
+		}
+	}
+	require.NotEmpty(t, created)
+	_, err = dockerClient.ContainerInspect(context.Background(), created)
+	assert.Error(t, err)
+
+	output, err := cmd.Output()
+	require.NoError(t, err)
+	assert.Equal(t, "hello\n", string(output))
+}
+
+func TestDryRunInvalidMount(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "true")
+	cmd.HostConfig.Binds = []string{"./relative:/mnt"}
+	err := cmd.DryRun(context.Background())
+	assert.Error(t, err)
+}
+
+func TestDryRunNameInUse(t *testing.T) {
+	existing := dockerexec.Command(dockerClient, testImage, "sleep", "infinity")
+	existing.ContainerName = "dockerexec-test-dryrun"
+	require.NoError(t, existing.Start())
+	defer func() {
+		_ = dockerClient.ContainerRemove(context.Background(), existing.ContainerID, container.RemoveOptions{Force: true})
+	}()
+
+	cmd := dockerexec.Command(dockerClient, testImage, "true")
+	cmd.ContainerName = "dockerexec-test-dryrun"
+	cmd.OnNameConflict = dockerexec.NameConflictReuse
+	err := cmd.DryRun(context.Background())
+	var conflictErr *dockerexec.NameConflictError
+	assert.ErrorAs(t, err, &conflictErr)
+}
+
+func TestDryRunMissingImage(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, "dockerexec-does-not-exist:latest", "true")
+	err := cmd.DryRun(context.Background())
+	assert.Error(t, err)
+}
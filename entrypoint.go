@@ -0,0 +1,22 @@
+package dockerexec
+
+import "github.com/docker/docker/api/types/strslice"
+
+// SetEntrypoint overrides the entrypoint of the image, by setting Config.Entrypoint, so that the
+// container runs entrypoint followed by the command. With no arguments, the entrypoint of the
+// image is cleared, so that the container runs just the command, which is what Config.Entrypoint
+// being empty but non-nil means, as opposed to nil, which keeps the entrypoint of the image.
+func (c *Cmd) SetEntrypoint(entrypoint ...string) {
+	c.Config.Entrypoint = append(strslice.StrSlice{}, entrypoint...)
+}
+
+// ResetEntrypoint undoes SetEntrypoint, so that the container uses the entrypoint of the image.
+func (c *Cmd) ResetEntrypoint() {
+	c.Config.Entrypoint = nil
+}
+
+// WithEntrypoint is like SetEntrypoint, but returns c to allow chaining.
+func (c *Cmd) WithEntrypoint(entrypoint ...string) *Cmd {
+	c.SetEntrypoint(entrypoint...)
+	return c
+}
@@ -0,0 +1,41 @@
+package dockerexec_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+// entrypointImage is an image with an entrypoint, socat.
+const entrypointImage = "alpine/socat:1.8.0.0"
+
+func TestSetEntrypoint(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "-c", "echo hello").WithEntrypoint("sh")
+	output, err := cmd.Output()
+	require.NoError(t, err)
+	assert.Equal(t, "hello\n", string(output))
+}
+
+func TestClearEntrypoint(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, entrypointImage, "echo", "hello")
+	cmd.Pull = &dockerexec.PullConfig{}
+	cmd.SetEntrypoint()
+	assert.NotNil(t, cmd.Config.Entrypoint)
+	assert.Empty(t, cmd.Config.Entrypoint)
+	output, err := cmd.Output()
+	require.NoError(t, err)
+	assert.Equal(t, "hello\n", string(output))
+}
+
+func TestResetEntrypoint(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, entrypointImage, "-V").WithEntrypoint()
+	cmd.Pull = &dockerexec.PullConfig{}
+	cmd.ResetEntrypoint()
+	assert.Nil(t, cmd.Config.Entrypoint)
+	output, err := cmd.Output()
+	require.NoError(t, err)
+	assert.Contains(t, string(output), "socat version")
+}
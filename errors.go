@@ -0,0 +1,148 @@
+package dockerexec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// wrapError wraps an error from the daemon, or from talking to it, with a stable prefix naming
+// the failed operation, so that errors.Is and errors.As keep working through it, e.g. with
+// errdefs.IsNotFound or net.Error.
+//
+// Context errors are returned as is, matching the errors returned when the context is done
+// before reaching the daemon.
+func wrapError(op string, err error) error {
+	if err == nil || err == context.Canceled || err == context.DeadlineExceeded {
+		return err
+	}
+	return fmt.Errorf("dockerexec: %s: %w", op, err)
+}
+
+// WaitError is returned by Wait when waiting for the container failed, meaning the container
+// failed to run to completion or its outcome is unknown, as opposed to an *ExitError, where it
+// ran and exited with a non-zero status.
+type WaitError struct {
+	Err error
+}
+
+func (e *WaitError) Error() string {
+	return "dockerexec: wait container: " + e.Err.Error()
+}
+
+func (e *WaitError) Unwrap() error {
+	return e.Err
+}
+
+// CopyError is returned by Wait when copying one of the standard streams of the container failed,
+// even though the container itself might have exited successfully.
+//
+// For failures reading the output from the daemon rather than writing it to Stdout or Stderr,
+// Stream is StreamStdout, as the stream being read can't be told.
+type CopyError struct {
+	Stream Stream
+	Err    error
+
+	write bool // failed writing the output rather than reading it
+	ended bool // failed reading the output after the container exited or it was detached
+}
+
+func (e *CopyError) Error() string {
+	return "dockerexec: copy " + e.Stream.String() + ": " + e.Err.Error()
+}
+
+func (e *CopyError) Unwrap() error {
+	return e.Err
+}
+
+// copyError returns a *CopyError for err, if not nil.
+func copyError(stream Stream, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &CopyError{Stream: stream, Err: err}
+}
+
+// isClosedError reports whether err is the error of using a connection closed by either end.
+func isClosedError(err error) bool {
+	return errors.Is(err, net.ErrClosed) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, syscall.ECONNRESET)
+}
+
+// isBenignCopyError reports whether err is an error copying a standard stream that's expected
+// once the container exits, and shouldn't fail an otherwise successful run. This is the case when
+// writing to the standard input of a container that already exited, or reading its output after
+// it exited or its attachment was closed, see endCopyError. The same errors while the container
+// is running, or from writing the output to Stdout or Stderr, aren't benign.
+func isBenignCopyError(err error) bool {
+	var ce *CopyError
+	if !errors.As(err, &ce) {
+		return false
+	}
+	if ce.Stream == StreamStdin {
+		return isClosedError(ce.Err) || errors.Is(ce.Err, io.ErrClosedPipe)
+	}
+	return !ce.write && ce.ended && isClosedError(ce.Err)
+}
+
+// endCopyError marks err, if it's a *CopyError from reading the output failing due to the
+// connection being closed, as benign if ended reports that the output had ended by then, i.e. the
+// container exited or it was detached. ended is only called in that case, so it may be slow.
+func endCopyError(err error, ended func() bool) error {
+	var ce *CopyError
+	if errors.As(err, &ce) && !ce.write && ce.Stream != StreamStdin && isClosedError(ce.Err) {
+		ce.ended = ended()
+	}
+	return err
+}
+
+// errorWriter records the error of the last failed write to w.
+type errorWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (w *errorWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	if err != nil {
+		w.err = err
+	}
+	return n, err
+}
+
+// copyOutput copies the output of an attached container or exec from r to stdout and stderr,
+// either of which may be nil to discard it, returning a *CopyError on failure.
+func copyOutput(r io.Reader, tty bool, stdout, stderr io.Writer) error {
+	if fw, ok := stdout.(*frameWriter); ok {
+		return fw.copy(r, tty)
+	}
+
+	if stdout == nil {
+		stdout = io.Discard
+	}
+	if stderr == nil {
+		stderr = io.Discard
+	}
+	outw, errw := &errorWriter{w: stdout}, &errorWriter{w: stderr}
+
+	var err error
+	if tty {
+		_, err = io.Copy(outw, r)
+	} else {
+		_, err = stdcopy.StdCopy(outw, errw, r)
+	}
+
+	if err == nil {
+		return nil
+	}
+	if errw.err != nil && outw.err == nil {
+		return &CopyError{Stream: StreamStderr, Err: err, write: true}
+	}
+	return &CopyError{Stream: StreamStdout, Err: err, write: outw.err != nil}
+}
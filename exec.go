@@ -0,0 +1,392 @@
+package dockerexec
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+)
+
+// execPollInterval is the interval in which ExecCmd polls for the exit code of a command whose
+// output has ended but which the daemon still reports as running.
+const execPollInterval = 50 * time.Millisecond
+
+// ExecCmd represents a command being prepared or run inside an already running container using
+// exec, see Session.
+//
+// Its interface mirrors that of Cmd. An ExecCmd cannot be reused after calling its Run, Output or
+// CombinedOutput methods.
+type ExecCmd struct {
+	// Config is the configuration of the exec. The Attach fields are set according to Stdin,
+	// Stdout and Stderr.
+	Config *container.ExecOptions
+
+	// User, if set, is the user the command runs as, e.g. "root" or "1000:1000", overriding the
+	// user of the container.
+	User string
+
+	// Privileged runs the command with extended privileges, even if the container isn't
+	// privileged.
+	Privileged bool
+
+	// WorkingDir, if set, is the working directory of the command, overriding the working
+	// directory of the container. Requires API 1.35.
+	WorkingDir string
+
+	// Env specifies additional environment variables of the command, in the form "key=value", on
+	// top of the environment of the container.
+	Env []string
+
+	// Stdin specifies the command's standard input.
+	//
+	// If Stdin is nil, the command has no standard input.
+	Stdin io.Reader
+
+	// Stdout and Stderr specify the command's standard output and error.
+	//
+	// If either is nil, the corresponding output will be discarded.
+	//
+	// While using Config.Tty, only a single stream of output is available in Stdout.
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// ContainerID is the ID of the container the command runs in.
+	ContainerID string
+
+	// ExecID is the ID of the exec, once started.
+	ExecID string
+
+	// StatusCode contains the status code of the command, available after a call to Wait or Run.
+	StatusCode int64
+
+	// ExecInspect contains the low-level information on the exec from the daemon once the command
+	// exited, available after a call to Wait or Run. Its Pid is the PID of the command on the
+	// host, which is 0 if the command never started, such as when it isn't found, see Started.
+	ExecInspect *container.ExecInspect
+
+	ctx       context.Context // nil means None
+	cli       Client
+	group     *execGroup // tracking the command, if created by a Cmd
+	finished  bool       // when Wait was called
+	attach    types.HijackedResponse
+	goroutine []func() error
+	errch     chan error // one send per goroutine
+}
+
+// ExecCommand returns the ExecCmd struct to execute the named program with the given arguments
+// inside the running container with the given ID.
+func ExecCommand(cli Client, containerID string, name string, arg ...string) *ExecCmd {
+	return &ExecCmd{
+		Config: &container.ExecOptions{
+			Cmd: append([]string{name}, arg...),
+		},
+
+		ContainerID: containerID,
+		StatusCode:  -1,
+
+		cli: cli,
+	}
+}
+
+// ExecCommandContext is like ExecCommand but includes a context.
+//
+// The provided context is used to abort waiting for the command if it becomes done before the
+// command completes on its own. Note that the daemon has no way to kill an exec, so the command
+// itself keeps running until it exits or its container is stopped.
+func ExecCommandContext(ctx context.Context, cli Client, containerID string, name string, arg ...string) *ExecCmd {
+	if ctx == nil {
+		panic("nil Context")
+	}
+	cmd := ExecCommand(cli, containerID, name, arg...)
+	cmd.ctx = ctx
+	return cmd
+}
+
+// String returns a human-readable description of c, with arguments quoted by ShellQuote.
+// It is intended only for debugging.
+func (c *ExecCmd) String() string {
+	return ShellJoin(c.Config.Cmd...)
+}
+
+// context returns the context of c, or context.Background if there is none.
+func (c *ExecCmd) context() context.Context {
+	if c.ctx != nil {
+		return c.ctx
+	}
+	return context.Background()
+}
+
+// Run starts the command and waits for it to complete.
+//
+// If the command starts but does not complete successfully, the error is of type *ExitError.
+// Other error types may be returned for other situations.
+func (c *ExecCmd) Run() error {
+	if err := c.Start(); err != nil {
+		return err
+	}
+	return c.Wait()
+}
+
+// Start starts the command but does not wait for it to complete.
+//
+// If Start returns successfully, the c.ExecID field will be set.
+func (c *ExecCmd) Start() error {
+	if len(c.ExecID) != 0 {
+		return errors.New("dockerexec: already started")
+	}
+	if c.Config.Tty && c.Stderr != nil {
+		return errors.New("dockerexec: can't set both Config.Tty and Stderr")
+	}
+
+	ctx := c.context()
+
+	if c.User != "" {
+		c.Config.User = c.User
+	}
+	if c.Privileged {
+		c.Config.Privileged = true
+	}
+	if c.WorkingDir != "" {
+		c.Config.WorkingDir = c.WorkingDir
+	}
+	c.Config.Env = append(c.Config.Env, c.Env...)
+
+	if c.Config.WorkingDir != "" {
+		if err := checkAPIVersion(ctx, c.cli, "1.35", "WorkingDir"); err != nil {
+			return err
+		}
+	}
+
+	c.Config.AttachStdin = c.Stdin != nil
+	// Always attach the output, as its end is how the exit of the command is detected.
+	c.Config.AttachStdout = true
+	c.Config.AttachStderr = !c.Config.Tty
+
+	resp, err := c.cli.ContainerExecCreate(ctx, c.ContainerID, *c.Config)
+	if err != nil {
+		return wrapError("create exec", err)
+	}
+
+	attach, err := c.cli.ContainerExecAttach(ctx, resp.ID, container.ExecAttachOptions{
+		Tty:         c.Config.Tty,
+		ConsoleSize: c.Config.ConsoleSize,
+	})
+	if err != nil {
+		return wrapError("attach exec", err)
+	}
+	c.attach = attach
+	c.ExecID = resp.ID
+
+	if c.Stdin != nil {
+		c.goroutine = append(c.goroutine, func() error {
+			_, err := io.Copy(attach.Conn, c.Stdin)
+			if err1 := attach.CloseWrite(); err == nil {
+				err = err1
+			}
+			return copyError(StreamStdin, err)
+		})
+	}
+
+	c.goroutine = append(c.goroutine, func() error {
+		err := copyOutput(attach.Reader, c.Config.Tty, c.Stdout, c.Stderr)
+		return endCopyError(err, func() bool {
+			inspect, err := c.cli.ContainerExecInspect(context.Background(), resp.ID)
+			return err == nil && !inspect.Running
+		})
+	})
+
+	c.errch = make(chan error, len(c.goroutine))
+	for _, fn := range c.goroutine {
+		go func(fn func() error) {
+			c.errch <- fn()
+		}(fn)
+	}
+
+	if c.group != nil {
+		c.group.add(c)
+	}
+	return nil
+}
+
+// Wait waits for the command to exit and waits for any copying to stdin or copying from stdout or
+// stderr to complete.
+//
+// The returned error is nil if the command runs, has no problems copying stdin, stdout, and
+// stderr, and exits with a zero exit status. If the command doesn't complete successfully, the
+// error is of type *ExitError, and if copying any of its standard streams fails, the error is of
+// type *CopyError.
+func (c *ExecCmd) Wait() error {
+	if len(c.ExecID) == 0 {
+		return errors.New("dockerexec: not started")
+	}
+	if c.finished {
+		return errors.New("dockerexec: Wait was already called")
+	}
+	c.finished = true
+	defer c.attach.Close()
+	if c.group != nil {
+		defer c.group.remove(c)
+	}
+
+	var copyError error
+	for range c.goroutine {
+		select {
+		case err := <-c.errch:
+			if err != nil && copyError == nil && !isBenignCopyError(err) {
+				copyError = err
+			}
+		case <-c.context().Done():
+			return c.context().Err()
+		}
+	}
+
+	inspect, err := c.waitExit()
+	if err != nil {
+		return err
+	}
+	c.ExecInspect = &inspect
+	c.StatusCode = int64(inspect.ExitCode)
+	if c.StatusCode != 0 {
+		return &ExitError{StatusCode: c.StatusCode}
+	}
+	return copyError
+}
+
+// waitExit returns the inspect data of the command, once the daemon no longer reports it as
+// running, which might lag slightly behind the end of its output.
+func (c *ExecCmd) waitExit() (container.ExecInspect, error) {
+	ctx := c.context()
+	for {
+		inspect, err := c.cli.ContainerExecInspect(ctx, c.ExecID)
+		if err != nil {
+			return container.ExecInspect{}, wrapError("inspect exec", err)
+		}
+		if !inspect.Running {
+			return inspect, nil
+		}
+
+		select {
+		case <-time.After(execPollInterval):
+		case <-ctx.Done():
+			return container.ExecInspect{}, ctx.Err()
+		}
+	}
+}
+
+// Inspect returns the low-level information on the exec from the daemon, such as the PID of the
+// command on the host and whether it's still running.
+//
+// The command must have been started by Start.
+func (c *ExecCmd) Inspect() (container.ExecInspect, error) {
+	if len(c.ExecID) == 0 {
+		return container.ExecInspect{}, errors.New("dockerexec: not started")
+	}
+	inspect, err := c.cli.ContainerExecInspect(c.context(), c.ExecID)
+	return inspect, wrapError("inspect exec", err)
+}
+
+// Started reports whether the command actually started running, as opposed to failing to start,
+// such as when it isn't found, in which case the daemon reports an exit status of 126 or 127.
+// It's only meaningful after a call to Wait or Run.
+func (c *ExecCmd) Started() bool {
+	return c.ExecInspect != nil && c.ExecInspect.Pid != 0
+}
+
+// Output runs the command and returns its standard output.
+// Any returned error will usually be of type *ExitError.
+// If c.Stderr was nil, Output populates ExitError.Stderr.
+func (c *ExecCmd) Output() ([]byte, error) {
+	if c.Stdout != nil {
+		return nil, errors.New("dockerexec: Stdout already set")
+	}
+	var stdout bytes.Buffer
+	c.Stdout = &stdout
+
+	captureErr := c.Stderr == nil && !c.Config.Tty
+	if captureErr {
+		c.Stderr = &prefixSuffixSaver{N: 32 << 10}
+	}
+
+	err := c.Run()
+	if err != nil && captureErr {
+		if ee, ok := err.(*ExitError); ok {
+			ee.Stderr = c.Stderr.(*prefixSuffixSaver).Bytes()
+		}
+	}
+	return stdout.Bytes(), err
+}
+
+// CombinedOutput runs the command and returns its combined standard output and standard error.
+func (c *ExecCmd) CombinedOutput() ([]byte, error) {
+	if c.Stdout != nil {
+		return nil, errors.New("dockerexec: Stdout already set")
+	}
+	if c.Stderr != nil {
+		return nil, errors.New("dockerexec: Stderr already set")
+	}
+	var b bytes.Buffer
+	c.Stdout = &b
+	if !c.Config.Tty {
+		c.Stderr = &b
+	}
+	err := c.Run()
+	return b.Bytes(), err
+}
+
+// execGroup tracks the execs of a container that were started but not yet waited for.
+type execGroup struct {
+	mu    sync.Mutex
+	execs []*ExecCmd
+}
+
+func (g *execGroup) add(c *ExecCmd) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.execs = append(g.execs, c)
+}
+
+func (g *execGroup) remove(c *ExecCmd) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for i, e := range g.execs {
+		if e == c {
+			g.execs = append(g.execs[:i], g.execs[i+1:]...)
+			return
+		}
+	}
+}
+
+func (g *execGroup) list() []*ExecCmd {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]*ExecCmd(nil), g.execs...)
+}
+
+// ExecCommand returns the ExecCmd struct to execute the named program with the given arguments
+// inside the container of c, which must have been started by Start.
+//
+// Any number of commands may run concurrently in the container, each with its own standard I/O
+// and Wait. Commands started and not yet waited for are tracked by Execs.
+func (c *Cmd) ExecCommand(name string, arg ...string) *ExecCmd {
+	cmd := ExecCommand(c.cli, c.ContainerID, name, arg...)
+	cmd.group = &c.execs
+	return cmd
+}
+
+// ExecCommandContext is like ExecCommand but includes a context.
+func (c *Cmd) ExecCommandContext(ctx context.Context, name string, arg ...string) *ExecCmd {
+	cmd := ExecCommandContext(ctx, c.cli, c.ContainerID, name, arg...)
+	cmd.group = &c.execs
+	return cmd
+}
+
+// Execs returns the commands created by ExecCommand that were started but not yet waited for,
+// in the order they were started.
+func (c *Cmd) Execs() []*ExecCmd {
+	return c.execs.list()
+}
@@ -0,0 +1,310 @@
+package dockerexec
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/registry"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// ErrInjectedFault is a convenient error for a FaultInjector to inject.
+var ErrInjectedFault = errors.New("dockerexec: injected fault")
+
+// FaultStage is a stage at which a FaultInjector can inject a fault, see WithFaults.
+type FaultStage int
+
+const (
+	// FaultDisconnect is injected before each request to the daemon, so that an error fails the
+	// request as if the daemon disconnected.
+	FaultDisconnect FaultStage = iota
+
+	// FaultAttach is injected when attaching to a container or an exec, so that an error fails
+	// the attach.
+	FaultAttach
+
+	// FaultWait is injected while waiting for a container, before the result of the wait is
+	// delivered, so that an error fails the wait, and blocking delays it.
+	FaultWait
+
+	// FaultCopy is injected before each read of the output of an attached container or exec, so
+	// that an error fails the copy, and blocking slows it down.
+	FaultCopy
+)
+
+func (s FaultStage) String() string {
+	switch s {
+	case FaultDisconnect:
+		return "disconnect"
+	case FaultAttach:
+		return "attach"
+	case FaultWait:
+		return "wait"
+	case FaultCopy:
+		return "copy"
+	default:
+		return fmt.Sprintf("FaultStage(%d)", int(s))
+	}
+}
+
+// FaultInjector decides which faults to inject, see WithFaults.
+type FaultInjector interface {
+	// InjectFault is called at stage for the container or exec id, which is empty for requests
+	// not specific to one. It returns the error to fail the stage with, or nil to continue as
+	// usual, and may block to inject a delay.
+	InjectFault(ctx context.Context, stage FaultStage, id string) error
+}
+
+// FaultInjectorFunc is a function implementing FaultInjector.
+type FaultInjectorFunc func(ctx context.Context, stage FaultStage, id string) error
+
+// InjectFault calls f(ctx, stage, id).
+func (f FaultInjectorFunc) InjectFault(ctx context.Context, stage FaultStage, id string) error {
+	return f(ctx, stage, id)
+}
+
+// WithFaults wraps cli so that injector can inject faults at specific stages, for deterministically
+// testing error paths, such as a failed attach or a daemon going away mid-run.
+func WithFaults(cli Client, injector FaultInjector) Client {
+	return &faultClient{Client: cli, injector: injector}
+}
+
+type faultClient struct {
+	Client
+	injector FaultInjector
+}
+
+func (f *faultClient) Ping(ctx context.Context) (types.Ping, error) {
+	if err := f.injector.InjectFault(ctx, FaultDisconnect, ""); err != nil {
+		return types.Ping{}, err
+	}
+	return f.Client.Ping(ctx)
+}
+
+func (f *faultClient) ContainerAttach(ctx context.Context, id string, options container.AttachOptions) (types.HijackedResponse, error) {
+	if err := f.injectAttach(ctx, id); err != nil {
+		return types.HijackedResponse{}, err
+	}
+	resp, err := f.Client.ContainerAttach(ctx, id, options)
+	if err != nil {
+		return resp, err
+	}
+	return f.wrapHijacked(ctx, id, resp), nil
+}
+
+func (f *faultClient) ContainerCreate(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, platform *ocispec.Platform, containerName string) (container.CreateResponse, error) {
+	if err := f.injector.InjectFault(ctx, FaultDisconnect, ""); err != nil {
+		return container.CreateResponse{}, err
+	}
+	return f.Client.ContainerCreate(ctx, config, hostConfig, networkingConfig, platform, containerName)
+}
+
+func (f *faultClient) NewVersionError(ctx context.Context, APIrequired, feature string) error {
+	return newVersionError(ctx, f.Client, APIrequired, feature)
+}
+
+func (f *faultClient) ContainerDiff(ctx context.Context, id string) ([]container.FilesystemChange, error) {
+	if err := f.injector.InjectFault(ctx, FaultDisconnect, id); err != nil {
+		return nil, err
+	}
+	return containerDiff(ctx, f.Client, id)
+}
+
+func (f *faultClient) ContainerExecAttach(ctx context.Context, execID string, options container.ExecAttachOptions) (types.HijackedResponse, error) {
+	if err := f.injectAttach(ctx, execID); err != nil {
+		return types.HijackedResponse{}, err
+	}
+	resp, err := f.Client.ContainerExecAttach(ctx, execID, options)
+	if err != nil {
+		return resp, err
+	}
+	return f.wrapHijacked(ctx, execID, resp), nil
+}
+
+func (f *faultClient) ContainerExecCreate(ctx context.Context, id string, options container.ExecOptions) (types.IDResponse, error) {
+	if err := f.injector.InjectFault(ctx, FaultDisconnect, id); err != nil {
+		return types.IDResponse{}, err
+	}
+	return f.Client.ContainerExecCreate(ctx, id, options)
+}
+
+func (f *faultClient) ContainerExecInspect(ctx context.Context, execID string) (container.ExecInspect, error) {
+	if err := f.injector.InjectFault(ctx, FaultDisconnect, execID); err != nil {
+		return container.ExecInspect{}, err
+	}
+	return f.Client.ContainerExecInspect(ctx, execID)
+}
+
+func (f *faultClient) ContainerInspect(ctx context.Context, id string) (types.ContainerJSON, error) {
+	if err := f.injector.InjectFault(ctx, FaultDisconnect, id); err != nil {
+		return types.ContainerJSON{}, err
+	}
+	return f.Client.ContainerInspect(ctx, id)
+}
+
+func (f *faultClient) ContainerKill(ctx context.Context, id, signal string) error {
+	if err := f.injector.InjectFault(ctx, FaultDisconnect, id); err != nil {
+		return err
+	}
+	return f.Client.ContainerKill(ctx, id, signal)
+}
+
+func (f *faultClient) ContainerList(ctx context.Context, options container.ListOptions) ([]types.Container, error) {
+	if err := f.injector.InjectFault(ctx, FaultDisconnect, ""); err != nil {
+		return nil, err
+	}
+	return f.Client.ContainerList(ctx, options)
+}
+
+func (f *faultClient) ContainerLogs(ctx context.Context, id string, options container.LogsOptions) (io.ReadCloser, error) {
+	if err := f.injector.InjectFault(ctx, FaultDisconnect, id); err != nil {
+		return nil, err
+	}
+	return f.Client.ContainerLogs(ctx, id, options)
+}
+
+func (f *faultClient) ContainerRemove(ctx context.Context, id string, options container.RemoveOptions) error {
+	if err := f.injector.InjectFault(ctx, FaultDisconnect, id); err != nil {
+		return err
+	}
+	return f.Client.ContainerRemove(ctx, id, options)
+}
+
+func (f *faultClient) ContainerResize(ctx context.Context, id string, options container.ResizeOptions) error {
+	if err := f.injector.InjectFault(ctx, FaultDisconnect, id); err != nil {
+		return err
+	}
+	return f.Client.ContainerResize(ctx, id, options)
+}
+
+func (f *faultClient) ContainerStart(ctx context.Context, id string, options container.StartOptions) error {
+	if err := f.injector.InjectFault(ctx, FaultDisconnect, id); err != nil {
+		return err
+	}
+	return f.Client.ContainerStart(ctx, id, options)
+}
+
+func (f *faultClient) ContainerStats(ctx context.Context, id string, stream bool) (container.StatsResponseReader, error) {
+	if err := f.injector.InjectFault(ctx, FaultDisconnect, id); err != nil {
+		return container.StatsResponseReader{}, err
+	}
+	return containerStats(ctx, f.Client, id, stream)
+}
+
+func (f *faultClient) ContainerStatsOneShot(ctx context.Context, id string) (container.StatsResponseReader, error) {
+	if err := f.injector.InjectFault(ctx, FaultDisconnect, id); err != nil {
+		return container.StatsResponseReader{}, err
+	}
+	return containerStatsOneShot(ctx, f.Client, id)
+}
+
+func (f *faultClient) ContainerStop(ctx context.Context, id string, options container.StopOptions) error {
+	if err := f.injector.InjectFault(ctx, FaultDisconnect, id); err != nil {
+		return err
+	}
+	return f.Client.ContainerStop(ctx, id, options)
+}
+
+func (f *faultClient) ContainerWait(ctx context.Context, id string, condition container.WaitCondition) (<-chan container.WaitResponse, <-chan error) {
+	resultC := make(chan container.WaitResponse, 1)
+	errC := make(chan error, 1)
+
+	if err := f.injector.InjectFault(ctx, FaultDisconnect, id); err != nil {
+		errC <- err
+		return resultC, errC
+	}
+
+	// The wait must be requested right away, as it's usually requested before the container is
+	// started, and only its result is held back by the fault
+	waitCh, errCh := f.Client.ContainerWait(ctx, id, condition)
+	go func() {
+		if err := f.injector.InjectFault(ctx, FaultWait, id); err != nil {
+			errC <- err
+			return
+		}
+		select {
+		case result := <-waitCh:
+			resultC <- result
+		case err := <-errCh:
+			errC <- err
+		}
+	}()
+	return resultC, errC
+}
+
+func (f *faultClient) CopyFromContainer(ctx context.Context, id, srcPath string) (io.ReadCloser, container.PathStat, error) {
+	if err := f.injector.InjectFault(ctx, FaultDisconnect, id); err != nil {
+		return nil, container.PathStat{}, err
+	}
+	return f.Client.CopyFromContainer(ctx, id, srcPath)
+}
+
+func (f *faultClient) CopyToContainer(ctx context.Context, id, path string, content io.Reader, options container.CopyToContainerOptions) error {
+	if err := f.injector.InjectFault(ctx, FaultDisconnect, id); err != nil {
+		return err
+	}
+	return f.Client.CopyToContainer(ctx, id, path, content, options)
+}
+
+func (f *faultClient) DistributionInspect(ctx context.Context, image, encodedRegistryAuth string) (registry.DistributionInspect, error) {
+	if err := f.injector.InjectFault(ctx, FaultDisconnect, ""); err != nil {
+		return registry.DistributionInspect{}, err
+	}
+	return distributionInspect(ctx, f.Client, image, encodedRegistryAuth)
+}
+
+func (f *faultClient) ImageInspectWithRaw(ctx context.Context, image string) (types.ImageInspect, []byte, error) {
+	if err := f.injector.InjectFault(ctx, FaultDisconnect, ""); err != nil {
+		return types.ImageInspect{}, nil, err
+	}
+	return f.Client.ImageInspectWithRaw(ctx, image)
+}
+
+func (f *faultClient) ImagePull(ctx context.Context, ref string, options image.PullOptions) (io.ReadCloser, error) {
+	if err := f.injector.InjectFault(ctx, FaultDisconnect, ""); err != nil {
+		return nil, err
+	}
+	return f.Client.ImagePull(ctx, ref, options)
+}
+
+func (f *faultClient) ImageTag(ctx context.Context, image, ref string) error {
+	if err := f.injector.InjectFault(ctx, FaultDisconnect, ""); err != nil {
+		return err
+	}
+	return f.Client.ImageTag(ctx, image, ref)
+}
+
+// injectAttach injects the faults of attaching to the container or exec id.
+func (f *faultClient) injectAttach(ctx context.Context, id string) error {
+	if err := f.injector.InjectFault(ctx, FaultDisconnect, id); err != nil {
+		return err
+	}
+	return f.injector.InjectFault(ctx, FaultAttach, id)
+}
+
+// wrapHijacked injects FaultCopy before each read of the output of resp.
+func (f *faultClient) wrapHijacked(ctx context.Context, id string, resp types.HijackedResponse) types.HijackedResponse {
+	resp.Reader = bufio.NewReader(&faultReader{ctx: ctx, injector: f.injector, id: id, r: resp.Reader})
+	return resp
+}
+
+type faultReader struct {
+	ctx      context.Context
+	injector FaultInjector
+	id       string
+	r        io.Reader
+}
+
+func (r *faultReader) Read(p []byte) (int, error) {
+	if err := r.injector.InjectFault(r.ctx, FaultCopy, r.id); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
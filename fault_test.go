@@ -0,0 +1,79 @@
+package dockerexec_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+// faultAt returns a FaultInjector injecting ErrInjectedFault at stage.
+func faultAt(stage dockerexec.FaultStage) dockerexec.FaultInjector {
+	return dockerexec.FaultInjectorFunc(func(ctx context.Context, s dockerexec.FaultStage, id string) error {
+		if s == stage {
+			return dockerexec.ErrInjectedFault
+		}
+		return nil
+	})
+}
+
+func TestFaultAttach(t *testing.T) {
+	cli := dockerexec.WithFaults(dockerClient, faultAt(dockerexec.FaultAttach))
+	cmd := dockerexec.Command(cli, testImage, "echo", "hello")
+	_, err := cmd.Output()
+	assert.ErrorIs(t, err, dockerexec.ErrInjectedFault)
+}
+
+func TestFaultWait(t *testing.T) {
+	cli := dockerexec.WithFaults(dockerClient, faultAt(dockerexec.FaultWait))
+	cmd := dockerexec.Command(cli, testImage, "true")
+	err := cmd.Run()
+
+	var waitErr *dockerexec.WaitError
+	assert.ErrorAs(t, err, &waitErr)
+	assert.ErrorIs(t, err, dockerexec.ErrInjectedFault)
+}
+
+func TestFaultCopy(t *testing.T) {
+	cli := dockerexec.WithFaults(dockerClient, faultAt(dockerexec.FaultCopy))
+	cmd := dockerexec.Command(cli, testImage, "echo", "hello")
+	_, err := cmd.Output()
+
+	var copyErr *dockerexec.CopyError
+	assert.ErrorAs(t, err, &copyErr)
+	assert.ErrorIs(t, err, dockerexec.ErrInjectedFault)
+}
+
+func TestFaultSlowCopy(t *testing.T) {
+	cli := dockerexec.WithFaults(dockerClient, dockerexec.FaultInjectorFunc(func(ctx context.Context, stage dockerexec.FaultStage, id string) error {
+		if stage == dockerexec.FaultCopy {
+			time.Sleep(10 * time.Millisecond)
+		}
+		return nil
+	}))
+	cmd := dockerexec.Command(cli, testImage, "echo", "hello")
+	output, err := cmd.Output()
+	require.NoError(t, err)
+	assert.Equal(t, "hello\n", string(output))
+}
+
+func TestFaultDisconnect(t *testing.T) {
+	cli := dockerexec.WithFaults(dockerClient, dockerexec.FaultInjectorFunc(func(ctx context.Context, stage dockerexec.FaultStage, id string) error {
+		if stage == dockerexec.FaultDisconnect && id != "" {
+			return dockerexec.ErrInjectedFault
+		}
+		return nil
+	}))
+	cmd := dockerexec.Command(cli, testImage, "true")
+	err := cmd.Run()
+	assert.ErrorIs(t, err, dockerexec.ErrInjectedFault)
+}
+
+func TestFaultStageString(t *testing.T) {
+	assert.Equal(t, "attach", dockerexec.FaultAttach.String())
+	assert.Equal(t, "FaultStage(42)", dockerexec.FaultStage(42).String())
+}
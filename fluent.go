@@ -0,0 +1,111 @@
+package dockerexec
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"net"
+	"sync"
+)
+
+// FluentSink is a LogSink that forwards output to Fluentd or Fluent Bit using the Forward
+// protocol. Each line is sent as an event with the same record keys as used by the daemon's
+// fluentd log driver: container_id, container_name, image, source and log.
+//
+// A FluentSink is safe for concurrent use. It connects on the first record, and reconnects once
+// if sending a record fails.
+type FluentSink struct {
+	// Network and Addr are the address of the forward input, defaults to "tcp" and
+	// "localhost:24224".
+	Network string
+	Addr    string
+
+	// Tag is the tag of the events, defaults to "docker.<container ID>".
+	Tag string
+
+	once sync.Once
+	conn sinkConn
+}
+
+// Send sends record to Fluentd.
+func (s *FluentSink) Send(record LogRecord) error {
+	s.once.Do(func() { s.conn.dial = s.dial })
+
+	tag := s.Tag
+	if tag == "" {
+		tag = "docker." + record.ContainerID
+	}
+
+	// Message mode: [tag, time, record]
+	var b []byte
+	b = msgpackArray(b, 3)
+	b = msgpackString(b, tag)
+	b = msgpackEventTime(b, record.Time.Unix(), record.Time.Nanosecond())
+	b = msgpackMap(b, 5)
+	b = msgpackString(b, "container_id")
+	b = msgpackString(b, record.ContainerID)
+	b = msgpackString(b, "container_name")
+	b = msgpackString(b, "/"+record.ContainerName)
+	b = msgpackString(b, "image")
+	b = msgpackString(b, record.Image)
+	b = msgpackString(b, "source")
+	b = msgpackString(b, record.Stream.String())
+	b = msgpackString(b, "log")
+	b = msgpackString(b, record.Line)
+
+	return wrapError("fluentd", s.conn.write(b))
+}
+
+// Close closes the connection to Fluentd.
+func (s *FluentSink) Close() error {
+	return s.conn.close()
+}
+
+func (s *FluentSink) dial() (io.WriteCloser, error) {
+	network, addr := s.Network, s.Addr
+	if network == "" {
+		network = "tcp"
+	}
+	if addr == "" {
+		addr = "localhost:24224"
+	}
+	return net.Dial(network, addr)
+}
+
+// The following implement the subset of MessagePack needed for the Forward protocol.
+
+func msgpackArray(b []byte, n int) []byte {
+	if n < 16 {
+		return append(b, 0x90|byte(n))
+	}
+	return binary.BigEndian.AppendUint32(append(b, 0xdd), uint32(n))
+}
+
+func msgpackMap(b []byte, n int) []byte {
+	if n < 16 {
+		return append(b, 0x80|byte(n))
+	}
+	return binary.BigEndian.AppendUint32(append(b, 0xdf), uint32(n))
+}
+
+func msgpackString(b []byte, s string) []byte {
+	switch n := len(s); {
+	case n < 32:
+		b = append(b, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		b = append(b, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		b = binary.BigEndian.AppendUint16(append(b, 0xda), uint16(n))
+	default:
+		b = binary.BigEndian.AppendUint32(append(b, 0xdb), uint32(n))
+	}
+	return append(b, s...)
+}
+
+// msgpackEventTime appends an EventTime, which is a fixext 8 of type 0 holding the seconds and
+// nanoseconds.
+func msgpackEventTime(b []byte, sec int64, nsec int) []byte {
+	b = append(b, 0xd7, 0x00)
+	b = binary.BigEndian.AppendUint32(b, uint32(sec))
+	return binary.BigEndian.AppendUint32(b, uint32(nsec))
+}
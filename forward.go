@@ -0,0 +1,192 @@
+package dockerexec
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// LogRecord is a line of output of a container forwarded to a LogSink.
+type LogRecord struct {
+	Time          time.Time
+	ContainerID   string
+	ContainerName string
+	Image         string
+	Stream        Stream
+
+	// Line is the line, without its trailing newline.
+	Line string
+}
+
+// LogSink receives the output of containers, line by line, for forwarding it to a centralized
+// logging system. See SyslogSink and FluentSink.
+type LogSink interface {
+	Send(record LogRecord) error
+}
+
+// ForwardOutput sets Stdout and Stderr (unless Config.Tty is set) to forward the output of the
+// container to sink, line by line, along with the ID, name and image of the container. A trailing
+// partial line is forwarded once the output ends.
+//
+// This allows centralizing the output of containers without changing the log driver of the
+// daemon. A failure to send a record fails copying the output, see CopyError.
+func (c *Cmd) ForwardOutput(sink LogSink) error {
+	if c.Stdout != nil {
+		return errors.New("dockerexec: Stdout already set")
+	}
+	if c.Stderr != nil {
+		return errors.New("dockerexec: Stderr already set")
+	}
+	if len(c.ContainerID) != 0 {
+		return errors.New("dockerexec: ForwardOutput after container started")
+	}
+
+	c.Stdout = &forwardWriter{sink: sink, stream: StreamStdout}
+	if !c.Config.Tty {
+		c.Stderr = &forwardWriter{sink: sink, stream: StreamStderr}
+	}
+	return nil
+}
+
+// containerWriter is implemented by output writers that need the metadata of the container,
+// which are given it once the container is created, before any output is written.
+type containerWriter interface {
+	setContainer(c *Cmd, id string)
+}
+
+// setOutputContainer gives the metadata of the container to Stdout and Stderr if they are
+// containerWriters.
+func (c *Cmd) setOutputContainer(id string) {
+	for _, w := range []io.Writer{c.Stdout, c.Stderr} {
+		if cw, ok := w.(containerWriter); ok {
+			cw.setContainer(c, id)
+		}
+	}
+}
+
+// outputFlusher is implemented by output writers buffering part of the output, which need to be
+// flushed once the output ends.
+type outputFlusher interface {
+	flush() error
+}
+
+// flushOutput flushes Stdout and Stderr if they are outputFlushers, returning a *CopyError on
+// failure.
+func (c *Cmd) flushOutput() error {
+	for _, out := range []struct {
+		w      io.Writer
+		stream Stream
+	}{{c.Stdout, StreamStdout}, {c.Stderr, StreamStderr}} {
+		if f, ok := out.w.(outputFlusher); ok {
+			if err := f.flush(); err != nil {
+				return &CopyError{Stream: out.stream, Err: err, write: true}
+			}
+		}
+	}
+	return nil
+}
+
+// forwardWriter splits output into lines, sending each to a LogSink.
+type forwardWriter struct {
+	sink   LogSink
+	stream Stream
+	record LogRecord // template of the records sent
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+func (w *forwardWriter) setContainer(c *Cmd, id string) {
+	w.record = LogRecord{
+		ContainerID:   id,
+		ContainerName: c.ContainerName,
+		Image:         c.Config.Image,
+		Stream:        w.stream,
+	}
+}
+
+func (w *forwardWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		line := bytes.TrimSuffix(w.buf[:i], []byte{'\r'})
+		if err := w.send(string(line)); err != nil {
+			return len(p), err
+		}
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+func (w *forwardWriter) send(line string) error {
+	record := w.record
+	record.Time = time.Now()
+	record.Line = line
+	return w.sink.Send(record)
+}
+
+// flush sends the trailing partial line, if any.
+func (w *forwardWriter) flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.buf) == 0 {
+		return nil
+	}
+	line := string(w.buf)
+	w.buf = nil
+	return w.send(line)
+}
+
+// sinkConn is a lazily dialed connection of a LogSink, which is redialed once on write failure.
+type sinkConn struct {
+	dial func() (io.WriteCloser, error)
+
+	mu   sync.Mutex
+	conn io.WriteCloser
+}
+
+func (s *sinkConn) write(p []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for attempt := 0; ; attempt++ {
+		if s.conn == nil {
+			conn, err := s.dial()
+			if err != nil {
+				return err
+			}
+			s.conn = conn
+		}
+
+		_, err := s.conn.Write(p)
+		if err == nil {
+			return nil
+		}
+		s.conn.Close()
+		s.conn = nil
+		if attempt > 0 {
+			return err
+		}
+	}
+}
+
+func (s *sinkConn) close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
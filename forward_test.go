@@ -0,0 +1,123 @@
+package dockerexec_test
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+type recordingSink struct {
+	mu      sync.Mutex
+	records []dockerexec.LogRecord
+}
+
+func (s *recordingSink) Send(record dockerexec.LogRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, record)
+	return nil
+}
+
+func TestForwardOutput(t *testing.T) {
+	sink := &recordingSink{}
+	cmd := dockerexec.Command(dockerClient, testImage, "sh", "-c", "echo out; echo err >&2; printf partial")
+	require.NoError(t, cmd.ForwardOutput(sink))
+	assert.Error(t, cmd.ForwardOutput(sink))
+	require.NoError(t, cmd.Run())
+
+	require.Len(t, sink.records, 3)
+	lines := map[string]dockerexec.LogRecord{}
+	for _, record := range sink.records {
+		assert.Equal(t, cmd.ContainerID, record.ContainerID)
+		assert.Equal(t, testImage, record.Image)
+		lines[record.Line] = record
+	}
+	assert.Equal(t, dockerexec.StreamStdout, lines["out"].Stream)
+	assert.Equal(t, dockerexec.StreamStderr, lines["err"].Stream)
+	assert.Equal(t, dockerexec.StreamStdout, lines["partial"].Stream)
+}
+
+// partialFailingSink fails sending lines starting with "partial".
+type partialFailingSink struct {
+	recordingSink
+}
+
+func (s *partialFailingSink) Send(record dockerexec.LogRecord) error {
+	if strings.HasPrefix(record.Line, "partial") {
+		return errors.New("send failed")
+	}
+	return s.recordingSink.Send(record)
+}
+
+func TestForwardOutputTrailingLineError(t *testing.T) {
+	sink := &partialFailingSink{}
+	cmd := dockerexec.Command(dockerClient, testImage, "sh", "-c", "echo out; printf partial")
+	require.NoError(t, cmd.ForwardOutput(sink))
+	err := cmd.Run()
+
+	var copyErr *dockerexec.CopyError
+	require.ErrorAs(t, err, &copyErr)
+	assert.Equal(t, dockerexec.StreamStdout, copyErr.Stream)
+	assert.EqualError(t, err, "dockerexec: copy stdout: send failed")
+}
+
+func TestSyslogSink(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	sink := &dockerexec.SyslogSink{Network: "udp", Addr: conn.LocalAddr().String(), Tag: "test"}
+	defer sink.Close()
+
+	cmd := dockerexec.Command(dockerClient, testImage, "echo", "hello")
+	require.NoError(t, cmd.ForwardOutput(sink))
+	require.NoError(t, cmd.Run())
+
+	buf := make([]byte, 4096)
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(t, err)
+	msg := string(buf[:n])
+	assert.True(t, strings.HasPrefix(msg, "<14>1 "), msg)
+	assert.Contains(t, msg, " test - - ")
+	assert.Contains(t, msg, `id="`+cmd.ContainerID+`"`)
+	assert.True(t, strings.HasSuffix(msg, "] hello"), msg)
+}
+
+func TestFluentSink(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	received := make(chan []byte, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		buf := make([]byte, 4096)
+		n, _ := bufio.NewReader(conn).Read(buf)
+		received <- buf[:n]
+	}()
+
+	sink := &dockerexec.FluentSink{Addr: ln.Addr().String(), Tag: "test"}
+	defer sink.Close()
+
+	cmd := dockerexec.Command(dockerClient, testImage, "echo", "hello")
+	require.NoError(t, cmd.ForwardOutput(sink))
+	require.NoError(t, cmd.Run())
+
+	msg := <-received
+	// fixarray of 3, fixstr "test"
+	assert.Equal(t, []byte{0x93, 0xa4, 't', 'e', 's', 't'}, msg[:6])
+	assert.Contains(t, string(msg), cmd.ContainerID)
+	assert.True(t, strings.HasSuffix(string(msg), "\xa3log\xa5hello"))
+}
@@ -0,0 +1,95 @@
+package dockerexec
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// frameHeaderSize is the size of the header of a frame of multiplexed output.
+const frameHeaderSize = 8
+
+// streamSystemErr is the stream of frames carrying an error from the daemon.
+const streamSystemErr = 3
+
+// Frame is a frame of the multiplexed output of a container, see Cmd.OutputFrames.
+type Frame struct {
+	Stream Stream
+
+	// Payload is the data of the frame. It's only valid until OutputFrames returns.
+	Payload []byte
+}
+
+// Header returns the header of the frame in the multiplexed stream format of the daemon (See
+// stdcopy), which followed by Payload, re-encodes the frame.
+func (f Frame) Header() [frameHeaderSize]byte {
+	var header [frameHeaderSize]byte
+	header[0] = byte(f.Stream)
+	binary.BigEndian.PutUint32(header[4:], uint32(len(f.Payload)))
+	return header
+}
+
+// frameWriter is the output writer used for Cmd.OutputFrames. It's never written to, but is
+// recognized by copyOutput, which delivers frames to fn instead.
+type frameWriter struct {
+	fn func(Frame) error
+}
+
+func (w *frameWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("dockerexec: frameWriter can't be written to")
+}
+
+// copy delivers the frames read from r to fn, returning a *CopyError on failure.
+func (w *frameWriter) copy(r io.Reader, tty bool) error {
+	buf := make([]byte, 32*1024)
+
+	if tty {
+		for {
+			n, err := r.Read(buf)
+			if n > 0 {
+				if err := w.fn(Frame{Stream: StreamStdout, Payload: buf[:n]}); err != nil {
+					return &CopyError{Stream: StreamStdout, Err: err, write: true}
+				}
+			}
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return &CopyError{Stream: StreamStdout, Err: err}
+			}
+		}
+	}
+
+	var header [frameHeaderSize]byte
+	for {
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return &CopyError{Stream: StreamStdout, Err: err}
+		}
+
+		size := int(binary.BigEndian.Uint32(header[4:]))
+		if size > len(buf) {
+			buf = make([]byte, size)
+		}
+		payload := buf[:size]
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return &CopyError{Stream: StreamStdout, Err: err}
+		}
+
+		stream := Stream(header[0])
+		switch stream {
+		case StreamStdin, StreamStdout, StreamStderr:
+		case streamSystemErr:
+			return &CopyError{Stream: StreamStdout, Err: fmt.Errorf("error from daemon in stream: %s", payload)}
+		default:
+			return &CopyError{Stream: StreamStdout, Err: fmt.Errorf("unrecognized stream: %d", stream)}
+		}
+
+		if err := w.fn(Frame{Stream: stream, Payload: payload}); err != nil {
+			return &CopyError{Stream: stream, Err: err, write: true}
+		}
+	}
+}
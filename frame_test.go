@@ -0,0 +1,44 @@
+package dockerexec_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestOutputFrames(t *testing.T) {
+	var muxed bytes.Buffer
+	streams := map[dockerexec.Stream]int{}
+
+	cmd := dockerexec.Command(dockerClient, testImage, "sh", "-c", "echo out; echo err >&2")
+	cmd.OutputFrames = func(frame dockerexec.Frame) error {
+		streams[frame.Stream] += len(frame.Payload)
+		header := frame.Header()
+		muxed.Write(header[:])
+		muxed.Write(frame.Payload)
+		return nil
+	}
+	require.NoError(t, cmd.Run())
+
+	assert.Equal(t, 4, streams[dockerexec.StreamStdout])
+	assert.Equal(t, 4, streams[dockerexec.StreamStderr])
+
+	// The re-encoded frames can be demultiplexed by stdcopy.
+	var stdout, stderr bytes.Buffer
+	_, err := stdcopy.StdCopy(&stdout, &stderr, &muxed)
+	require.NoError(t, err)
+	assert.Equal(t, "out\n", stdout.String())
+	assert.Equal(t, "err\n", stderr.String())
+}
+
+func TestOutputFramesWithStdout(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "true")
+	cmd.OutputFrames = func(dockerexec.Frame) error { return nil }
+	cmd.Stdout = &bytes.Buffer{}
+	assert.Error(t, cmd.Run())
+}
@@ -0,0 +1,53 @@
+package dockerexec
+
+import (
+	"context"
+	"fmt"
+)
+
+// An ImageGate decides whether a container may be run from the given image, e.g. based on the
+// results of a vulnerability scanner such as Trivy exceeding a severity threshold.
+//
+// Returning an error prevents the container from being run, just like rejecting it.
+type ImageGate func(ctx context.Context, image string) (GateDecision, error)
+
+// GateDecision is the decision of an ImageGate.
+type GateDecision struct {
+	// Allow allows the container to be run.
+	Allow bool
+
+	// Reason is a human-readable explanation of the decision, e.g. a summary of the
+	// vulnerabilities found.
+	Reason string
+}
+
+// An ImageRejectedError is returned by Start when Cmd.ImageGate rejects the image.
+type ImageRejectedError struct {
+	Image  string
+	Reason string
+}
+
+func (e *ImageRejectedError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("dockerexec: image %s rejected", e.Image)
+	}
+	return fmt.Sprintf("dockerexec: image %s rejected: %s", e.Image, e.Reason)
+}
+
+// checkImageGate runs c.ImageGate, if set, recording its decision in c.GateDecision.
+func (c *Cmd) checkImageGate(ctx context.Context) error {
+	if c.ImageGate == nil {
+		return nil
+	}
+
+	decision, err := c.ImageGate(ctx, c.Config.Image)
+	if err != nil {
+		return fmt.Errorf("dockerexec: image gate: %w", err)
+	}
+	c.GateDecision = &decision
+
+	if !decision.Allow {
+		return &ImageRejectedError{Image: c.Config.Image, Reason: decision.Reason}
+	}
+	return nil
+}
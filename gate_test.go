@@ -0,0 +1,54 @@
+package dockerexec_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestImageGateAllow(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "true")
+
+	var gatedImage string
+	cmd.ImageGate = func(ctx context.Context, image string) (dockerexec.GateDecision, error) {
+		gatedImage = image
+		return dockerexec.GateDecision{Allow: true, Reason: "no vulnerabilities"}, nil
+	}
+
+	err := cmd.Run()
+	require.NoError(t, err)
+	assert.Equal(t, testImage, gatedImage)
+	assert.Equal(t, &dockerexec.GateDecision{Allow: true, Reason: "no vulnerabilities"}, cmd.GateDecision)
+}
+
+func TestImageGateReject(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "true")
+	cmd.ImageGate = func(ctx context.Context, image string) (dockerexec.GateDecision, error) {
+		return dockerexec.GateDecision{Reason: "2 critical vulnerabilities"}, nil
+	}
+
+	err := cmd.Run()
+	var rejectedErr *dockerexec.ImageRejectedError
+	require.ErrorAs(t, err, &rejectedErr)
+	assert.Equal(t, "2 critical vulnerabilities", rejectedErr.Reason)
+	assert.EqualError(t, err, "dockerexec: image "+testImage+" rejected: 2 critical vulnerabilities")
+	assert.Empty(t, cmd.ContainerID)
+}
+
+func TestImageGateError(t *testing.T) {
+	scanErr := errors.New("scanner unavailable")
+
+	cmd := dockerexec.Command(dockerClient, testImage, "true")
+	cmd.ImageGate = func(ctx context.Context, image string) (dockerexec.GateDecision, error) {
+		return dockerexec.GateDecision{}, scanErr
+	}
+
+	err := cmd.Run()
+	assert.ErrorIs(t, err, scanErr)
+	assert.Nil(t, cmd.GateDecision)
+}
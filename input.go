@@ -0,0 +1,136 @@
+package dockerexec
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// InputSpec is a path copied into the container before it starts, see Cmd.Inputs.
+type InputSpec struct {
+	// HostPath is the path of a file or a directory on the host.
+	HostPath string
+
+	// ContainerDest is the path in the container to copy the file to, or to copy the contents of
+	// the directory into. Its parent directory must already exist in the image.
+	ContainerDest string
+
+	// Mode, if non-zero, overrides the permissions of the copied files, but not of directories.
+	Mode os.FileMode
+}
+
+// InputError records a failure to copy an input into the container, see Cmd.Inputs.
+type InputError struct {
+	Input InputSpec
+	Err   error
+}
+
+func (e *InputError) Error() string {
+	return fmt.Sprintf("dockerexec: input %s: %v", e.Input.HostPath, e.Err)
+}
+
+func (e *InputError) Unwrap() error {
+	return e.Err
+}
+
+// copyInputs copies Inputs into the created container.
+func (c *Cmd) copyInputs(ctx context.Context, id string) error {
+	for _, input := range c.Inputs {
+		if err := c.copyInput(ctx, id, input); err != nil {
+			return &InputError{Input: input, Err: err}
+		}
+	}
+	return nil
+}
+
+func (c *Cmd) copyInput(ctx context.Context, id string, input InputSpec) error {
+	// Fail early on a missing path rather than with a broken archive
+	if _, err := os.Lstat(input.HostPath); err != nil {
+		return err
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(writeInputArchive(pw, input))
+	}()
+	defer pr.Close()
+
+	dir := path.Dir(path.Clean(input.ContainerDest))
+	err := c.cli.CopyToContainer(ctx, id, dir, pr, container.CopyToContainerOptions{})
+	if err != nil && c.HostConfig != nil && c.HostConfig.ReadonlyRootfs {
+		// The daemon refuses copying into a read-only root filesystem, and tmpfs mounts aren't
+		// mounted until the container starts
+		return fmt.Errorf("dockerexec: copy to container: %w (with HostConfig.ReadonlyRootfs, as set by ProfileSecure or ForceReadOnlyRootfs, inputs can only be copied into volumes and bind mounts)", err)
+	}
+	return wrapError("copy to container", err)
+}
+
+// writeInputArchive writes a tar archive of input.HostPath to w, with the names of its entries
+// rooted at the base name of input.ContainerDest.
+func writeInputArchive(w io.Writer, input InputSpec) error {
+	base := path.Base(path.Clean(input.ContainerDest))
+	tw := tar.NewWriter(w)
+
+	err := filepath.WalkDir(input.HostPath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		var link string
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(p); err != nil {
+				return err
+			}
+		} else if !info.Mode().IsRegular() && !info.IsDir() {
+			// Skip special files, such as sockets
+			return nil
+		}
+
+		h, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(input.HostPath, p)
+		if err != nil {
+			return err
+		}
+		h.Name = path.Join(base, filepath.ToSlash(rel))
+		if info.IsDir() {
+			h.Name += "/"
+		} else if input.Mode != 0 && info.Mode().IsRegular() {
+			h.Mode = int64(input.Mode.Perm())
+		}
+		// Ownership on the host is meaningless in the container
+		h.Uid, h.Gid, h.Uname, h.Gname = 0, 0, "", ""
+
+		if err := tw.WriteHeader(h); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
@@ -0,0 +1,77 @@
+package dockerexec_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestInputs(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "file.txt"), []byte("file\n"), 0o644))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "data", "sub"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "data", "a"), []byte("a\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "data", "sub", "b"), []byte("b\n"), 0o644))
+
+	cmd := dockerexec.Command(dockerClient, testImage, "sh", "-c",
+		"cat /tmp/input.txt /data/a /data/sub/b && stat -c %a /tmp/input.txt")
+	cmd.Inputs = []dockerexec.InputSpec{
+		{HostPath: filepath.Join(dir, "file.txt"), ContainerDest: "/tmp/input.txt", Mode: 0o600},
+		{HostPath: filepath.Join(dir, "data"), ContainerDest: "/data"},
+	}
+	output, err := cmd.Output()
+	require.NoError(t, err)
+	assert.Equal(t, "file\na\nb\n600\n", string(output))
+}
+
+func TestInputsMissing(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "true")
+	cmd.Inputs = []dockerexec.InputSpec{
+		{HostPath: filepath.Join(t.TempDir(), "missing"), ContainerDest: "/tmp/missing"},
+	}
+	err := cmd.Run()
+
+	var inputErr *dockerexec.InputError
+	require.ErrorAs(t, err, &inputErr)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	assert.Empty(t, cmd.ContainerID)
+}
+
+func TestInputsReadonlyRootfs(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file.txt")
+	require.NoError(t, os.WriteFile(file, []byte("file\n"), 0o644))
+
+	cmd := dockerexec.Command(dockerClient, testImage, "cat", "/tmp/input.txt")
+	cmd.ApplyProfile(dockerexec.ProfileSecure)
+	cmd.Inputs = []dockerexec.InputSpec{{HostPath: file, ContainerDest: "/tmp/input.txt"}}
+	err := cmd.Run()
+
+	var inputErr *dockerexec.InputError
+	require.ErrorAs(t, err, &inputErr)
+	assert.ErrorContains(t, err, "HostConfig.ReadonlyRootfs")
+}
+
+func TestInputsReusedContainer(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file.txt")
+	require.NoError(t, os.WriteFile(file, []byte("file\n"), 0o644))
+
+	const name = "dockerexec-test-inputs-reuse"
+	existing := dockerexec.Command(dockerClient, testImage, "sleep", "120")
+	existing.ContainerName = name
+	require.NoError(t, existing.Start())
+	defer func() {
+		_ = existing.Kill("SIGKILL")
+		_ = existing.Wait()
+	}()
+
+	cmd := dockerexec.Command(dockerClient, testImage, "cat", "/tmp/input.txt")
+	cmd.ContainerName = name
+	cmd.OnNameConflict = dockerexec.NameConflictReuse
+	cmd.Inputs = []dockerexec.InputSpec{{HostPath: file, ContainerDest: "/tmp/input.txt"}}
+	assert.ErrorContains(t, cmd.Start(), "can't copy Inputs")
+}
@@ -0,0 +1,107 @@
+package dockerexec
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+)
+
+// JSONLogEntry is a line of output of a container, as retained by the json-file log driver.
+type JSONLogEntry struct {
+	// Log is the line, including its trailing newline, unless it's the partial last line of the
+	// output.
+	Log string
+
+	// Stream is the stream the line was written to, "stdout" or "stderr".
+	Stream string
+
+	// Time is the time the line, or its first part if it was split, was logged.
+	Time time.Time
+
+	// Attrs contains the extra attributes logged with the line, per the labels and env log
+	// options.
+	Attrs map[string]string
+}
+
+// jsonLogRecord is a record in a json-file log file.
+type jsonLogRecord struct {
+	Log    string            `json:"log"`
+	Stream string            `json:"stream"`
+	Time   time.Time         `json:"time"`
+	Attrs  map[string]string `json:"attrs,omitempty"`
+}
+
+// JSONLogDecoder decodes the log files of the json-file log driver, found at the LogPath of the
+// container in its inspect data, into lines.
+//
+// The daemon splits long lines (over 16KiB) into several records, writing all but the last
+// without a trailing newline. JSONLogDecoder joins them back into a single entry, separately for
+// each stream, since the records of stdout and stderr might be interleaved.
+type JSONLogDecoder struct {
+	dec     *json.Decoder
+	pending map[string]*JSONLogEntry // partial lines by stream
+	order   []string                 // streams with partial lines, in order
+	err     error
+}
+
+// NewJSONLogDecoder returns a JSONLogDecoder that reads from r.
+func NewJSONLogDecoder(r io.Reader) *JSONLogDecoder {
+	return &JSONLogDecoder{
+		dec:     json.NewDecoder(r),
+		pending: make(map[string]*JSONLogEntry),
+	}
+}
+
+// Decode returns the next line. It returns io.EOF once there are no more lines. Lines that are
+// still partial at the end of the file are returned as is before that.
+func (d *JSONLogDecoder) Decode() (JSONLogEntry, error) {
+	for d.err == nil {
+		var record jsonLogRecord
+		if err := d.dec.Decode(&record); err != nil {
+			d.err = err
+			break
+		}
+
+		entry := d.pending[record.Stream]
+		if entry == nil {
+			entry = &JSONLogEntry{Stream: record.Stream, Time: record.Time, Attrs: record.Attrs}
+		}
+		entry.Log += record.Log
+
+		if strings.HasSuffix(record.Log, "\n") {
+			if d.pending[record.Stream] != nil {
+				delete(d.pending, record.Stream)
+				d.removeOrder(record.Stream)
+			}
+			return *entry, nil
+		}
+
+		if d.pending[record.Stream] == nil {
+			d.pending[record.Stream] = entry
+			d.order = append(d.order, record.Stream)
+		}
+	}
+
+	if d.err != io.EOF {
+		return JSONLogEntry{}, d.err
+	}
+
+	if len(d.order) > 0 {
+		stream := d.order[0]
+		d.order = d.order[1:]
+		entry := d.pending[stream]
+		delete(d.pending, stream)
+		return *entry, nil
+	}
+	return JSONLogEntry{}, io.EOF
+}
+
+func (d *JSONLogDecoder) removeOrder(stream string) {
+	for i, s := range d.order {
+		if s == stream {
+			d.order = append(d.order[:i], d.order[i+1:]...)
+			return
+		}
+	}
+}
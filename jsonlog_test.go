@@ -0,0 +1,49 @@
+package dockerexec_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestJSONLogDecoder(t *testing.T) {
+	input := `{"log":"hello\n","stream":"stdout","time":"2024-01-01T00:00:00.000000001Z"}
+{"log":"long ","stream":"stdout","time":"2024-01-01T00:00:01Z"}
+{"log":"oops\n","stream":"stderr","time":"2024-01-01T00:00:02Z","attrs":{"tag":"x"}}
+{"log":"line\n","stream":"stdout","time":"2024-01-01T00:00:03Z"}
+{"log":"partial","stream":"stderr","time":"2024-01-01T00:00:04Z"}
+`
+	dec := dockerexec.NewJSONLogDecoder(strings.NewReader(input))
+
+	var entries []dockerexec.JSONLogEntry
+	for {
+		entry, err := dec.Decode()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		entries = append(entries, entry)
+	}
+
+	require.Len(t, entries, 4)
+	assert.Equal(t, "hello\n", entries[0].Log)
+	assert.Equal(t, 1, entries[0].Time.Nanosecond())
+	assert.Equal(t, "oops\n", entries[1].Log)
+	assert.Equal(t, "stderr", entries[1].Stream)
+	assert.Equal(t, map[string]string{"tag": "x"}, entries[1].Attrs)
+	assert.Equal(t, "long line\n", entries[2].Log)
+	assert.Equal(t, 1, entries[2].Time.Second())
+	assert.Equal(t, "partial", entries[3].Log)
+}
+
+func TestJSONLogDecoderError(t *testing.T) {
+	dec := dockerexec.NewJSONLogDecoder(strings.NewReader(`{"log":`))
+	_, err := dec.Decode()
+	assert.Error(t, err)
+	assert.NotEqual(t, io.EOF, err)
+}
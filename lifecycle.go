@@ -0,0 +1,159 @@
+package dockerexec
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+)
+
+// State is a state in the lifecycle of the container of a Cmd.
+type State int
+
+// States in the lifecycle of a container, in order.
+const (
+	StateNew     State = iota // not created yet
+	StateCreated              // created but not started
+	StateStarted              // started
+	StateExited               // exited, as observed by Wait
+	StateRemoved              // removed
+)
+
+func (s State) String() string {
+	switch s {
+	case StateNew:
+		return "new"
+	case StateCreated:
+		return "created"
+	case StateStarted:
+		return "started"
+	case StateExited:
+		return "exited"
+	case StateRemoved:
+		return "removed"
+	default:
+		return "State(" + strconv.Itoa(int(s)) + ")"
+	}
+}
+
+// Transition is a transition between states in the lifecycle of a container.
+type Transition struct {
+	From, To State
+	Time     time.Time
+
+	// StatusCode is the status code of the container, when To is StateExited.
+	StatusCode int64
+}
+
+// lifecycle tracks the State of a Cmd and delivers its transitions to subscribers.
+type lifecycle struct {
+	mu       sync.Mutex
+	state    State
+	subs     []chan Transition
+	finished bool
+}
+
+// stateFromStatus returns the State corresponding to the state of an existing container.
+func stateFromStatus(state *types.ContainerState) State {
+	switch {
+	case state == nil:
+		return StateNew
+	case state.Status == "created":
+		return StateCreated
+	case state.Status == "exited" || state.Status == "dead":
+		return StateExited
+	default:
+		return StateStarted
+	}
+}
+
+// State returns the current state in the lifecycle of the container.
+func (c *Cmd) State() State {
+	c.lifecycle.mu.Lock()
+	defer c.lifecycle.mu.Unlock()
+	return c.lifecycle.state
+}
+
+// Subscribe returns a channel delivering the transitions in the lifecycle of the container from
+// now on, so that orchestration layers don't need to poll for them. The channel is closed once no
+// more transitions will be delivered, which is after Wait returns, or once the container is
+// removed if HostConfig.AutoRemove is set.
+//
+// The transition to StateExited is delivered by Wait. The transition to StateRemoved is only
+// delivered for containers using HostConfig.AutoRemove, or removed by someone else, see
+// ContainerRemovedError.
+func (c *Cmd) Subscribe() <-chan Transition {
+	c.lifecycle.mu.Lock()
+	defer c.lifecycle.mu.Unlock()
+
+	// There are at most 4 transitions, so sends never block.
+	ch := make(chan Transition, 4)
+	if c.lifecycle.finished {
+		close(ch)
+	} else {
+		c.lifecycle.subs = append(c.lifecycle.subs, ch)
+	}
+	return ch
+}
+
+// transition moves the lifecycle to state, if it's a later state.
+func (c *Cmd) transition(to State, statusCode int64) {
+	c.lifecycle.mu.Lock()
+	defer c.lifecycle.mu.Unlock()
+
+	if to <= c.lifecycle.state || c.lifecycle.finished {
+		return
+	}
+	t := Transition{From: c.lifecycle.state, To: to, Time: time.Now(), StatusCode: statusCode}
+	c.lifecycle.state = to
+	for _, ch := range c.lifecycle.subs {
+		ch <- t
+	}
+}
+
+// finishLifecycle closes the channels of the subscribers.
+func (c *Cmd) finishLifecycle() {
+	c.lifecycle.mu.Lock()
+	defer c.lifecycle.mu.Unlock()
+
+	if c.lifecycle.finished {
+		return
+	}
+	c.lifecycle.finished = true
+	for _, ch := range c.lifecycle.subs {
+		close(ch)
+	}
+	c.lifecycle.subs = nil
+}
+
+// watchRemoval delivers the transition to StateRemoved once the container is removed by the
+// daemon, when using HostConfig.AutoRemove and there are subscribers, and reports whether it
+// does.
+func (c *Cmd) watchRemoval(id string) bool {
+	c.lifecycle.mu.Lock()
+	subscribed := len(c.lifecycle.subs) > 0
+	c.lifecycle.mu.Unlock()
+
+	if !subscribed || c.HostConfig == nil || !c.HostConfig.AutoRemove {
+		return false
+	}
+
+	waitCh, errCh := c.cli.ContainerWait(context.Background(), id, container.WaitConditionRemoved)
+	go func() {
+		select {
+		case <-waitCh:
+			c.transition(StateRemoved, -1)
+		case err := <-errCh:
+			if client.IsErrNotFound(err) {
+				// Already removed.
+				c.transition(StateRemoved, -1)
+			}
+		}
+		c.finishLifecycle()
+	}()
+	return true
+}
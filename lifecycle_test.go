@@ -0,0 +1,34 @@
+package dockerexec_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestLifecycle(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "sh", "-c", "exit 3")
+	assert.Equal(t, dockerexec.StateNew, cmd.State())
+
+	transitions := cmd.Subscribe()
+	require.NoError(t, cmd.Start())
+	assert.Equal(t, dockerexec.StateStarted, cmd.State())
+	assert.Error(t, cmd.Wait())
+
+	var states []dockerexec.State
+	for transition := range transitions {
+		states = append(states, transition.To)
+		if transition.To == dockerexec.StateExited {
+			assert.EqualValues(t, 3, transition.StatusCode)
+		}
+	}
+	assert.Equal(t, []dockerexec.State{
+		dockerexec.StateCreated,
+		dockerexec.StateStarted,
+		dockerexec.StateExited,
+		dockerexec.StateRemoved,
+	}, states)
+}
@@ -0,0 +1,69 @@
+package dockerexec
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrLimitReached is returned by Start when the Cmd's Limiter is saturated and FailFast is set.
+var ErrLimitReached = errors.New("dockerexec: concurrency limit reached")
+
+// A Limiter limits the number of containers running concurrently through the Cmds sharing it,
+// protecting a shared daemon from a thundering herd.
+//
+// A container occupies a slot of the Limiter from Start until Wait sees it exit.
+type Limiter struct {
+	// FailFast makes Start fail with ErrLimitReached when the Limiter is saturated, instead of
+	// blocking until a slot frees up or the Cmd's context is done.
+	FailFast bool
+
+	sem chan struct{}
+}
+
+// DefaultLimiter is the Limiter used by Cmds created by Command and CommandContext. It is nil by
+// default, meaning there is no limit. It should be set once before creating any Cmd, to limit the
+// number of containers running concurrently through this package process-wide.
+var DefaultLimiter *Limiter
+
+// NewLimiter returns a Limiter allowing up to n containers to run concurrently.
+func NewLimiter(n int) *Limiter {
+	if n <= 0 {
+		panic("dockerexec: non-positive limit")
+	}
+	return &Limiter{sem: make(chan struct{}, n)}
+}
+
+// Running returns the number of containers currently occupying a slot of the Limiter.
+func (l *Limiter) Running() int {
+	return len(l.sem)
+}
+
+func (l *Limiter) acquire(ctx context.Context) error {
+	if l.FailFast {
+		select {
+		case l.sem <- struct{}{}:
+			return nil
+		default:
+			return ErrLimitReached
+		}
+	}
+
+	select {
+	case l.sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l *Limiter) release() {
+	<-l.sem
+}
+
+// releaseLimiter releases the slot c occupies in its Limiter, if any.
+func (c *Cmd) releaseLimiter() {
+	if c.limiterAcquired {
+		c.limiterAcquired = false
+		c.Limiter.release()
+	}
+}
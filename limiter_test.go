@@ -0,0 +1,58 @@
+package dockerexec_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestLimiterFailFast(t *testing.T) {
+	limiter := dockerexec.NewLimiter(1)
+	limiter.FailFast = true
+
+	cmd1 := dockerexec.Command(dockerClient, testImage, "sleep", "120")
+	cmd1.Limiter = limiter
+	err := cmd1.Start()
+	require.NoError(t, err)
+	assert.Equal(t, 1, limiter.Running())
+
+	cmd2 := dockerexec.Command(dockerClient, testImage, "true")
+	cmd2.Limiter = limiter
+	err = cmd2.Start()
+	assert.ErrorIs(t, err, dockerexec.ErrLimitReached)
+
+	require.NoError(t, cmd1.Kill("SIGKILL"))
+	_ = cmd1.Wait()
+	assert.Equal(t, 0, limiter.Running())
+
+	err = cmd2.Run()
+	require.NoError(t, err)
+	assert.Equal(t, 0, limiter.Running())
+}
+
+func TestLimiterBlocks(t *testing.T) {
+	limiter := dockerexec.NewLimiter(1)
+
+	cmd1 := dockerexec.Command(dockerClient, testImage, "sleep", "120")
+	cmd1.Limiter = limiter
+	err := cmd1.Start()
+	require.NoError(t, err)
+	defer func() {
+		_ = cmd1.Kill("SIGKILL")
+		_ = cmd1.Wait()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	cmd2 := dockerexec.CommandContext(ctx, dockerClient, testImage, "true")
+	cmd2.Limiter = limiter
+	err = cmd2.Start()
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Empty(t, cmd2.ContainerID)
+}
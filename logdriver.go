@@ -0,0 +1,63 @@
+package dockerexec
+
+import (
+	"errors"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// ErrLogsNotReadable is matched, using errors.Is, by a *LogDriverError.
+var ErrLogsNotReadable = errors.New("dockerexec: logs are not readable")
+
+// LogDriverError is returned when reading the logs of a container, or a Swarm job, whose log
+// driver doesn't support reading them back, instead of returning empty logs.
+type LogDriverError struct {
+	Driver string
+}
+
+func (e *LogDriverError) Error() string {
+	return "dockerexec: log driver " + e.Driver + " does not support reading logs"
+}
+
+func (e *LogDriverError) Is(target error) bool {
+	return target == ErrLogsNotReadable
+}
+
+// SetLogDriver sets the log driver of the container, and its options, in HostConfig.LogConfig.
+//
+// Note that the output of the container is captured by attaching to it regardless of the log
+// driver, so Stdout and Stderr work with any driver, while Logs and FollowLogs fail with a
+// *LogDriverError for drivers that don't support reading logs back.
+func (c *Cmd) SetLogDriver(driver string, opts map[string]string) {
+	if c.HostConfig == nil {
+		c.HostConfig = &container.HostConfig{}
+	}
+	c.HostConfig.LogConfig = container.LogConfig{Type: driver, Config: opts}
+}
+
+// LogsReadable reports whether the logs of a container using the given log driver, and options,
+// can be read back from the daemon.
+//
+// The json-file, local and journald drivers can be read natively. Other drivers can be read
+// through the daemon's dual logging cache (Docker 20.10 and later), unless it's disabled using the
+// cache-disabled option. The none driver can never be read. An empty driver means the daemon's
+// default driver, which is assumed to be readable.
+func LogsReadable(driver string, opts map[string]string) bool {
+	switch driver {
+	case "", "json-file", "local", "journald":
+		return true
+	case "none":
+		return false
+	default:
+		return opts["cache-disabled"] != "true"
+	}
+}
+
+// checkLogsReadable returns a *LogDriverError if the logs of a container using config can't be
+// read back.
+func checkLogsReadable(config container.LogConfig) error {
+	if !LogsReadable(config.Type, config.Config) {
+		return &LogDriverError{Driver: config.Type}
+	}
+	return nil
+}
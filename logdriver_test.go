@@ -0,0 +1,52 @@
+package dockerexec_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestLogsReadable(t *testing.T) {
+	assert.True(t, dockerexec.LogsReadable("", nil))
+	assert.True(t, dockerexec.LogsReadable("json-file", nil))
+	assert.True(t, dockerexec.LogsReadable("syslog", nil))
+	assert.False(t, dockerexec.LogsReadable("syslog", map[string]string{"cache-disabled": "true"}))
+	assert.False(t, dockerexec.LogsReadable("none", nil))
+}
+
+func TestSetLogDriver(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "echo", "hello")
+	cmd.HostConfig.AutoRemove = false
+	cmd.SetLogDriver("none", nil)
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	require.NoError(t, cmd.Run())
+	defer func() {
+		_ = dockerClient.ContainerRemove(context.Background(), cmd.ContainerID, container.RemoveOptions{Force: true})
+	}()
+
+	// Output is captured by attaching regardless of the log driver.
+	assert.Equal(t, "hello\n", stdout.String())
+
+	_, err := cmd.Logs(container.LogsOptions{ShowStdout: true})
+	assert.ErrorIs(t, err, dockerexec.ErrLogsNotReadable)
+
+	err = cmd.FollowLogs(context.Background(), &stdout, nil)
+	var lde *dockerexec.LogDriverError
+	require.ErrorAs(t, err, &lde)
+	assert.Equal(t, "none", lde.Driver)
+}
+
+func TestSwarmLogDriverNotReadable(t *testing.T) {
+	cmd := dockerexec.SwarmCommand(dockerClient, testImage, "echo", "hello")
+	cmd.Spec.TaskTemplate.LogDriver = &swarm.Driver{Name: "none"}
+	_, err := cmd.Output()
+	assert.ErrorIs(t, err, dockerexec.ErrLogsNotReadable)
+}
@@ -0,0 +1,171 @@
+package dockerexec
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// LogFile is an io.WriteCloser that writes to a file, rotating it once it reaches MaxSize. It can
+// be set as Cmd.OutputLog to keep bounded on-disk logs of the output of containers.
+//
+// Rotated files are named by appending a number to Filename, where 1 is the most recent (e.g.
+// output.log.1, output.log.2), and are optionally compressed with gzip (e.g. output.log.1.gz).
+//
+// A LogFile is safe for concurrent use and may be shared between several Cmds. It is opened on
+// first write.
+type LogFile struct {
+	// Filename is the path of the log file.
+	Filename string
+
+	// MaxSize is the size in bytes at which the file is rotated. Zero means the file is never
+	// rotated.
+	MaxSize int64
+
+	// MaxBackups is the maximum number of rotated files to keep. Zero means rotated files are
+	// discarded.
+	MaxBackups int
+
+	// Compress enables compressing rotated files with gzip.
+	Compress bool
+
+	// Perm is the permissions used to create the file, defaults to 0644.
+	Perm fs.FileMode
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// Write writes p to the file, rotating it first if needed. A single write is never split between
+// files, so the file might exceed MaxSize if p is larger than it.
+func (l *LogFile) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		if err := l.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	if l.MaxSize > 0 && l.size > 0 && l.size+int64(len(p)) > l.MaxSize {
+		if err := l.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := l.file.Write(p)
+	l.size += int64(n)
+	return n, err
+}
+
+// Close closes the file. A later write reopens it.
+func (l *LogFile) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+func (l *LogFile) open() error {
+	perm := l.Perm
+	if perm == 0 {
+		perm = 0o644
+	}
+
+	f, err := os.OpenFile(l.Filename, os.O_WRONLY|os.O_APPEND|os.O_CREATE, perm)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	l.file = f
+	l.size = info.Size()
+	return nil
+}
+
+func (l *LogFile) backupName(n int) string {
+	name := l.Filename + "." + strconv.Itoa(n)
+	if l.Compress {
+		name += ".gz"
+	}
+	return name
+}
+
+func (l *LogFile) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+	l.file = nil
+
+	if l.MaxBackups <= 0 {
+		if err := os.Remove(l.Filename); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return l.open()
+	}
+
+	if err := os.Remove(l.backupName(l.MaxBackups)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	for n := l.MaxBackups - 1; n >= 1; n-- {
+		err := os.Rename(l.backupName(n), l.backupName(n+1))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+
+	if l.Compress {
+		if err := compressFile(l.Filename, l.backupName(1)); err != nil {
+			return err
+		}
+	} else if err := os.Rename(l.Filename, l.backupName(1)); err != nil {
+		return err
+	}
+
+	return l.open()
+}
+
+// compressFile compresses src into dst with gzip and removes src.
+func compressFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(out)
+	_, err = io.Copy(gz, in)
+	if err1 := gz.Close(); err == nil {
+		err = err1
+	}
+	if err1 := out.Close(); err == nil {
+		err = err1
+	}
+	if err != nil {
+		os.Remove(dst)
+		return err
+	}
+
+	in.Close()
+	return os.Remove(src)
+}
@@ -0,0 +1,85 @@
+package dockerexec_test
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestLogFileRotation(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "output.log")
+	logFile := &dockerexec.LogFile{
+		Filename:   filename,
+		MaxSize:    10,
+		MaxBackups: 2,
+	}
+	defer logFile.Close()
+
+	for _, s := range []string{"aaaaaaaa\n", "bbbbbbbb\n", "cccccccc\n", "dddddddd\n"} {
+		_, err := io.WriteString(logFile, s)
+		require.NoError(t, err)
+	}
+
+	assertFileContent(t, filename, "dddddddd\n")
+	assertFileContent(t, filename+".1", "cccccccc\n")
+	assertFileContent(t, filename+".2", "bbbbbbbb\n")
+	assert.NoFileExists(t, filename+".3")
+}
+
+func TestLogFileCompress(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "output.log")
+	logFile := &dockerexec.LogFile{
+		Filename:   filename,
+		MaxSize:    10,
+		MaxBackups: 1,
+		Compress:   true,
+	}
+	defer logFile.Close()
+
+	for _, s := range []string{"aaaaaaaa\n", "bbbbbbbb\n"} {
+		_, err := io.WriteString(logFile, s)
+		require.NoError(t, err)
+	}
+
+	assertFileContent(t, filename, "bbbbbbbb\n")
+
+	f, err := os.Open(filename + ".1.gz")
+	require.NoError(t, err)
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	content, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	assert.Equal(t, "aaaaaaaa\n", string(content))
+}
+
+func TestOutputLog(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "output.log")
+	logFile := &dockerexec.LogFile{Filename: filename}
+	defer logFile.Close()
+
+	cmd := dockerexec.Command(dockerClient, testImage, "sh", "-c", "echo stdout; sleep 0.5; echo stderr >&2")
+	cmd.OutputLog = logFile
+
+	output, err := cmd.Output()
+	require.NoError(t, err)
+	assert.Equal(t, "stdout\n", string(output))
+
+	assertFileContent(t, filename, "stdout\nstderr\n")
+}
+
+func assertFileContent(t *testing.T, filename string, expected string) {
+	t.Helper()
+
+	content, err := os.ReadFile(filename)
+	if assert.NoError(t, err) {
+		assert.Equal(t, expected, string(content))
+	}
+}
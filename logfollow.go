@@ -0,0 +1,180 @@
+package dockerexec
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+)
+
+// followReconnectDelay is the delay before FollowLogs reconnects after the log stream ends while
+// the container is still running.
+const followReconnectDelay = 100 * time.Millisecond
+
+// FollowLogs streams the logs of a container to stdout and stderr, either of which may be nil to
+// discard it, until the container exits or ctx becomes done, in which case it returns ctx.Err().
+//
+// Unlike following the logs using ContainerLogs directly, FollowLogs transparently reconnects if
+// the stream ends while the container is still running, such as can happen when the daemon
+// rotates a json-file or local log file, or restarts. Lines are requested with timestamps, which
+// are used to resume from the last line delivered without duplicating it, and stripped before
+// being written.
+//
+// If the log driver of the container doesn't support reading logs back, a *LogDriverError is
+// returned.
+func FollowLogs(ctx context.Context, cli Client, id string, stdout, stderr io.Writer) error {
+	inspect, err := cli.ContainerInspect(ctx, id)
+	if err != nil {
+		return wrapError("inspect container", err)
+	}
+	if inspect.HostConfig != nil {
+		if err := checkLogsReadable(inspect.HostConfig.LogConfig); err != nil {
+			return err
+		}
+	}
+	tty := inspect.Config != nil && inspect.Config.Tty
+
+	var last time.Time
+	outw := &timestampWriter{w: stdout, last: &last}
+	errw := &timestampWriter{w: stderr, last: &last}
+
+	follow := inspect.State != nil && inspect.State.Running
+	for {
+		options := container.LogsOptions{
+			ShowStdout: stdout != nil,
+			ShowStderr: stderr != nil,
+			Follow:     follow,
+			Timestamps: true,
+		}
+		if !last.IsZero() {
+			options.Since = fmt.Sprintf("%d.%09d", last.Unix(), last.Nanosecond())
+		}
+
+		logs, err := cli.ContainerLogs(ctx, id, options)
+		if err != nil {
+			if client.IsErrNotFound(err) {
+				return nil
+			}
+			return wrapError("container logs", err)
+		}
+
+		err = copyOutput(logs, tty, outw, errw)
+		logs.Close()
+		if flushErr := outw.flush(); err == nil {
+			err = flushErr
+		}
+		if flushErr := errw.flush(); err == nil {
+			err = flushErr
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		var ce *CopyError
+		if errors.As(err, &ce) && ce.write {
+			return err
+		}
+		if !follow {
+			return nil
+		}
+
+		inspect, err := cli.ContainerInspect(ctx, id)
+		if err != nil {
+			if client.IsErrNotFound(err) {
+				return nil
+			}
+			return wrapError("inspect container", err)
+		}
+		// Once the container stops, fetch whatever was missed without following.
+		follow = inspect.State != nil && inspect.State.Running
+
+		if follow {
+			select {
+			case <-time.After(followReconnectDelay):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+// FollowLogs streams the logs of the container to stdout and stderr until it exits, reconnecting
+// as needed. See FollowLogs.
+//
+// The container must have been started by Start.
+func (c *Cmd) FollowLogs(ctx context.Context, stdout, stderr io.Writer) error {
+	if len(c.ContainerID) == 0 {
+		return errors.New("dockerexec: not started")
+	}
+	return FollowLogs(ctx, c.cli, c.ContainerID, stdout, stderr)
+}
+
+// FollowLogs streams the logs of the current container of the service to stdout and stderr until
+// it exits, reconnecting as needed. See FollowLogs.
+func (s *Service) FollowLogs(ctx context.Context, stdout, stderr io.Writer) error {
+	s.mu.Lock()
+	cmd := s.cmd
+	s.mu.Unlock()
+
+	if cmd == nil {
+		return errors.New("dockerexec: service not started")
+	}
+	return cmd.FollowLogs(ctx, stdout, stderr)
+}
+
+// timestampWriter strips the timestamps prepended to each line of logs requested with
+// timestamps, dropping lines not newer than the last line delivered, as they were already
+// delivered before reconnecting.
+type timestampWriter struct {
+	w    io.Writer // nil to discard
+	last *time.Time
+	buf  []byte
+}
+
+func (w *timestampWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		if err := w.line(w.buf[:i+1]); err != nil {
+			return len(p), err
+		}
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// flush writes a trailing partial line, such as a partial message or the end of the stream.
+func (w *timestampWriter) flush() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+	err := w.line(w.buf)
+	w.buf = nil
+	return err
+}
+
+func (w *timestampWriter) line(line []byte) error {
+	if i := bytes.IndexByte(line, ' '); i > 0 {
+		if t, err := time.Parse(time.RFC3339Nano, string(line[:i])); err == nil {
+			if !t.After(*w.last) {
+				return nil
+			}
+			*w.last = t
+			line = line[i+1:]
+		}
+	}
+
+	if w.w == nil {
+		return nil
+	}
+	_, err := w.w.Write(line)
+	return err
+}
@@ -0,0 +1,34 @@
+package dockerexec_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestFollowLogs(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "sh", "-c", "for i in 1 2 3; do echo out $i; echo err $i >&2; sleep 0.2; done")
+	cmd.HostConfig.AutoRemove = false
+	require.NoError(t, cmd.Start())
+	defer func() {
+		_ = dockerClient.ContainerRemove(context.Background(), cmd.ContainerID, container.RemoveOptions{Force: true})
+	}()
+
+	var stdout, stderr bytes.Buffer
+	require.NoError(t, cmd.FollowLogs(context.Background(), &stdout, &stderr))
+	require.NoError(t, cmd.Wait())
+
+	assert.Equal(t, "out 1\nout 2\nout 3\n", stdout.String())
+	assert.Equal(t, "err 1\nerr 2\nerr 3\n", stderr.String())
+
+	// Following an exited container returns its logs.
+	stdout.Reset()
+	require.NoError(t, dockerexec.FollowLogs(context.Background(), dockerClient, cmd.ContainerID, &stdout, nil))
+	assert.Equal(t, "out 1\nout 2\nout 3\n", stdout.String())
+}
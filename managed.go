@@ -0,0 +1,142 @@
+package dockerexec
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+)
+
+// Labels applied to every container created by this package.
+const (
+	// LabelManaged marks a container as created by dockerexec. Its value is always "true".
+	LabelManaged = "com.github.segevfiner.dockerexec"
+
+	// LabelOwner records the Cmd.Owner of the container.
+	LabelOwner = "com.github.segevfiner.dockerexec.owner"
+
+	// LabelDeadline records the deadline derived from Cmd.MaxRuntime, in RFC 3339 format. It is
+	// only set when Cmd.MaxRuntime is set.
+	LabelDeadline = "com.github.segevfiner.dockerexec.deadline"
+
+	// LabelSession records the SessionID of the process that created the container.
+	LabelSession = "com.github.segevfiner.dockerexec.session"
+
+	// LabelConfigHash records a hash of the configuration of the container. It is only set when
+	// Cmd.OnNameConflict is NameConflictIdempotent.
+	LabelConfigHash = "com.github.segevfiner.dockerexec.config-hash"
+
+	// LabelCacheVolume marks a volume as a cache volume created by Cmd.WithCacheVolume. Its value
+	// is the name of the cache.
+	LabelCacheVolume = "com.github.segevfiner.dockerexec.cache"
+)
+
+// defaultOwner is the default Cmd.Owner, the base name of the running executable.
+var defaultOwner = filepath.Base(os.Args[0])
+
+// applyManagedLabels adds the management labels to c.Config.Labels.
+func (c *Cmd) applyManagedLabels() {
+	if c.Config.Labels == nil {
+		c.Config.Labels = make(map[string]string)
+	}
+	c.Config.Labels[LabelManaged] = "true"
+	c.Config.Labels[LabelSession] = sessionID
+	if c.Owner != "" {
+		c.Config.Labels[LabelOwner] = c.Owner
+	}
+}
+
+// ManagedContainer is a lightweight handle to a container created by this package.
+type ManagedContainer struct {
+	ID      string
+	Image   string
+	Command string
+	State   string
+	Owner   string
+
+	// Deadline is the deadline derived from Cmd.MaxRuntime, or the zero time if there is none.
+	Deadline time.Time
+}
+
+// ListManaged returns all containers created by this package, including stopped ones, optionally
+// narrowed down by additional filters (e.g. label=com.github.segevfiner.dockerexec.owner=myapp).
+//
+// This is useful for supervisors that need to reconcile their state after a restart.
+func ListManaged(ctx context.Context, cli Client, filter filters.Args) ([]ManagedContainer, error) {
+	filter = filter.Clone()
+	filter.Add("label", LabelManaged+"=true")
+
+	containers, err := cli.ContainerList(ctx, container.ListOptions{
+		All:     true,
+		Filters: filter,
+	})
+	if err != nil {
+		return nil, wrapError("list containers", err)
+	}
+
+	result := make([]ManagedContainer, 0, len(containers))
+	for _, cont := range containers {
+		// A malformed deadline is treated as no deadline
+		deadline, _ := time.Parse(time.RFC3339, cont.Labels[LabelDeadline])
+
+		result = append(result, ManagedContainer{
+			ID:       cont.ID,
+			Image:    cont.Image,
+			Command:  cont.Command,
+			State:    cont.State,
+			Owner:    cont.Labels[LabelOwner],
+			Deadline: deadline,
+		})
+	}
+	return result, nil
+}
+
+// Prune removes exited containers created by this package, along with their anonymous volumes,
+// that were created more than olderThan ago. It returns the IDs of the removed containers.
+//
+// Containers are normally removed automatically once they exit (see Cmd.HostConfig), but
+// long-lived services may still occasionally leave some behind, e.g. when AutoRemove was disabled
+// or the daemon was restarted.
+//
+// Prune attempts to remove all matching containers even if some fail, in which case the returned
+// error joins all failures.
+func Prune(ctx context.Context, cli Client, olderThan time.Duration) ([]string, error) {
+	containers, err := cli.ContainerList(ctx, container.ListOptions{
+		All: true,
+		Filters: filters.NewArgs(
+			filters.Arg("label", LabelManaged+"=true"),
+			filters.Arg("status", "exited"),
+		),
+	})
+	if err != nil {
+		return nil, wrapError("list containers", err)
+	}
+
+	cutoff := time.Now().Add(-olderThan)
+
+	var removed []string
+	var errs []error
+	for _, cont := range containers {
+		if !time.Unix(cont.Created, 0).Before(cutoff) {
+			continue
+		}
+
+		err := cli.ContainerRemove(ctx, cont.ID, container.RemoveOptions{
+			RemoveVolumes: true,
+		})
+		if err != nil {
+			if !client.IsErrNotFound(err) {
+				errs = append(errs, wrapError("remove container", err))
+			}
+			continue
+		}
+		removed = append(removed, cont.ID)
+	}
+
+	return removed, errors.Join(errs...)
+}
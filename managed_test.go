@@ -0,0 +1,57 @@
+package dockerexec_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestListManaged(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cmd := dockerexec.CommandContext(ctx, dockerClient, testImage, "sleep", "120")
+	cmd.Owner = "dockerexec-test-list-managed"
+	err := cmd.Start()
+	require.NoError(t, err)
+	defer func() {
+		cancel()
+		_ = cmd.Wait()
+	}()
+
+	containers, err := dockerexec.ListManaged(context.Background(), dockerClient, filters.NewArgs(
+		filters.Arg("label", dockerexec.LabelOwner+"=dockerexec-test-list-managed"),
+	))
+	require.NoError(t, err)
+	require.Len(t, containers, 1)
+
+	assert.Equal(t, cmd.ContainerID, containers[0].ID)
+	assert.Equal(t, testImage, containers[0].Image)
+	assert.Equal(t, "running", containers[0].State)
+	assert.Equal(t, "dockerexec-test-list-managed", containers[0].Owner)
+}
+
+func TestPrune(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "true")
+	cmd.HostConfig.AutoRemove = false
+	err := cmd.Run()
+	require.NoError(t, err)
+
+	removed, err := dockerexec.Prune(context.Background(), dockerClient, time.Hour)
+	require.NoError(t, err)
+	assert.NotContains(t, removed, cmd.ContainerID)
+
+	removed, err = dockerexec.Prune(context.Background(), dockerClient, 0)
+	require.NoError(t, err)
+	assert.Contains(t, removed, cmd.ContainerID)
+
+	_, err = dockerClient.ContainerInspect(context.Background(), cmd.ContainerID)
+	assert.True(t, client.IsErrNotFound(err))
+}
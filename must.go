@@ -0,0 +1,71 @@
+package dockerexec
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// MustRun is like Run but panics if the container fails to run or exits with a non-zero status.
+//
+// It's meant for scripts and tooling, where there's nothing sensible to do about an error other
+// than aborting. The panic value is an error describing the command, its image and container, and
+// any captured standard error output, which wraps the original error.
+func (c *Cmd) MustRun() {
+	if err := c.Run(); err != nil {
+		panic(c.mustError(err, nil))
+	}
+}
+
+// MustOutput is like Output but panics on error, see MustRun.
+func (c *Cmd) MustOutput() []byte {
+	output, err := c.Output()
+	if err != nil {
+		panic(c.mustError(err, nil))
+	}
+	return output
+}
+
+// MustCombinedOutput is like CombinedOutput but panics on error, see MustRun. The combined output
+// is included in the panic value.
+func (c *Cmd) MustCombinedOutput() []byte {
+	output, err := c.CombinedOutput()
+	if err != nil {
+		panic(c.mustError(err, output))
+	}
+	return output
+}
+
+// mustError is the panic value of the Must methods.
+type mustError struct {
+	context string
+	err     error
+	output  []byte
+}
+
+func (e *mustError) Error() string {
+	s := e.context + ": " + e.err.Error()
+	if len(e.output) > 0 {
+		s += "\n" + string(bytes.TrimRight(e.output, "\n"))
+	}
+	return s
+}
+
+func (e *mustError) Unwrap() error {
+	return e.err
+}
+
+func (c *Cmd) mustError(err error, output []byte) error {
+	context := fmt.Sprintf("dockerexec: %s (image %s", c, c.Config.Image)
+	if c.ContainerID != "" {
+		context += fmt.Sprintf(", container %.12s", c.ContainerID)
+	}
+	context += ")"
+
+	var ee *ExitError
+	if output == nil && errors.As(err, &ee) {
+		output = ee.Stderr
+	}
+
+	return &mustError{context: context, err: err, output: output}
+}
@@ -0,0 +1,29 @@
+package dockerexec_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestMustOutput(t *testing.T) {
+	output := dockerexec.Command(dockerClient, testImage, "echo", "Hello, World!").MustOutput()
+	assert.Equal(t, "Hello, World!\n", string(output))
+
+	defer func() {
+		r := recover()
+		require.NotNil(t, r)
+		err, ok := r.(error)
+		require.True(t, ok)
+
+		var exitErr *dockerexec.ExitError
+		require.ErrorAs(t, err, &exitErr)
+		assert.EqualValues(t, 1, exitErr.StatusCode)
+		assert.Contains(t, err.Error(), "image "+testImage)
+		assert.Contains(t, err.Error(), "exit status 1\nfailed 100%")
+	}()
+	dockerexec.Command(dockerClient, testImage, "sh", "-c", "echo 'failed 100%' >&2; exit 1").MustOutput()
+}
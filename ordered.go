@@ -0,0 +1,129 @@
+package dockerexec
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// logEntry is a message read back from the logs of a container, see OrderedOutput.
+type logEntry struct {
+	time    time.Time
+	stream  Stream
+	payload []byte
+}
+
+// applyOrderedOutput checks that the logs of the container can be read back for OrderedOutput,
+// and disables HostConfig.AutoRemove, remembering to remove the container once they're read
+// instead.
+func (c *Cmd) applyOrderedOutput() error {
+	if c.OrderedOutput == nil {
+		return nil
+	}
+	hc := c.hostConfig()
+	if err := checkLogsReadable(hc.LogConfig); err != nil {
+		return err
+	}
+	if hc.AutoRemove {
+		hc.AutoRemove = false
+		c.removeAfterWait = true
+	}
+	return nil
+}
+
+// copyOrderedOutput reads the output of the exited container back from its logs, with
+// timestamps, and writes it to OrderedOutput in timestamp order.
+func (c *Cmd) copyOrderedOutput() error {
+	logs, err := c.cli.ContainerLogs(context.Background(), c.ContainerID, container.LogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Timestamps: true,
+	})
+	if err != nil {
+		return wrapError("container logs", err)
+	}
+	defer logs.Close()
+
+	entries, err := readLogEntries(logs, c.Config.Tty)
+	if err != nil {
+		return err
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].time.Before(entries[j].time)
+	})
+	for _, entry := range entries {
+		if _, err := c.OrderedOutput.Write(entry.payload); err != nil {
+			return &CopyError{Stream: entry.stream, Err: err, write: true}
+		}
+	}
+	return nil
+}
+
+// readLogEntries reads the messages of logs requested with timestamps, in the order the daemon
+// returns them, stripping the timestamps. Messages without a valid timestamp keep the time of
+// the previous one.
+func readLogEntries(logs io.Reader, tty bool) ([]logEntry, error) {
+	var entries []logEntry
+	var last time.Time
+	add := func(stream Stream, message []byte) {
+		if i := bytes.IndexByte(message, ' '); i > 0 {
+			if t, err := time.Parse(time.RFC3339Nano, string(message[:i])); err == nil {
+				last = t
+				message = message[i+1:]
+			}
+		}
+		entries = append(entries, logEntry{time: last, stream: stream, payload: bytes.Clone(message)})
+	}
+
+	if tty {
+		// Without multiplexing, there's a single stream, with a timestamp at the start of each line
+		data, err := io.ReadAll(logs)
+		if err != nil {
+			return nil, &CopyError{Stream: StreamStdout, Err: err}
+		}
+		for len(data) > 0 {
+			line := data
+			if i := bytes.IndexByte(data, '\n'); i >= 0 {
+				line = data[:i+1]
+			}
+			add(StreamStdout, line)
+			data = data[len(line):]
+		}
+		return entries, nil
+	}
+
+	// Each frame is a single message, prefixed by its timestamp
+	fw := &frameWriter{fn: func(frame Frame) error {
+		add(frame.Stream, frame.Payload)
+		return nil
+	}}
+	if err := fw.copy(logs, false); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// CombinedOutputOrdered is like CombinedOutput, but the output is read back from the logs of the
+// container once it exits, and merged in the order of the timestamps recorded by the daemon, see
+// OrderedOutput.
+func (c *Cmd) CombinedOutputOrdered() ([]byte, error) {
+	if c.Stdout != nil {
+		return nil, errors.New("dockerexec: Stdout already set")
+	}
+	if c.Stderr != nil {
+		return nil, errors.New("dockerexec: Stderr already set")
+	}
+	if c.OrderedOutput != nil {
+		return nil, errors.New("dockerexec: OrderedOutput already set")
+	}
+	var b bytes.Buffer
+	c.OrderedOutput = &b
+	err := c.Run()
+	return b.Bytes(), err
+}
@@ -0,0 +1,58 @@
+package dockerexec_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestCombinedOutputOrdered(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "sh", "-c",
+		"for i in 1 2 3 4 5; do echo out $i; sleep 0.05; echo err $i >&2; sleep 0.05; done")
+	output, err := cmd.CombinedOutputOrdered()
+	require.NoError(t, err)
+	assert.Equal(t, "out 1\nerr 1\nout 2\nerr 2\nout 3\nerr 3\nout 4\nerr 4\nout 5\nerr 5\n", string(output))
+
+	_, err = dockerClient.ContainerInspect(context.Background(), cmd.ContainerID)
+	assert.Error(t, err, "container should have been removed")
+}
+
+func TestOrderedOutputExitError(t *testing.T) {
+	var output bytes.Buffer
+	cmd := dockerexec.Command(dockerClient, testImage, "sh", "-c", "echo out; sleep 0.05; echo err >&2; exit 3")
+	cmd.OrderedOutput = &output
+	err := cmd.Run()
+
+	var exitErr *dockerexec.ExitError
+	require.ErrorAs(t, err, &exitErr)
+	assert.EqualValues(t, 3, exitErr.StatusCode)
+	assert.Equal(t, "out\nerr\n", output.String())
+}
+
+func TestOrderedOutputTty(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "sh", "-c", "echo a; echo b")
+	cmd.Config.Tty = true
+	output, err := cmd.CombinedOutputOrdered()
+	require.NoError(t, err)
+	assert.Equal(t, "a\r\nb\r\n", string(output))
+}
+
+func TestOrderedOutputLogsNotReadable(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "true")
+	cmd.SetLogDriver("none", nil)
+	_, err := cmd.CombinedOutputOrdered()
+	assert.ErrorIs(t, err, dockerexec.ErrLogsNotReadable)
+}
+
+func TestOrderedOutputWithStdout(t *testing.T) {
+	var stdout bytes.Buffer
+	cmd := dockerexec.Command(dockerClient, testImage, "true")
+	cmd.Stdout = &stdout
+	cmd.OrderedOutput = &stdout
+	assert.Error(t, cmd.Start())
+}
@@ -0,0 +1,34 @@
+package dockerexec
+
+import "io"
+
+// hashOutput tees stdout and stderr to the hashes from HashOutput, if set. The hashes are created
+// on the first call, and reused by later ones, so that they cover all of the output copied after
+// Attach too.
+func (c *Cmd) hashOutput(stdout, stderr io.Writer) (io.Writer, io.Writer) {
+	if c.HashOutput == nil {
+		return stdout, stderr
+	}
+
+	if c.stdoutHash == nil {
+		c.stdoutHash = c.HashOutput()
+	}
+	stdout = teeWriter(stdout, c.stdoutHash)
+	if !c.Config.Tty {
+		if c.stderrHash == nil {
+			c.stderrHash = c.HashOutput()
+		}
+		stderr = teeWriter(stderr, c.stderrHash)
+	}
+	return stdout, stderr
+}
+
+// sumOutputHashes sets StdoutDigest and StderrDigest once the output was copied.
+func (c *Cmd) sumOutputHashes() {
+	if c.stdoutHash != nil {
+		c.StdoutDigest = c.stdoutHash.Sum(nil)
+	}
+	if c.stderrHash != nil {
+		c.StderrDigest = c.stderrHash.Sum(nil)
+	}
+}
@@ -0,0 +1,67 @@
+package dockerexec_test
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestHashOutput(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "sh", "-c", "echo out; echo err >&2")
+	cmd.HashOutput = sha256.New
+	output, err := cmd.Output()
+	require.NoError(t, err)
+	assert.Equal(t, "out\n", string(output))
+
+	stdoutDigest := sha256.Sum256([]byte("out\n"))
+	stderrDigest := sha256.Sum256([]byte("err\n"))
+	assert.Equal(t, stdoutDigest[:], cmd.StdoutDigest)
+	assert.Equal(t, stderrDigest[:], cmd.StderrDigest)
+}
+
+func TestHashOutputDiscarded(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "echo", "hello")
+	cmd.HashOutput = md5.New
+	require.NoError(t, cmd.Run())
+
+	digest := md5.Sum([]byte("hello\n"))
+	assert.Equal(t, digest[:], cmd.StdoutDigest)
+}
+
+func TestHashOutputTty(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "echo", "hello")
+	cmd.Config.Tty = true
+	cmd.HashOutput = sha256.New
+	require.NoError(t, cmd.Run())
+
+	digest := sha256.Sum256([]byte("hello\r\n"))
+	assert.Equal(t, digest[:], cmd.StdoutDigest)
+	assert.Nil(t, cmd.StderrDigest)
+}
+
+func TestNoHashOutput(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "true")
+	require.NoError(t, cmd.Run())
+	assert.Nil(t, cmd.StdoutDigest)
+}
+
+func TestHashOutputReattach(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "sh", "-c", "echo before; sleep 2; echo after")
+	lines := make(chan string, 16)
+	cmd.Stdout = &lineWriter{lines: lines}
+	cmd.HashOutput = sha256.New
+	require.NoError(t, cmd.Start())
+	assert.Equal(t, "before", <-lines)
+
+	require.NoError(t, cmd.Detach())
+	require.NoError(t, cmd.Attach())
+	require.NoError(t, cmd.Wait())
+
+	digest := sha256.Sum256([]byte("before\nafter\n"))
+	assert.Equal(t, digest[:], cmd.StdoutDigest)
+}
@@ -0,0 +1,165 @@
+package dockerexec
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+)
+
+// passwdName is the name of the user, and group, added by EnsurePasswd.
+const passwdName = "user"
+
+// passwdHome is the home directory of the user added by EnsurePasswd, which needs to exist and be
+// writable in any image.
+const passwdHome = "/tmp"
+
+// ensurePasswd adds entries for the numeric UID and GID of Config.User to the /etc/passwd and
+// /etc/group files of the created container id, if they are missing, see Cmd.EnsurePasswd.
+func (c *Cmd) ensurePasswd(ctx context.Context, id string) error {
+	uid, gid, ok := numericUser(c.Config.User)
+	if !ok {
+		return nil
+	}
+
+	passwd, passwdHeader, err := c.readContainerFile(ctx, id, "/etc/passwd")
+	if err != nil {
+		return err
+	}
+	group, groupHeader, err := c.readContainerFile(ctx, id, "/etc/group")
+	if err != nil {
+		return err
+	}
+
+	var files []containerFile
+
+	if !hasEntry(passwd, 2, uid) {
+		name := passwdName
+		if hasEntry(passwd, 0, name) {
+			name += uid
+		}
+		if gid == "" {
+			gid = uid
+		}
+		passwd = appendLine(passwd, name+":x:"+uid+":"+gid+"::"+passwdHome+":/bin/sh")
+		files = append(files, containerFile{header: passwdHeader, data: passwd})
+	}
+
+	if gid != "" && !hasEntry(group, 2, gid) {
+		name := passwdName
+		if hasEntry(group, 0, name) {
+			name += gid
+		}
+		group = appendLine(group, name+":x:"+gid+":")
+		files = append(files, containerFile{header: groupHeader, data: group})
+	}
+
+	if len(files) == 0 {
+		return nil
+	}
+	if c.HostConfig != nil && c.HostConfig.ReadonlyRootfs {
+		// The daemon refuses to copy files into a read-only root filesystem
+		return fmt.Errorf("dockerexec: EnsurePasswd can't add user %s to /etc/passwd with HostConfig.ReadonlyRootfs, as set by ProfileSecure or ForceReadOnlyRootfs", c.Config.User)
+	}
+	return c.writeContainerFiles(ctx, id, "/etc", files)
+}
+
+// numericUser parses a user spec of the form "uid" or "uid:gid", reporting whether the UID is
+// numeric. gid is empty if unspecified, and the GID is ignored if it isn't numeric.
+func numericUser(user string) (uid, gid string, ok bool) {
+	uid, gid, _ = strings.Cut(user, ":")
+	if _, err := strconv.ParseUint(uid, 10, 32); err != nil {
+		return "", "", false
+	}
+	if _, err := strconv.ParseUint(gid, 10, 32); err != nil {
+		gid = ""
+	}
+	return uid, gid, true
+}
+
+// hasEntry reports whether any line of a passwd or group file has value as its field'th field.
+func hasEntry(file []byte, field int, value string) bool {
+	for _, line := range strings.Split(string(file), "\n") {
+		fields := strings.Split(line, ":")
+		if len(fields) > field && fields[field] == value {
+			return true
+		}
+	}
+	return false
+}
+
+func appendLine(file []byte, line string) []byte {
+	if len(file) > 0 && file[len(file)-1] != '\n' {
+		file = append(file, '\n')
+	}
+	return append(file, line+"\n"...)
+}
+
+// containerFile is a file to be written to a container.
+type containerFile struct {
+	header tar.Header
+	data   []byte
+}
+
+// readContainerFile reads the file at path from the container, returning an empty file with a
+// default header if it doesn't exist.
+func (c *Cmd) readContainerFile(ctx context.Context, id, path string) ([]byte, tar.Header, error) {
+	header := tar.Header{Name: path[strings.LastIndexByte(path, '/')+1:], Mode: 0o644}
+
+	rc, _, err := c.cli.CopyFromContainer(ctx, id, path)
+	if err != nil {
+		if client.IsErrNotFound(err) {
+			return nil, header, nil
+		}
+		return nil, header, wrapError("copy from container", err)
+	}
+	defer rc.Close()
+
+	tr := tar.NewReader(rc)
+	h, err := tr.Next()
+	if err != nil {
+		return nil, header, wrapError("copy from container", err)
+	}
+	if h.Typeflag != tar.TypeReg {
+		return nil, header, errors.New("dockerexec: " + path + " is not a regular file")
+	}
+	data, err := io.ReadAll(tr)
+	if err != nil {
+		return nil, header, wrapError("copy from container", err)
+	}
+
+	header.Mode = h.Mode
+	header.Uid = h.Uid
+	header.Gid = h.Gid
+	return data, header, nil
+}
+
+// writeContainerFiles writes files to the directory dir of the container.
+func (c *Cmd) writeContainerFiles(ctx context.Context, id, dir string, files []containerFile) error {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, file := range files {
+		header := file.header
+		header.Typeflag = tar.TypeReg
+		header.Size = int64(len(file.data))
+		if err := tw.WriteHeader(&header); err != nil {
+			return err
+		}
+		if _, err := tw.Write(file.data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+
+	err := c.cli.CopyToContainer(ctx, id, dir, &buf, container.CopyToContainerOptions{})
+	return wrapError("copy to container", err)
+}
@@ -0,0 +1,46 @@
+package dockerexec_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestEnsurePasswd(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "sh", "-c", "id -un; id -gn; echo $HOME")
+	cmd.Config.User = "12345:23456"
+	cmd.EnsurePasswd = true
+	output, err := cmd.Output()
+	require.NoError(t, err)
+	assert.Equal(t, "user\nuser\n/tmp\n", string(output))
+
+	// Existing entries are left as is.
+	cmd = dockerexec.Command(dockerClient, testImage, "id", "-un")
+	cmd.Config.User = "0"
+	cmd.EnsurePasswd = true
+	output, err = cmd.Output()
+	require.NoError(t, err)
+	assert.Equal(t, "root\n", string(output))
+}
+
+func TestEnsurePasswdReadonlyRootfs(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "id", "-un")
+	cmd.ApplyProfile(dockerexec.ProfileSecure)
+	cmd.Config.User = "12345:23456"
+	cmd.EnsurePasswd = true
+	err := cmd.Run()
+	assert.ErrorContains(t, err, "HostConfig.ReadonlyRootfs")
+	assert.Empty(t, cmd.ContainerID)
+
+	// Nothing needs to be added for existing entries.
+	cmd = dockerexec.Command(dockerClient, testImage, "id", "-un")
+	cmd.ApplyProfile(dockerexec.ProfileSecure)
+	cmd.Config.User = "0"
+	cmd.EnsurePasswd = true
+	output, err := cmd.Output()
+	require.NoError(t, err)
+	assert.Equal(t, "root\n", string(output))
+}
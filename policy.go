@@ -0,0 +1,82 @@
+package dockerexec
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+)
+
+// A PolicyFunc validates the final configuration of a container right before it is created, and
+// may reject it by returning an error, or mutate it, e.g. to forbid privileged containers or force
+// a read-only root filesystem.
+//
+// hostConfig and networkingConfig may be nil.
+type PolicyFunc func(config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig) error
+
+// A PolicyError is returned by Start when Cmd.Policy rejects the container.
+type PolicyError struct {
+	Err error
+}
+
+func (e *PolicyError) Error() string {
+	return "dockerexec: rejected by policy: " + e.Err.Error()
+}
+
+func (e *PolicyError) Unwrap() error {
+	return e.Err
+}
+
+// Policies returns a PolicyFunc applying each of the given policies in order, stopping at the
+// first one that rejects the container.
+func Policies(policies ...PolicyFunc) PolicyFunc {
+	return func(config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig) error {
+		for _, policy := range policies {
+			if err := policy(config, hostConfig, networkingConfig); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// DenyPrivileged is a PolicyFunc rejecting privileged containers.
+func DenyPrivileged(config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig) error {
+	if hostConfig != nil && hostConfig.Privileged {
+		return errors.New("privileged containers are not allowed")
+	}
+	return nil
+}
+
+// ForceReadOnlyRootfs is a PolicyFunc forcing containers to use a read-only root filesystem.
+func ForceReadOnlyRootfs(config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig) error {
+	if hostConfig == nil {
+		return errors.New("missing host config")
+	}
+	hostConfig.ReadonlyRootfs = true
+	return nil
+}
+
+// AllowImages returns a PolicyFunc only allowing containers from the given images.
+func AllowImages(images ...string) PolicyFunc {
+	return func(config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig) error {
+		for _, image := range images {
+			if config.Image == image {
+				return nil
+			}
+		}
+		return fmt.Errorf("image %s is not allowed", config.Image)
+	}
+}
+
+// checkPolicy runs c.Policy, if set.
+func (c *Cmd) checkPolicy() error {
+	if c.Policy == nil {
+		return nil
+	}
+	if err := c.Policy(c.Config, c.HostConfig, c.Networkingconfig); err != nil {
+		return &PolicyError{Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package dockerexec_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestPolicyReject(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "true")
+	cmd.HostConfig.Privileged = true
+	cmd.Policy = dockerexec.Policies(dockerexec.AllowImages(testImage), dockerexec.DenyPrivileged)
+
+	err := cmd.Run()
+	assert.IsType(t, &dockerexec.PolicyError{}, err)
+	assert.EqualError(t, err, "dockerexec: rejected by policy: privileged containers are not allowed")
+	assert.Empty(t, cmd.ContainerID)
+}
+
+func TestPolicyMutate(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "touch", "/file")
+	cmd.Policy = dockerexec.Policies(dockerexec.AllowImages(testImage), dockerexec.ForceReadOnlyRootfs)
+
+	err := cmd.Run()
+	require.Error(t, err)
+	assert.IsType(t, &dockerexec.ExitError{}, err)
+	assert.True(t, cmd.HostConfig.ReadonlyRootfs)
+}
+
+func TestAllowImages(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "true")
+	cmd.Policy = dockerexec.AllowImages("alpine:latest")
+
+	err := cmd.Run()
+	assert.EqualError(t, err, "dockerexec: rejected by policy: image "+testImage+" is not allowed")
+}
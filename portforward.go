@@ -0,0 +1,214 @@
+package dockerexec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// forwardDialTimeout bounds dialing the container directly over the Docker network.
+const forwardDialTimeout = time.Second
+
+// forwardScript relays standard I/O to the port given as $0 on the loopback interface of the
+// container, using whichever tool the image has.
+const forwardScript = `if command -v socat >/dev/null 2>&1; then exec socat - "TCP:127.0.0.1:$0";
+elif command -v nc >/dev/null 2>&1; then exec nc 127.0.0.1 "$0";
+else exec bash -c 'exec 3<>"/dev/tcp/127.0.0.1/$0"; cat <&3 & cat >&3' "$0"; fi`
+
+// socketForwardScript relays standard I/O to the Unix socket given as $0 in the container.
+const socketForwardScript = `if command -v socat >/dev/null 2>&1; then exec socat - "UNIX-CONNECT:$0";
+else exec nc -U "$0"; fi`
+
+// PortForward proxies connections accepted on a host listener to a port, or a Unix socket, of a
+// container, see Cmd.Forward and Cmd.ForwardSocket.
+type PortForward struct {
+	cmd      *Cmd
+	port     int // zero when forwarding to a Unix socket
+	relay    []string
+	listener net.Listener
+
+	// noDirect is set once dialing the container directly timed out, meaning the Docker network
+	// isn't reachable from the host, e.g. with Docker Desktop.
+	noDirect atomic.Bool
+
+	mu     sync.Mutex
+	conns  map[net.Conn]struct{}
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// Forward listens on the host at localAddr, such as "127.0.0.1:0", and proxies each accepted
+// connection to containerPort of the container, which doesn't need to be published. This allows
+// tests to reach services in containers without clashing host ports.
+//
+// Connections are made directly over the Docker network when it's reachable from the host, which
+// is usually the case for a local daemon on Linux, and otherwise through an exec of a relay in the
+// container, which requires socat, nc or bash in the image.
+//
+// The container must have been started by Start. Close the returned PortForward when done.
+func (c *Cmd) Forward(localAddr string, containerPort int) (*PortForward, error) {
+	if len(c.ContainerID) == 0 {
+		return nil, errors.New("dockerexec: not started")
+	}
+
+	l, err := net.Listen("tcp", localAddr)
+	if err != nil {
+		return nil, fmt.Errorf("dockerexec: forward: %w", err)
+	}
+
+	return c.startForward(l, containerPort, []string{"sh", "-c", forwardScript, strconv.Itoa(containerPort)}), nil
+}
+
+// startForward starts a PortForward accepting connections on l, see PortForward.
+func (c *Cmd) startForward(l net.Listener, port int, relay []string) *PortForward {
+	f := &PortForward{
+		cmd:      c,
+		port:     port,
+		relay:    relay,
+		listener: l,
+		conns:    make(map[net.Conn]struct{}),
+	}
+	f.wg.Add(1)
+	go f.accept()
+	return f
+}
+
+// Addr returns the address the PortForward listens on.
+func (f *PortForward) Addr() net.Addr {
+	return f.listener.Addr()
+}
+
+// Close stops listening and closes all forwarded connections.
+func (f *PortForward) Close() error {
+	f.mu.Lock()
+	f.closed = true
+	for conn := range f.conns {
+		conn.Close()
+	}
+	f.mu.Unlock()
+
+	err := f.listener.Close()
+	f.wg.Wait()
+	return err
+}
+
+// track records conn as active, returning false if the PortForward is already closed.
+func (f *PortForward) track(conn net.Conn) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return false
+	}
+	f.conns[conn] = struct{}{}
+	return true
+}
+
+func (f *PortForward) untrack(conn net.Conn) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.conns, conn)
+}
+
+func (f *PortForward) accept() {
+	defer f.wg.Done()
+
+	for {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+		if !f.track(conn) {
+			conn.Close()
+			return
+		}
+
+		f.wg.Add(1)
+		go func() {
+			defer f.wg.Done()
+			defer f.untrack(conn)
+			defer conn.Close()
+			f.forward(conn)
+		}()
+	}
+}
+
+// forward proxies conn to the container, directly if possible, and otherwise through an exec.
+func (f *PortForward) forward(conn net.Conn) {
+	if f.port != 0 && !f.noDirect.Load() {
+		remote, err := f.dialDirect()
+		if err == nil {
+			defer remote.Close()
+			// Tracked too, so that Close unblocks reading from it
+			if !f.track(remote) {
+				return
+			}
+			defer f.untrack(remote)
+			proxy(conn, remote)
+			return
+		}
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			f.noDirect.Store(true)
+		}
+	}
+
+	exec := f.cmd.ExecCommand(f.relay[0], f.relay[1:]...)
+	exec.Stdin = conn
+	exec.Stdout = conn
+	_ = exec.Run()
+}
+
+// dialDirect dials the port of the container over the Docker network.
+func (f *PortForward) dialDirect() (net.Conn, error) {
+	inspect, err := f.cmd.cli.ContainerInspect(context.Background(), f.cmd.ContainerID)
+	if err != nil {
+		return nil, wrapError("inspect container", err)
+	}
+
+	err = errors.New("dockerexec: container has no IP address")
+	if inspect.NetworkSettings != nil {
+		for _, network := range inspect.NetworkSettings.Networks {
+			if network == nil || network.IPAddress == "" {
+				continue
+			}
+			var conn net.Conn
+			conn, err = net.DialTimeout("tcp", net.JoinHostPort(network.IPAddress, strconv.Itoa(f.port)), forwardDialTimeout)
+			if err == nil {
+				return conn, nil
+			}
+		}
+	}
+	return nil, err
+}
+
+// proxy copies data between a and b in both directions until both directions are done.
+func proxy(a, b net.Conn) {
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		_, _ = io.Copy(a, b)
+		closeWrite(a)
+	}()
+	go func() {
+		defer wg.Done()
+		_, _ = io.Copy(b, a)
+		closeWrite(b)
+	}()
+	wg.Wait()
+}
+
+// closeWrite half-closes conn if supported, and fully closes it otherwise.
+func closeWrite(conn net.Conn) {
+	if cw, ok := conn.(interface{ CloseWrite() error }); ok {
+		_ = cw.CloseWrite()
+	} else {
+		conn.Close()
+	}
+}
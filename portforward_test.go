@@ -0,0 +1,43 @@
+package dockerexec_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForward(t *testing.T) {
+	cmd := startHTTPServer(t, false)
+
+	f, err := cmd.Forward("127.0.0.1:0", 8080)
+	require.NoError(t, err)
+	defer f.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var body []byte
+	require.Eventually(t, func() bool {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+f.Addr().String()+"/", nil)
+		if err != nil {
+			return false
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return false
+		}
+		defer resp.Body.Close()
+		body, err = io.ReadAll(resp.Body)
+		return err == nil && resp.StatusCode == http.StatusOK
+	}, 30*time.Second, 100*time.Millisecond)
+	assert.Equal(t, "hello\n", string(body))
+
+	require.NoError(t, f.Close())
+	_, err = http.Get("http://" + f.Addr().String() + "/")
+	assert.Error(t, err)
+}
@@ -0,0 +1,108 @@
+package dockerexec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/docker/docker/client"
+	"github.com/docker/go-connections/nat"
+)
+
+// HostPort returns the address on the host, reachable from this process, that the container port
+// containerPort is published at, such as "localhost:32768". containerPort is a port number with an
+// optional protocol, defaulting to TCP, e.g. "8080" or "53/udp".
+//
+// The port must be published, e.g. by adding it to Config.ExposedPorts and HostConfig.PortBindings,
+// or by setting HostConfig.PublishAllPorts. The container must have been started by Start.
+func (c *Cmd) HostPort(ctx context.Context, containerPort string) (string, error) {
+	if len(c.ContainerID) == 0 {
+		return "", errors.New("dockerexec: not started")
+	}
+
+	proto, port := nat.SplitProtoPort(containerPort)
+	natPort, err := nat.NewPort(proto, port)
+	if err != nil {
+		return "", fmt.Errorf("dockerexec: invalid container port %q: %w", containerPort, err)
+	}
+
+	inspect, err := c.cli.ContainerInspect(ctx, c.ContainerID)
+	if err != nil {
+		return "", wrapError("inspect container", err)
+	}
+
+	if inspect.NetworkSettings != nil {
+		for _, binding := range inspect.NetworkSettings.Ports[natPort] {
+			if binding.HostPort == "" {
+				continue
+			}
+			host := binding.HostIP
+			if ip := net.ParseIP(host); ip == nil || ip.IsUnspecified() {
+				host = daemonHostname(c.cli.DaemonHost())
+			}
+			return net.JoinHostPort(host, binding.HostPort), nil
+		}
+	}
+	return "", fmt.Errorf("dockerexec: container port %s is not published", natPort)
+}
+
+// WaitForHTTP polls path, such as "/healthz", on the container port containerPort, published on
+// the host (See HostPort), until it responds with expectStatus, or any 2xx status if it's zero,
+// backing off between attempts. This is the most common readiness check for services used by
+// tests.
+//
+// It returns ErrContainerNotRunning if the container stops running, and the error of ctx, along
+// with the error of the last attempt, if ctx is done first.
+func (c *Cmd) WaitForHTTP(ctx context.Context, containerPort, path string, expectStatus int) error {
+	if len(c.ContainerID) == 0 {
+		return errors.New("dockerexec: not started")
+	}
+
+	httpClient := &http.Client{Timeout: 5 * time.Second}
+	backoff := 50 * time.Millisecond
+
+	for {
+		err := c.pollHTTP(ctx, httpClient, containerPort, path, expectStatus)
+		if err == nil {
+			return nil
+		}
+
+		inspect, inspectErr := c.cli.ContainerInspect(ctx, c.ContainerID)
+		if client.IsErrNotFound(inspectErr) || (inspectErr == nil && inspect.State != nil && !inspect.State.Running) {
+			return ErrContainerNotRunning
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("dockerexec: wait for HTTP: %w: last attempt: %v", ctx.Err(), err)
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, time.Second)
+	}
+}
+
+// pollHTTP makes a single attempt for WaitForHTTP.
+func (c *Cmd) pollHTTP(ctx context.Context, httpClient *http.Client, containerPort, path string, expectStatus int) error {
+	addr, err := c.HostPort(ctx, containerPort)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+addr+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if expectStatus == 0 && resp.StatusCode >= 200 && resp.StatusCode < 300 || resp.StatusCode == expectStatus {
+		return nil
+	}
+	return fmt.Errorf("unexpected status %s", resp.Status)
+}
@@ -0,0 +1,68 @@
+package dockerexec_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+// startHTTPServer starts a container serving "hello\n" over HTTP on port 8080 after a delay.
+func startHTTPServer(t *testing.T, publish bool) *dockerexec.Cmd {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd := dockerexec.CommandContext(ctx, dockerClient, pullTestImage, "sh", "-c",
+		"mkdir /www && echo hello > /www/index.html && sleep 1 && exec httpd -f -p 8080 -h /www")
+	cmd.Pull = &dockerexec.PullConfig{}
+	cmd.Config.ExposedPorts = nat.PortSet{"8080/tcp": struct{}{}}
+	cmd.HostConfig.PublishAllPorts = publish
+	require.NoError(t, cmd.Start())
+	t.Cleanup(func() {
+		cancel()
+		_ = cmd.Wait()
+	})
+	return cmd
+}
+
+func TestWaitForHTTP(t *testing.T) {
+	cmd := startHTTPServer(t, true)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	err := cmd.WaitForHTTP(ctx, "8080", "/", http.StatusOK)
+	require.NoError(t, err)
+
+	addr, err := cmd.HostPort(ctx, "8080/tcp")
+	require.NoError(t, err)
+	resp, err := http.Get("http://" + addr + "/")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "hello\n", string(body))
+}
+
+func TestWaitForHTTPTimeout(t *testing.T) {
+	cmd := startHTTPServer(t, true)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	err := cmd.WaitForHTTP(ctx, "8080", "/missing", http.StatusOK)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorContains(t, err, "404")
+}
+
+func TestHostPortNotPublished(t *testing.T) {
+	cmd := startHTTPServer(t, false)
+
+	_, err := cmd.HostPort(context.Background(), "8080")
+	assert.EqualError(t, err, "dockerexec: container port 8080/tcp is not published")
+}
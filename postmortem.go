@@ -0,0 +1,186 @@
+package dockerexec
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// CollectPostMortem gathers information on the container of c, for attaching to bug reports and
+// CI artifacts after a failed run, into path. If path ends with ".tar.gz" or ".tgz", a gzipped tar
+// archive is written, otherwise path is created as a directory. The bundle contains:
+//
+//   - command.txt: a description of the command and its container.
+//   - inspect.json: the inspect data of the container.
+//   - stdout.log and stderr.log: the logs of the container (Only stdout.log when using
+//     Config.Tty).
+//   - diff.txt: the changes to the filesystem of the container, like "docker diff".
+//   - stats.json: a snapshot of the stats of the container, if it's still running.
+//
+// The container must still exist, so HostConfig.AutoRemove should be unset, or DebugOnFailure
+// set. Collection is best effort: whatever can be collected is written, and the returned error
+// joins the errors of what couldn't.
+func (c *Cmd) CollectPostMortem(path string) error {
+	if len(c.ContainerID) == 0 {
+		return errors.New("dockerexec: not started")
+	}
+
+	ctx := context.Background()
+	var files []containerFile
+	var errs []error
+	add := func(name string, data []byte) {
+		files = append(files, containerFile{header: tar.Header{Name: name, Mode: 0o644}, data: data})
+	}
+
+	inspect, err := c.cli.ContainerInspect(ctx, c.ContainerID)
+	if err != nil {
+		return wrapError("inspect container", err)
+	}
+
+	var command strings.Builder
+	fmt.Fprintf(&command, "Command: %s\n", c.String())
+	fmt.Fprintf(&command, "Image: %s\n", inspect.Config.Image)
+	fmt.Fprintf(&command, "Container: %s\n", inspect.ID)
+	if inspect.Name != "" {
+		fmt.Fprintf(&command, "Name: %s\n", strings.TrimPrefix(inspect.Name, "/"))
+	}
+	if inspect.State != nil {
+		fmt.Fprintf(&command, "Status: %s\n", inspect.State.Status)
+		fmt.Fprintf(&command, "Exit code: %d\n", inspect.State.ExitCode)
+	}
+	fmt.Fprintf(&command, "Collected: %s\n", time.Now().UTC().Format(time.RFC3339))
+	add("command.txt", []byte(command.String()))
+
+	inspectJSON, err := json.MarshalIndent(inspect, "", "  ")
+	if err != nil {
+		errs = append(errs, err)
+	} else {
+		add("inspect.json", inspectJSON)
+	}
+
+	if stdout, stderr, err := c.readLogs(ctx, inspect.HostConfig, inspect.Config.Tty); err != nil {
+		errs = append(errs, err)
+	} else {
+		add("stdout.log", stdout)
+		if !inspect.Config.Tty {
+			add("stderr.log", stderr)
+		}
+	}
+
+	if changes, err := containerDiff(ctx, c.cli, c.ContainerID); err != nil {
+		errs = append(errs, wrapError("diff container", err))
+	} else {
+		var diff bytes.Buffer
+		for _, change := range changes {
+			fmt.Fprintf(&diff, "%s %s\n", change.Kind, change.Path)
+		}
+		add("diff.txt", diff.Bytes())
+	}
+
+	if inspect.State != nil && inspect.State.Running {
+		if stats, err := c.statsSnapshot(ctx); err != nil {
+			errs = append(errs, err)
+		} else {
+			add("stats.json", stats)
+		}
+	}
+
+	if strings.HasSuffix(path, ".tar.gz") || strings.HasSuffix(path, ".tgz") {
+		err = writeTarGz(path, files)
+	} else {
+		err = writeDir(path, files)
+	}
+	if err != nil {
+		errs = append(errs, fmt.Errorf("dockerexec: write post-mortem: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// readLogs returns the logs of the container.
+func (c *Cmd) readLogs(ctx context.Context, hostConfig *container.HostConfig, tty bool) (stdout, stderr []byte, err error) {
+	if hostConfig != nil {
+		if err := checkLogsReadable(hostConfig.LogConfig); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	logs, err := c.cli.ContainerLogs(ctx, c.ContainerID, container.LogsOptions{ShowStdout: true, ShowStderr: true})
+	if err != nil {
+		return nil, nil, wrapError("container logs", err)
+	}
+	defer logs.Close()
+
+	var outBuf, errBuf bytes.Buffer
+	if err := copyOutput(logs, tty, &outBuf, &errBuf); err != nil {
+		return nil, nil, err
+	}
+	return outBuf.Bytes(), errBuf.Bytes(), nil
+}
+
+// statsSnapshot returns a snapshot of the normalized stats of the container as JSON.
+func (c *Cmd) statsSnapshot(ctx context.Context) ([]byte, error) {
+	resp, err := containerStatsOneShot(ctx, c.cli, c.ContainerID)
+	if err != nil {
+		return nil, wrapError("container stats", err)
+	}
+	defer resp.Body.Close()
+
+	var raw container.StatsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return nil, wrapError("container stats", err)
+	}
+	return json.MarshalIndent(NormalizeStats(resp.OSType, &raw), "", "  ")
+}
+
+func writeDir(dir string, files []containerFile) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := os.WriteFile(filepath.Join(dir, file.header.Name), file.data, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeTarGz(path string, files []containerFile) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	now := time.Now()
+	for _, file := range files {
+		header := file.header
+		header.Typeflag = tar.TypeReg
+		header.Size = int64(len(file.data))
+		header.ModTime = now
+		if err := tw.WriteHeader(&header); err != nil {
+			return err
+		}
+		if _, err := tw.Write(file.data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gw.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
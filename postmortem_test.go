@@ -0,0 +1,65 @@
+package dockerexec_test
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestCollectPostMortem(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "sh", "-c", "echo out; echo err >&2; touch /created; exit 1")
+	cmd.HostConfig.AutoRemove = false
+	require.Error(t, cmd.Run())
+	defer func() {
+		_ = dockerClient.ContainerRemove(context.Background(), cmd.ContainerID, container.RemoveOptions{Force: true})
+	}()
+
+	dir := filepath.Join(t.TempDir(), "postmortem")
+	require.NoError(t, cmd.CollectPostMortem(dir))
+
+	command, err := os.ReadFile(filepath.Join(dir, "command.txt"))
+	require.NoError(t, err)
+	assert.Contains(t, string(command), "Container: "+cmd.ContainerID)
+	assert.Contains(t, string(command), "Exit code: 1")
+
+	stdout, err := os.ReadFile(filepath.Join(dir, "stdout.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "out\n", string(stdout))
+	stderr, err := os.ReadFile(filepath.Join(dir, "stderr.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "err\n", string(stderr))
+
+	diff, err := os.ReadFile(filepath.Join(dir, "diff.txt"))
+	require.NoError(t, err)
+	assert.Contains(t, string(diff), "A /created\n")
+	assert.FileExists(t, filepath.Join(dir, "inspect.json"))
+	assert.NoFileExists(t, filepath.Join(dir, "stats.json"))
+
+	archive := filepath.Join(t.TempDir(), "postmortem.tar.gz")
+	require.NoError(t, cmd.CollectPostMortem(archive))
+
+	f, err := os.Open(archive)
+	require.NoError(t, err)
+	defer f.Close()
+	gr, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	tr := tar.NewReader(gr)
+	var names []string
+	for {
+		header, err := tr.Next()
+		if err != nil {
+			break
+		}
+		names = append(names, header.Name)
+	}
+	assert.ElementsMatch(t, []string{"command.txt", "inspect.json", "stdout.log", "stderr.log", "diff.txt"}, names)
+}
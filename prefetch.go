@@ -0,0 +1,169 @@
+package dockerexec
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/jsonmessage"
+)
+
+// PrefetchProgress reports the progress of Prefetch, aggregated over all images.
+type PrefetchProgress struct {
+	// Image is the image whose progress triggered this update.
+	Image string
+
+	// Done is set once Image finished pulling, in which case Err is set if it failed.
+	Done bool
+	Err  error
+
+	// Current and Total are the number of bytes downloaded and to download over all images, as
+	// known so far. Total grows as the layers of each image become known.
+	Current int64
+	Total   int64
+
+	// Completed is the number of images done, out of Images.
+	Completed int
+	Images    int
+}
+
+// Prefetch pulls the images missing on the daemon, up to parallel at a time, so that services can
+// warm their image cache at boot, before the first Cmd runs. A parallel of zero or less means
+// all at once.
+//
+// The returned error joins the errors of all images that failed to pull.
+func Prefetch(ctx context.Context, cli Client, images []string, parallel int) error {
+	return PrefetchWithProgress(ctx, cli, images, parallel, nil)
+}
+
+// PrefetchWithProgress is like Prefetch, but also reports the aggregated progress to progress,
+// if not nil. Calls to progress are serialized.
+func PrefetchWithProgress(ctx context.Context, cli Client, images []string, parallel int, progress func(PrefetchProgress)) error {
+	if parallel <= 0 {
+		parallel = len(images)
+	}
+
+	p := &prefetcher{
+		cli:      cli,
+		progress: progress,
+		layers:   make(map[string]*jsonmessage.JSONProgress),
+		state:    PrefetchProgress{Images: len(images)},
+	}
+
+	sem := make(chan struct{}, parallel)
+	errs := make([]error, len(images))
+	var wg sync.WaitGroup
+	for i, ref := range images {
+		wg.Add(1)
+		go func(i int, ref string) {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				errs[i] = ctx.Err()
+				p.done(ref, errs[i])
+				return
+			}
+
+			errs[i] = p.prefetch(ctx, ref)
+			p.done(ref, errs[i])
+		}(i, ref)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+type prefetcher struct {
+	cli      Client
+	progress func(PrefetchProgress)
+
+	mu     sync.Mutex
+	layers map[string]*jsonmessage.JSONProgress // by image and layer ID
+	state  PrefetchProgress
+}
+
+func (p *prefetcher) prefetch(ctx context.Context, ref string) error {
+	_, _, err := p.cli.ImageInspectWithRaw(ctx, ref)
+	if err == nil {
+		return nil
+	} else if !client.IsErrNotFound(err) {
+		return wrapError("inspect image", err)
+	}
+
+	resp, err := p.cli.ImagePull(ctx, ref, image.PullOptions{})
+	if err != nil {
+		return wrapError("pull image", err)
+	}
+	defer resp.Close()
+
+	dec := json.NewDecoder(resp)
+	for {
+		var msg jsonmessage.JSONMessage
+		if err := dec.Decode(&msg); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return wrapError("pull image", err)
+		}
+		if msg.Error != nil {
+			return fmt.Errorf("dockerexec: pull image %s: %w", ref, msg.Error)
+		}
+		p.update(ref, &msg)
+	}
+}
+
+// update records the progress of a layer of ref.
+func (p *prefetcher) update(ref string, msg *jsonmessage.JSONMessage) {
+	if msg.ID == "" {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := ref + "\x00" + msg.ID
+	layer := p.layers[key]
+	if layer == nil {
+		layer = &jsonmessage.JSONProgress{}
+		p.layers[key] = layer
+	}
+
+	p.state.Current -= layer.Current
+	p.state.Total -= layer.Total
+	switch {
+	case msg.Status == "Downloading" && msg.Progress != nil:
+		layer.Current, layer.Total = msg.Progress.Current, msg.Progress.Total
+	case msg.Status == "Download complete" || msg.Status == "Pull complete":
+		layer.Current = layer.Total
+	}
+	p.state.Current += layer.Current
+	p.state.Total += layer.Total
+
+	p.report(ref, false, nil)
+}
+
+// done records that ref is done pulling.
+func (p *prefetcher) done(ref string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.state.Completed++
+	p.report(ref, true, err)
+}
+
+// report reports the current progress, p.mu must be held.
+func (p *prefetcher) report(ref string, done bool, err error) {
+	if p.progress == nil {
+		return
+	}
+	state := p.state
+	state.Image, state.Done, state.Err = ref, done, err
+	p.progress(state)
+}
@@ -0,0 +1,32 @@
+package dockerexec_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/docker/docker/api/types/image"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestPrefetch(t *testing.T) {
+	_, _ = dockerClient.ImageRemove(context.Background(), pullTestImage, image.RemoveOptions{})
+
+	var last dockerexec.PrefetchProgress
+	err := dockerexec.PrefetchWithProgress(context.Background(), dockerClient, []string{testImage, pullTestImage}, 2,
+		func(progress dockerexec.PrefetchProgress) {
+			last = progress
+		})
+	require.NoError(t, err)
+	assert.Equal(t, 2, last.Completed)
+	assert.Equal(t, 2, last.Images)
+	assert.Equal(t, last.Total, last.Current)
+
+	_, _, err = dockerClient.ImageInspectWithRaw(context.Background(), pullTestImage)
+	assert.NoError(t, err)
+
+	err = dockerexec.Prefetch(context.Background(), dockerClient, []string{"dockerexec-does-not-exist:latest"}, 0)
+	assert.ErrorContains(t, err, "dockerexec: pull image")
+}
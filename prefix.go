@@ -0,0 +1,149 @@
+package dockerexec
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+)
+
+// PrefixWriter is an io.WriteCloser that prefixes each line written to it with a label before
+// writing it to an underlying writer, so that the output of several containers can share a single
+// terminal.
+//
+// Partial lines are buffered until they are completed, or until Close, so each line is written to
+// the underlying writer whole, in a single Write call, and lines of different PrefixWriters
+// sharing it aren't mixed. Hence, output that doesn't end with a newline, such as a prompt, only
+// shows up once completed.
+//
+// A PrefixWriter is safe for concurrent use.
+type PrefixWriter struct {
+	w io.Writer
+
+	mu     sync.Mutex
+	prefix []byte
+	buf    []byte
+}
+
+// NewPrefixWriter returns a PrefixWriter writing to w with the given prefix.
+func NewPrefixWriter(w io.Writer, prefix string) *PrefixWriter {
+	return &PrefixWriter{w: w, prefix: []byte(prefix)}
+}
+
+func (w *PrefixWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		if err := w.writeLine(w.buf[:i+1]); err != nil {
+			return len(p), err
+		}
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Close writes the buffered partial line, if any, terminating it with a newline.
+func (w *PrefixWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.buf) == 0 {
+		return nil
+	}
+	line := append(w.buf, '\n')
+	w.buf = nil
+	return w.writeLine(line)
+}
+
+func (w *PrefixWriter) writeLine(line []byte) error {
+	b := make([]byte, 0, len(w.prefix)+len(line))
+	b = append(b, w.prefix...)
+	b = append(b, line...)
+	_, err := w.w.Write(b)
+	return err
+}
+
+// PrefixOutput sets Stdout and Stderr (unless Config.Tty is set) to write the output of the
+// container to stdout and stderr, respectively, with each line prefixed by the given prefix
+// using a PrefixWriter. stdout and stderr may be the same writer.
+//
+// If prefix is empty, the name of the container is used, or its short ID if it has no name, e.g.
+// "a1b2c3d4e5f6 | ".
+func (c *Cmd) PrefixOutput(stdout, stderr io.Writer, prefix string) error {
+	return c.prefixOutput(stdout, stderr, prefix, "PrefixOutput", func(label string) string {
+		return label + " | "
+	})
+}
+
+// prefixOutput implements PrefixOutput, with format making the prefix from the label of the
+// container when prefix is empty, and method being the name of the calling method for errors.
+func (c *Cmd) prefixOutput(stdout, stderr io.Writer, prefix, method string, format func(label string) string) error {
+	if c.Stdout != nil {
+		return errors.New("dockerexec: Stdout already set")
+	}
+	if c.Stderr != nil {
+		return errors.New("dockerexec: Stderr already set")
+	}
+	if len(c.ContainerID) != 0 {
+		return errors.New("dockerexec: " + method + " after container started")
+	}
+
+	if stdout == stderr {
+		// Lines of both streams are written concurrently.
+		w := &lockedWriter{w: stdout}
+		stdout, stderr = w, w
+	}
+
+	outw := &containerPrefixWriter{PrefixWriter: NewPrefixWriter(stdout, prefix), format: format}
+	c.Stdout = outw
+	c.closeAfterOutput = append(c.closeAfterOutput, outw)
+	if !c.Config.Tty {
+		errw := &containerPrefixWriter{PrefixWriter: NewPrefixWriter(stderr, prefix), format: format}
+		c.Stderr = errw
+		c.closeAfterOutput = append(c.closeAfterOutput, errw)
+	}
+	return nil
+}
+
+// lockedWriter serializes writes to w.
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
+
+// containerPrefixWriter is a PrefixWriter defaulting its prefix to one formatted from the name or
+// short ID of the container.
+type containerPrefixWriter struct {
+	*PrefixWriter
+	format func(label string) string
+}
+
+func (w *containerPrefixWriter) setContainer(c *Cmd, id string) {
+	if len(w.prefix) == 0 {
+		w.prefix = []byte(w.format(containerLabel(c.ContainerName, id)))
+	}
+}
+
+// containerLabel returns the name of a container, or its short ID if it has no name.
+func containerLabel(name, id string) string {
+	if name != "" {
+		return strings.TrimPrefix(name, "/")
+	}
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
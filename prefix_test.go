@@ -0,0 +1,41 @@
+package dockerexec_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestPrefixWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := dockerexec.NewPrefixWriter(&buf, "[a] ")
+
+	_, err := w.Write([]byte("hel"))
+	require.NoError(t, err)
+	assert.Empty(t, buf.String())
+
+	_, err = w.Write([]byte("lo\nwor"))
+	require.NoError(t, err)
+	_, err = w.Write([]byte("ld\nbye"))
+	require.NoError(t, err)
+	assert.Equal(t, "[a] hello\n[a] world\n", buf.String())
+
+	require.NoError(t, w.Close())
+	assert.Equal(t, "[a] hello\n[a] world\n[a] bye\n", buf.String())
+}
+
+func TestPrefixOutput(t *testing.T) {
+	var buf bytes.Buffer
+	cmd := dockerexec.Command(dockerClient, testImage, "sh", "-c", "echo out; echo err >&2")
+	require.NoError(t, cmd.PrefixOutput(&buf, &buf, ""))
+	require.NoError(t, cmd.Run())
+
+	prefix := cmd.ContainerID[:12] + " | "
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	assert.ElementsMatch(t, []string{prefix + "out", prefix + "err"}, lines)
+}
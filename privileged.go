@@ -0,0 +1,17 @@
+package dockerexec
+
+// Privileged runs the container privileged, by setting HostConfig.Privileged, giving it all
+// capabilities and access to all host devices, e.g. for running Docker in Docker.
+//
+// Privileged containers are effectively root on the host, so running one is traceable: it adds a
+// Warning with the WarningPrivileged code to Warnings, and is flagged in AuditEvent.Privileged, as
+// is any container with HostConfig.Privileged set. It returns c to allow chaining.
+func (c *Cmd) Privileged() *Cmd {
+	c.hostConfig().Privileged = true
+	return c
+}
+
+// privileged reports whether the container runs privileged.
+func (c *Cmd) privileged() bool {
+	return c.HostConfig != nil && c.HostConfig.Privileged
+}
@@ -0,0 +1,44 @@
+package dockerexec_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestPrivileged(t *testing.T) {
+	var events []*dockerexec.AuditEvent
+
+	// Only privileged containers may remount /sys read-write.
+	cmd := dockerexec.Command(dockerClient, testImage, "mount", "-o", "remount,rw", "/sys")
+	cmd.Privileged()
+	cmd.Audit = dockerexec.AuditFunc(func(event *dockerexec.AuditEvent) {
+		events = append(events, event)
+	})
+
+	err := cmd.Run()
+	require.NoError(t, err)
+	assert.Contains(t, warningCodes(cmd.Warnings), dockerexec.WarningPrivileged)
+
+	require.Len(t, events, 1)
+	assert.True(t, events[0].Privileged)
+}
+
+func TestUnprivileged(t *testing.T) {
+	var events []*dockerexec.AuditEvent
+
+	cmd := dockerexec.Command(dockerClient, testImage, "true")
+	cmd.Audit = dockerexec.AuditFunc(func(event *dockerexec.AuditEvent) {
+		events = append(events, event)
+	})
+
+	err := cmd.Run()
+	require.NoError(t, err)
+	assert.NotContains(t, warningCodes(cmd.Warnings), dockerexec.WarningPrivileged)
+
+	require.Len(t, events, 1)
+	assert.False(t, events[0].Privileged)
+}
@@ -0,0 +1,82 @@
+package dockerexec
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/docker/docker/client"
+)
+
+// ErrContainerNotRunning is matched, using errors.Is, by a *ProbeError when the container stopped
+// running before the probe succeeded.
+var ErrContainerNotRunning = errors.New("dockerexec: container is not running")
+
+// ProbeError is returned by Probe when the probe didn't succeed.
+type ProbeError struct {
+	// Err is why probing stopped, either the error of the context or ErrContainerNotRunning.
+	Err error
+
+	// LastErr is the error of the last attempt of the probe command, such as an *ExitError.
+	LastErr error
+}
+
+func (e *ProbeError) Error() string {
+	s := "dockerexec: probe failed: " + e.Err.Error()
+	if e.LastErr != nil {
+		s += ": last attempt: " + e.LastErr.Error()
+	}
+	return s
+}
+
+func (e *ProbeError) Unwrap() error {
+	return e.Err
+}
+
+// Probe repeatedly execs probeCmd in the container of c, every interval, until it exits with a
+// zero status, e.g. to wait for a server to become ready when the image defines no HEALTHCHECK.
+// Each attempt is killed if it doesn't complete within timeout, unless it's zero.
+//
+// It returns a *ProbeError if ctx is done, or the container stops running, first. The container
+// must have been started by Start, and interval must be positive.
+func (c *Cmd) Probe(ctx context.Context, probeCmd []string, interval, timeout time.Duration) error {
+	if len(c.ContainerID) == 0 {
+		return errors.New("dockerexec: not started")
+	}
+	if len(probeCmd) == 0 {
+		return errors.New("dockerexec: no probe command specified")
+	}
+	if interval <= 0 {
+		return errors.New("dockerexec: non-positive probe interval")
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if timeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, timeout)
+		}
+		err := c.ExecCommandContext(attemptCtx, probeCmd[0], probeCmd[1:]...).Run()
+		cancel()
+		if err == nil {
+			return nil
+		}
+
+		if ctx.Err() != nil {
+			return &ProbeError{Err: ctx.Err(), LastErr: err}
+		}
+
+		inspect, inspectErr := c.cli.ContainerInspect(ctx, c.ContainerID)
+		if client.IsErrNotFound(inspectErr) || (inspectErr == nil && inspect.State != nil && !inspect.State.Running) {
+			return &ProbeError{Err: ErrContainerNotRunning, LastErr: err}
+		}
+
+		select {
+		case <-ctx.Done():
+			return &ProbeError{Err: ctx.Err(), LastErr: err}
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package dockerexec_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestProbe(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cmd := dockerexec.CommandContext(ctx, dockerClient, testImage, "sh", "-c", "sleep 1; touch /tmp/ready; sleep 120")
+	require.NoError(t, cmd.Start())
+	defer func() {
+		cancel()
+		_ = cmd.Wait()
+	}()
+
+	probeCtx, probeCancel := context.WithTimeout(ctx, 30*time.Second)
+	defer probeCancel()
+	err := cmd.Probe(probeCtx, []string{"test", "-e", "/tmp/ready"}, 100*time.Millisecond, 5*time.Second)
+	require.NoError(t, err)
+}
+
+func TestProbeTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cmd := dockerexec.CommandContext(ctx, dockerClient, testImage, "sleep", "120")
+	require.NoError(t, cmd.Start())
+	defer func() {
+		cancel()
+		_ = cmd.Wait()
+	}()
+
+	probeCtx, probeCancel := context.WithTimeout(ctx, time.Second)
+	defer probeCancel()
+	err := cmd.Probe(probeCtx, []string{"false"}, 100*time.Millisecond, 0)
+
+	var probeErr *dockerexec.ProbeError
+	require.ErrorAs(t, err, &probeErr)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestProbeContainerExited(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "sleep", "1")
+	require.NoError(t, cmd.Start())
+	defer func() {
+		_ = cmd.Wait()
+	}()
+
+	probeCtx, probeCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer probeCancel()
+	err := cmd.Probe(probeCtx, []string{"false"}, 100*time.Millisecond, 0)
+	assert.ErrorIs(t, err, dockerexec.ErrContainerNotRunning)
+}
+
+func TestProbeInvalidInterval(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cmd := dockerexec.CommandContext(ctx, dockerClient, testImage, "sleep", "120")
+	require.NoError(t, cmd.Start())
+	defer func() {
+		cancel()
+		_ = cmd.Wait()
+	}()
+
+	assert.NotPanics(t, func() {
+		err := cmd.Probe(ctx, []string{"true"}, 0, 0)
+		assert.Error(t, err)
+	})
+}
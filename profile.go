@@ -0,0 +1,108 @@
+package dockerexec
+
+import (
+	"sync"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// Profile is a named, curated set of Config and HostConfig settings, which teams can standardize
+// on. Apply it to a Cmd using ApplyProfile, after which any of its settings may be overridden.
+type Profile struct {
+	Name  string
+	Apply func(c *Cmd)
+}
+
+// SecurePidsLimit is the limit on the number of processes applied by ProfileSecure, see
+// Cmd.PidsLimit. It's enough for most programs, while stopping fork bombs.
+const SecurePidsLimit = 256
+
+// ProfileSecure hardens the container: it drops all capabilities, disallows gaining new
+// privileges, makes the root filesystem read-only with a writable tmpfs at /tmp, disables
+// networking and limits the number of processes.
+var ProfileSecure = Profile{
+	Name: "secure",
+	Apply: func(c *Cmd) {
+		hc := c.hostConfig()
+		hc.Privileged = false
+		hc.CapAdd = nil
+		hc.CapDrop = []string{"ALL"}
+		hc.SecurityOpt = append(hc.SecurityOpt, "no-new-privileges")
+		hc.ReadonlyRootfs = true
+		if hc.Tmpfs == nil {
+			hc.Tmpfs = make(map[string]string)
+		}
+		hc.Tmpfs["/tmp"] = "rw,noexec,nosuid,size=64m"
+		hc.NetworkMode = "none"
+		c.PidsLimit = SecurePidsLimit
+	},
+}
+
+// ProfileDebug eases debugging the container: it allows tracing processes (e.g. with strace or
+// gdb), runs an init process to reap zombies and forward signals, and keeps the container after
+// it exits for inspection, disabling HostConfig.AutoRemove.
+var ProfileDebug = Profile{
+	Name: "debug",
+	Apply: func(c *Cmd) {
+		hc := c.hostConfig()
+		hc.CapAdd = append(hc.CapAdd, "SYS_PTRACE")
+		hc.SecurityOpt = append(hc.SecurityOpt, "seccomp=unconfined")
+		init := true
+		hc.Init = &init
+		hc.AutoRemove = false
+	},
+}
+
+// ProfileMinimal minimizes the overhead of the container: it disables networking and the
+// retention of its logs by the daemon, which doesn't affect capturing its output.
+var ProfileMinimal = Profile{
+	Name: "minimal",
+	Apply: func(c *Cmd) {
+		hc := c.hostConfig()
+		hc.NetworkMode = "none"
+		hc.LogConfig = container.LogConfig{Type: "none"}
+	},
+}
+
+var (
+	profilesMu sync.RWMutex
+	profiles   = map[string]Profile{
+		ProfileSecure.Name:  ProfileSecure,
+		ProfileDebug.Name:   ProfileDebug,
+		ProfileMinimal.Name: ProfileMinimal,
+	}
+)
+
+// RegisterProfile registers a profile under its name, replacing any profile of the same name, so
+// that it can be looked up using LookupProfile, such as from configuration files.
+func RegisterProfile(p Profile) {
+	profilesMu.Lock()
+	defer profilesMu.Unlock()
+	profiles[p.Name] = p
+}
+
+// LookupProfile returns the profile registered under name. The built-in profiles are registered
+// as "secure", "debug" and "minimal".
+func LookupProfile(name string) (Profile, bool) {
+	profilesMu.RLock()
+	defer profilesMu.RUnlock()
+	p, ok := profiles[name]
+	return p, ok
+}
+
+// ApplyProfile applies the given profiles to c, in order, so later profiles override earlier
+// ones. It returns c to allow chaining.
+func (c *Cmd) ApplyProfile(profiles ...Profile) *Cmd {
+	for _, p := range profiles {
+		p.Apply(c)
+	}
+	return c
+}
+
+// hostConfig returns HostConfig, allocating it if needed.
+func (c *Cmd) hostConfig() *container.HostConfig {
+	if c.HostConfig == nil {
+		c.HostConfig = &container.HostConfig{}
+	}
+	return c.HostConfig
+}
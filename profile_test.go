@@ -0,0 +1,46 @@
+package dockerexec_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestProfileSecure(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "sh", "-c", "touch /tmp/ok && touch /nope")
+	cmd.ApplyProfile(dockerexec.ProfileSecure)
+	assert.Equal(t, []string{"ALL"}, cmd.HostConfig.CapDrop)
+
+	output, err := cmd.CombinedOutput()
+	var exitErr *dockerexec.ExitError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Contains(t, string(output), "Read-only file system")
+}
+
+func TestLookupProfile(t *testing.T) {
+	p, ok := dockerexec.LookupProfile("debug")
+	require.True(t, ok)
+	assert.Equal(t, "debug", p.Name)
+
+	_, ok = dockerexec.LookupProfile("custom")
+	assert.False(t, ok)
+
+	dockerexec.RegisterProfile(dockerexec.Profile{
+		Name: "custom",
+		Apply: func(c *dockerexec.Cmd) {
+			c.Config.Env = append(c.Config.Env, "CUSTOM=1")
+		},
+	})
+	p, ok = dockerexec.LookupProfile("custom")
+	require.True(t, ok)
+
+	// Later profiles and explicit settings override earlier ones.
+	cmd := dockerexec.Command(dockerClient, testImage, "true").ApplyProfile(dockerexec.ProfileSecure, p, dockerexec.ProfileMinimal)
+	assert.Contains(t, cmd.Config.Env, "CUSTOM=1")
+	assert.Equal(t, "none", cmd.HostConfig.LogConfig.Type)
+	cmd.HostConfig.NetworkMode = "bridge"
+	require.NoError(t, cmd.Run())
+}
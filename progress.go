@@ -0,0 +1,42 @@
+package dockerexec
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/moby/term"
+)
+
+// DisplayProgress renders a stream of JSON progress messages, such as returned by ImagePull, to
+// out like the docker CLI does, and returns the error reported in the stream, if any. When out is
+// a terminal, progress bars are updated in place, otherwise each update is written as a line.
+//
+// If quiet is set, only the overall status messages are written, such as "Status: Downloaded
+// newer image for busybox:latest", omitting the progress of each layer.
+func DisplayProgress(in io.Reader, out io.Writer, quiet bool) error {
+	if !quiet {
+		fd, isTerminal := term.GetFdInfo(out)
+		return jsonmessage.DisplayJSONMessagesStream(in, out, fd, isTerminal, nil)
+	}
+
+	dec := json.NewDecoder(in)
+	for {
+		var msg jsonmessage.JSONMessage
+		if err := dec.Decode(&msg); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if msg.Error != nil {
+			return msg.Error
+		}
+		if msg.ID == "" && msg.Status != "" {
+			if _, err := fmt.Fprintln(out, msg.Status); err != nil {
+				return err
+			}
+		}
+	}
+}
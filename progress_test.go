@@ -0,0 +1,32 @@
+package dockerexec_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+const progressStream = `{"status":"Pulling from library/busybox","id":"1.36"}
+{"status":"Downloading","progressDetail":{"current":1,"total":2},"id":"abc"}
+{"status":"Pull complete","id":"abc"}
+{"status":"Status: Downloaded newer image for busybox:1.36"}
+`
+
+func TestDisplayProgress(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, dockerexec.DisplayProgress(strings.NewReader(progressStream), &out, false))
+	assert.Contains(t, out.String(), "abc: Pull complete")
+	assert.Contains(t, out.String(), "Status: Downloaded newer image for busybox:1.36")
+
+	out.Reset()
+	require.NoError(t, dockerexec.DisplayProgress(strings.NewReader(progressStream), &out, true))
+	assert.Equal(t, "Status: Downloaded newer image for busybox:1.36\n", out.String())
+
+	err := dockerexec.DisplayProgress(strings.NewReader(`{"errorDetail":{"message":"boom"},"error":"boom"}`), &out, true)
+	assert.EqualError(t, err, "boom")
+}
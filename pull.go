@@ -0,0 +1,162 @@
+package dockerexec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/registry"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/jsonmessage"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// Puller pulls the image of a Cmd when it's missing on the daemon, see Cmd.Pull. Implement it to
+// substitute custom pull logic, such as lazy pulling or going through an artifact proxy.
+//
+// Pull must not return before the image is available on the daemon, or the pull fails.
+type Puller interface {
+	Pull(ctx context.Context, cli Client, ref string, platform *ocispec.Platform) error
+}
+
+// PullerFunc is an adapter to allow the use of ordinary functions as a Puller.
+type PullerFunc func(ctx context.Context, cli Client, ref string, platform *ocispec.Platform) error
+
+// Pull calls f(ctx, cli, ref, platform).
+func (f PullerFunc) Pull(ctx context.Context, cli Client, ref string, platform *ocispec.Platform) error {
+	return f(ctx, cli, ref, platform)
+}
+
+// PullConfig is the default Puller, which pulls images using ImagePull, optionally trying
+// registry mirrors first. The zero value pulls from the image's own registry.
+type PullConfig struct {
+	// Mirrors are registries tried in order before the image's own registry, e.g. for air-gapped
+	// or rate-limited environments. An image pulled from a mirror is tagged with its original
+	// name, unless it's referenced only by digest, as images can't be tagged with a digest.
+	Mirrors []Registry
+
+	// Auth is the credentials for the image's own registry, if any.
+	Auth *registry.AuthConfig
+
+	// NoUpstream disables falling back to the image's own registry when no mirror has the image.
+	NoUpstream bool
+
+	// Progress, if set, receives the progress of pulls, rendered like the docker CLI does using
+	// DisplayProgress, e.g. os.Stderr.
+	Progress io.Writer
+
+	// QuietProgress renders only the overall status messages to Progress, see DisplayProgress.
+	QuietProgress bool
+}
+
+// Registry is a registry to pull images from.
+type Registry struct {
+	// Host is the host, and optionally port, of the registry, e.g. "mirror.example.com:5000".
+	Host string
+
+	// Auth is the credentials for the registry, if any.
+	Auth *registry.AuthConfig
+}
+
+// ensureImage pulls the image of c if Pull is set and it's missing on the daemon.
+func (c *Cmd) ensureImage(ctx context.Context) error {
+	if c.Pull == nil {
+		return nil
+	}
+
+	_, _, err := c.cli.ImageInspectWithRaw(ctx, c.Config.Image)
+	if err == nil {
+		return nil
+	} else if !client.IsErrNotFound(err) {
+		return wrapError("inspect image", err)
+	}
+
+	return c.Pull.Pull(ctx, c.cli, c.Config.Image, c.Platform)
+}
+
+// Pull pulls ref, trying the mirrors of config first.
+func (config *PullConfig) Pull(ctx context.Context, cli Client, ref string, p *ocispec.Platform) error {
+	platform := ""
+	if p != nil {
+		platform = formatPlatform(p.OS, p.Architecture, p.Variant)
+	}
+
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return fmt.Errorf("dockerexec: invalid image reference %q: %w", ref, err)
+	}
+	named = reference.TagNameOnly(named)
+
+	var errs []error
+	for _, mirror := range config.Mirrors {
+		mirrorRef := mirror.Host + "/" + reference.Path(named) + referenceSuffix(named)
+		err := config.pullFrom(ctx, cli, mirrorRef, platform, mirror.Auth)
+		if err == nil {
+			// The daemon can't tag an image by digest, so only the tag of a reference with both is
+			// used, and a reference with only a digest isn't tagged.
+			if tagged, ok := named.(reference.NamedTagged); ok {
+				tag := reference.FamiliarName(tagged) + ":" + tagged.Tag()
+				if err := cli.ImageTag(ctx, mirrorRef, tag); err != nil {
+					return wrapError("tag image", err)
+				}
+			}
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		errs = append(errs, fmt.Errorf("dockerexec: pull from mirror %s: %w", mirror.Host, err))
+	}
+
+	if !config.NoUpstream {
+		err := config.pullFrom(ctx, cli, named.String(), platform, config.Auth)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, fmt.Errorf("dockerexec: pull %s: %w", named, err))
+	}
+
+	if len(errs) == 0 {
+		return fmt.Errorf("dockerexec: image %s is missing and there is no registry to pull it from", ref)
+	}
+	return errors.Join(errs...)
+}
+
+// referenceSuffix returns the tag and digest parts of named, e.g. ":latest" or "@sha256:...".
+func referenceSuffix(named reference.Named) string {
+	var s string
+	if tagged, ok := named.(reference.Tagged); ok {
+		s += ":" + tagged.Tag()
+	}
+	if digested, ok := named.(reference.Digested); ok {
+		s += "@" + digested.Digest().String()
+	}
+	return s
+}
+
+// pullFrom pulls ref, waiting for the pull to complete.
+func (config *PullConfig) pullFrom(ctx context.Context, cli Client, ref string, platform string, auth *registry.AuthConfig) error {
+	options := image.PullOptions{Platform: platform}
+	if auth != nil {
+		encoded, err := registry.EncodeAuthConfig(*auth)
+		if err != nil {
+			return err
+		}
+		options.RegistryAuth = encoded
+	}
+
+	progress, err := cli.ImagePull(ctx, ref, options)
+	if err != nil {
+		return err
+	}
+	defer progress.Close()
+
+	// Errors during the pull are only reported in the progress stream.
+	if config.Progress != nil {
+		return DisplayProgress(progress, config.Progress, config.QuietProgress)
+	}
+	return jsonmessage.DisplayJSONMessagesStream(progress, io.Discard, 0, false, nil)
+}
@@ -0,0 +1,92 @@
+package dockerexec_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types/image"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+const pullTestImage = "busybox:1.36"
+
+func TestPull(t *testing.T) {
+	_, _ = dockerClient.ImageRemove(context.Background(), pullTestImage, image.RemoveOptions{})
+
+	cmd := dockerexec.Command(dockerClient, pullTestImage, "echo", "Hello, World!")
+	cmd.Pull = &dockerexec.PullConfig{
+		Mirrors: []dockerexec.Registry{{Host: "127.0.0.1:1"}},
+	}
+	output, err := cmd.Output()
+	require.NoError(t, err)
+	assert.Equal(t, "Hello, World!\n", string(output))
+}
+
+func TestPullNoUpstream(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, "dockerexec-does-not-exist:latest", "true")
+	cmd.Pull = &dockerexec.PullConfig{
+		Mirrors:    []dockerexec.Registry{{Host: "127.0.0.1:1"}},
+		NoUpstream: true,
+	}
+	err := cmd.Run()
+	assert.ErrorContains(t, err, "dockerexec: pull from mirror 127.0.0.1:1: ")
+}
+
+func TestPuller(t *testing.T) {
+	var pulled string
+	cmd := dockerexec.Command(dockerClient, "dockerexec-does-not-exist:latest", "true")
+	cmd.Pull = dockerexec.PullerFunc(func(ctx context.Context, cli dockerexec.Client, ref string, platform *ocispec.Platform) error {
+		pulled = ref
+		return errors.New("no such image in proxy")
+	})
+	err := cmd.Run()
+	assert.EqualError(t, err, "no such image in proxy")
+	assert.Equal(t, "dockerexec-does-not-exist:latest", pulled)
+}
+
+// mirrorClient pretends to pull any image successfully, recording the pulls and tags.
+type mirrorClient struct {
+	dockerexec.Client
+	pulled []string
+	tagged []string
+}
+
+func (c *mirrorClient) ImagePull(ctx context.Context, ref string, options image.PullOptions) (io.ReadCloser, error) {
+	c.pulled = append(c.pulled, ref)
+	return io.NopCloser(strings.NewReader("")), nil
+}
+
+func (c *mirrorClient) ImageTag(ctx context.Context, source, target string) error {
+	c.tagged = append(c.tagged, source+" "+target)
+	return nil
+}
+
+func TestPullMirrorTag(t *testing.T) {
+	const digest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	config := &dockerexec.PullConfig{
+		Mirrors: []dockerexec.Registry{{Host: "mirror.example.com"}},
+	}
+
+	cli := &mirrorClient{Client: dockerClient}
+	require.NoError(t, config.Pull(context.Background(), cli, "busybox:1.36", nil))
+	assert.Equal(t, []string{"mirror.example.com/library/busybox:1.36"}, cli.pulled)
+	assert.Equal(t, []string{"mirror.example.com/library/busybox:1.36 busybox:1.36"}, cli.tagged)
+
+	// Images can't be tagged by digest.
+	cli = &mirrorClient{Client: dockerClient}
+	require.NoError(t, config.Pull(context.Background(), cli, "busybox@"+digest, nil))
+	assert.Equal(t, []string{"mirror.example.com/library/busybox@" + digest}, cli.pulled)
+	assert.Empty(t, cli.tagged)
+
+	cli = &mirrorClient{Client: dockerClient}
+	require.NoError(t, config.Pull(context.Background(), cli, "busybox:1.36@"+digest, nil))
+	assert.Equal(t, []string{"mirror.example.com/library/busybox:1.36@" + digest}, cli.pulled)
+	assert.Equal(t, []string{"mirror.example.com/library/busybox:1.36@" + digest + " busybox:1.36"}, cli.tagged)
+}
@@ -0,0 +1,191 @@
+package dockerexec
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Quota limits the resources reserved by the containers running under a single key of a
+// QuotaManager. Zero fields mean no limit.
+type Quota struct {
+	// MaxRuns is the maximum number of containers running concurrently.
+	MaxRuns int
+
+	// MaxNanoCPUs is the maximum total CPU limit of the running containers, in units of 1e-9
+	// CPUs.
+	MaxNanoCPUs int64
+
+	// MaxMemory is the maximum total memory limit of the running containers, in bytes.
+	MaxMemory int64
+}
+
+// QuotaUsage is the resources reserved by the containers currently running under a key.
+type QuotaUsage struct {
+	Runs     int
+	NanoCPUs int64
+	Memory   int64
+}
+
+// A QuotaExceededError is returned by Start when running the container would exceed the quota of
+// one of its keys.
+type QuotaExceededError struct {
+	Key      string
+	Resource string // "runs", "cpus" or "memory"
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("dockerexec: quota exceeded for %s: %s", e.Key, e.Resource)
+}
+
+// A QuotaManager enforces quotas on the resources reserved by running containers, keyed by
+// arbitrary strings such as a user, tenant or image name. It's meant for multi-tenant services
+// running user-supplied commands.
+//
+// A Cmd reserves the CPU and memory limits set in its HostConfig.Resources under each of its
+// QuotaKeys from Start until Wait sees the container exit. If a key's quota limits CPUs or memory,
+// containers without the corresponding limit are rejected, as their usage is unbounded.
+//
+// A QuotaManager is safe for concurrent use.
+type QuotaManager struct {
+	mu           sync.Mutex
+	defaultQuota Quota
+	quotas       map[string]Quota
+	usage        map[string]QuotaUsage
+}
+
+// NewQuotaManager returns a QuotaManager applying defaultQuota to keys without a quota set using
+// SetQuota.
+func NewQuotaManager(defaultQuota Quota) *QuotaManager {
+	return &QuotaManager{
+		defaultQuota: defaultQuota,
+		quotas:       make(map[string]Quota),
+		usage:        make(map[string]QuotaUsage),
+	}
+}
+
+// SetQuota sets the quota of key. It doesn't affect containers that are already running.
+func (m *QuotaManager) SetQuota(key string, quota Quota) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.quotas[key] = quota
+}
+
+// Usage returns the resources currently reserved under key.
+func (m *QuotaManager) Usage(key string) QuotaUsage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.usage[key]
+}
+
+func (m *QuotaManager) quota(key string) Quota {
+	if quota, ok := m.quotas[key]; ok {
+		return quota
+	}
+	return m.defaultQuota
+}
+
+// reserve reserves the given resources under all keys, or none of them if any quota would be
+// exceeded.
+func (m *QuotaManager) reserve(keys []string, nanoCPUs, memory int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, key := range keys {
+		quota, usage := m.quota(key), m.usage[key]
+
+		if quota.MaxRuns > 0 && usage.Runs+1 > quota.MaxRuns {
+			return &QuotaExceededError{Key: key, Resource: "runs"}
+		}
+		if quota.MaxNanoCPUs > 0 && (nanoCPUs == 0 || usage.NanoCPUs+nanoCPUs > quota.MaxNanoCPUs) {
+			return &QuotaExceededError{Key: key, Resource: "cpus"}
+		}
+		if quota.MaxMemory > 0 && (memory == 0 || usage.Memory+memory > quota.MaxMemory) {
+			return &QuotaExceededError{Key: key, Resource: "memory"}
+		}
+	}
+
+	for _, key := range keys {
+		usage := m.usage[key]
+		usage.Runs++
+		usage.NanoCPUs += nanoCPUs
+		usage.Memory += memory
+		m.usage[key] = usage
+	}
+	return nil
+}
+
+func (m *QuotaManager) release(keys []string, nanoCPUs, memory int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, key := range keys {
+		usage := m.usage[key]
+		usage.Runs--
+		usage.NanoCPUs -= nanoCPUs
+		usage.Memory -= memory
+		if usage == (QuotaUsage{}) {
+			delete(m.usage, key)
+		} else {
+			m.usage[key] = usage
+		}
+	}
+}
+
+// reservation returns the CPU and memory limits of c to reserve.
+func (c *Cmd) reservation() (nanoCPUs, memory int64) {
+	if c.HostConfig == nil {
+		return 0, 0
+	}
+
+	resources := c.HostConfig.Resources
+	nanoCPUs = resources.NanoCPUs
+	if nanoCPUs == 0 && resources.CPUQuota > 0 {
+		period := resources.CPUPeriod
+		if period == 0 {
+			period = 100000 // The default CFS period
+		}
+		nanoCPUs = resources.CPUQuota * 1e9 / period
+	}
+	return nanoCPUs, resources.Memory
+}
+
+// reserveQuota reserves the resources of c in its QuotaManager, if any.
+func (c *Cmd) reserveQuota() error {
+	if c.Quota == nil || len(c.QuotaKeys) == 0 {
+		return nil
+	}
+
+	// A key listed twice is still only reserved under once.
+	keys := uniqueKeys(c.QuotaKeys)
+	nanoCPUs, memory := c.reservation()
+	if err := c.Quota.reserve(keys, nanoCPUs, memory); err != nil {
+		return err
+	}
+	c.quotaKeys = keys
+	c.quotaReserved = &QuotaUsage{Runs: 1, NanoCPUs: nanoCPUs, Memory: memory}
+	return nil
+}
+
+// releaseQuota releases the resources reserved by reserveQuota.
+func (c *Cmd) releaseQuota() {
+	if c.quotaReserved != nil {
+		c.Quota.release(c.quotaKeys, c.quotaReserved.NanoCPUs, c.quotaReserved.Memory)
+		c.quotaKeys = nil
+		c.quotaReserved = nil
+	}
+}
+
+// uniqueKeys returns keys without duplicates, in their original order.
+func uniqueKeys(keys []string) []string {
+	seen := make(map[string]bool, len(keys))
+	var unique []string
+	for _, key := range keys {
+		if !seen[key] {
+			seen[key] = true
+			unique = append(unique, key)
+		}
+	}
+	return unique
+}
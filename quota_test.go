@@ -0,0 +1,74 @@
+package dockerexec_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestQuota(t *testing.T) {
+	quota := dockerexec.NewQuotaManager(dockerexec.Quota{MaxRuns: 2})
+	quota.SetQuota("tenant-a", dockerexec.Quota{MaxNanoCPUs: 1e9, MaxMemory: 256 << 20})
+
+	cmd1 := dockerexec.Command(dockerClient, testImage, "sleep", "120")
+	cmd1.Quota = quota
+	cmd1.QuotaKeys = []string{"tenant-a", "user-1"}
+	cmd1.HostConfig.NanoCPUs = 5e8
+	cmd1.HostConfig.Memory = 128 << 20
+	err := cmd1.Start()
+	require.NoError(t, err)
+	defer func() {
+		_ = cmd1.Kill("SIGKILL")
+		_ = cmd1.Wait()
+		assert.Equal(t, dockerexec.QuotaUsage{}, quota.Usage("tenant-a"))
+		assert.Equal(t, dockerexec.QuotaUsage{}, quota.Usage("user-1"))
+	}()
+
+	assert.Equal(t, dockerexec.QuotaUsage{Runs: 1, NanoCPUs: 5e8, Memory: 128 << 20}, quota.Usage("tenant-a"))
+	assert.Equal(t, dockerexec.QuotaUsage{Runs: 1, NanoCPUs: 5e8, Memory: 128 << 20}, quota.Usage("user-1"))
+
+	// Exceeds the CPU quota of tenant-a.
+	cmd2 := dockerexec.Command(dockerClient, testImage, "true")
+	cmd2.Quota = quota
+	cmd2.QuotaKeys = []string{"tenant-a", "user-1"}
+	cmd2.HostConfig.NanoCPUs = 1e9
+	cmd2.HostConfig.Memory = 64 << 20
+	err = cmd2.Run()
+	assert.EqualError(t, err, "dockerexec: quota exceeded for tenant-a: cpus")
+
+	// Unlimited memory can't be reserved under tenant-a.
+	cmd3 := dockerexec.Command(dockerClient, testImage, "true")
+	cmd3.Quota = quota
+	cmd3.QuotaKeys = []string{"tenant-a"}
+	cmd3.HostConfig.NanoCPUs = 1e8
+	err = cmd3.Run()
+	assert.EqualError(t, err, "dockerexec: quota exceeded for tenant-a: memory")
+
+	// Nothing was reserved by the failed runs.
+	assert.Equal(t, dockerexec.QuotaUsage{Runs: 1, NanoCPUs: 5e8, Memory: 128 << 20}, quota.Usage("tenant-a"))
+
+	cmd4 := dockerexec.Command(dockerClient, testImage, "true")
+	cmd4.Quota = quota
+	cmd4.QuotaKeys = []string{"user-1"}
+	err = cmd4.Run()
+	require.NoError(t, err)
+}
+
+func TestQuotaDuplicateKeys(t *testing.T) {
+	quota := dockerexec.NewQuotaManager(dockerexec.Quota{MaxRuns: 1})
+
+	cmd := dockerexec.Command(dockerClient, testImage, "sleep", "120")
+	cmd.Quota = quota
+	cmd.QuotaKeys = []string{"tenant-a", "tenant-a"}
+	err := cmd.Start()
+	require.NoError(t, err)
+
+	assert.Equal(t, dockerexec.QuotaUsage{Runs: 1}, quota.Usage("tenant-a"))
+
+	_ = cmd.Kill("SIGKILL")
+	_ = cmd.Wait()
+	assert.Equal(t, dockerexec.QuotaUsage{}, quota.Usage("tenant-a"))
+}
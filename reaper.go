@@ -0,0 +1,228 @@
+package dockerexec
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/docker/go-connections/nat"
+)
+
+// DefaultReaperImage is the image used by StartReaper when ReaperOptions.Image is empty.
+const DefaultReaperImage = "testcontainers/ryuk:0.11.0"
+
+// reaperPort is the port the reaper listens on inside its container.
+const reaperPort = nat.Port("8080/tcp")
+
+// sessionID identifies the current process, see SessionID.
+var sessionID = newSessionID()
+
+func newSessionID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(fmt.Sprintf("dockerexec: generate session ID: %v", err))
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// SessionID returns a random ID generated once per process. Every container created by this
+// package is labeled with it using LabelSession, allowing a reaper to clean up after the process.
+func SessionID() string {
+	return sessionID
+}
+
+// ReaperOptions configures StartReaper.
+type ReaperOptions struct {
+	// Image is the reaper image, it must speak the Ryuk protocol. Defaults to DefaultReaperImage.
+	Image string
+
+	// DockerSocket is the path of the Docker socket on the daemon host, which is mounted into the
+	// reaper. Defaults to "/var/run/docker.sock".
+	DockerSocket string
+
+	// Privileged runs the reaper privileged, which is required to access the Docker socket on
+	// some hosts, e.g. with SELinux enabled.
+	Privileged bool
+
+	// Pull is used to pull the image when it's missing on the daemon. Defaults to a zero
+	// PullConfig.
+	Pull Puller
+
+	// ConnectTimeout is how long to keep trying to connect to the reaper after starting it.
+	// Defaults to 10 seconds.
+	ConnectTimeout time.Duration
+}
+
+// Reaper is a helper container that removes all containers, volumes and networks labeled with the
+// SessionID of the current process once the connection to it drops, i.e. when the process exits
+// for any reason, including SIGKILL.
+//
+// The reaper waits briefly for a reconnection before reaping, and then exits. Resources that are
+// not created by Cmd can be reaped too by labeling them with LabelSession and SessionID.
+type Reaper struct {
+	// ContainerID is the ID of the reaper container.
+	ContainerID string
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// StartReaper starts a reaper for the current session and connects to it. The connection is kept
+// open until Close is called or the process exits.
+//
+// The daemon must be able to publish a port that is reachable from this process, which is usually
+// not the case for a remote daemon behind SSH.
+func StartReaper(ctx context.Context, cli Client, opts ReaperOptions) (*Reaper, error) {
+	if opts.Image == "" {
+		opts.Image = DefaultReaperImage
+	}
+	if opts.DockerSocket == "" {
+		opts.DockerSocket = "/var/run/docker.sock"
+	}
+	if opts.Pull == nil {
+		opts.Pull = &PullConfig{}
+	}
+	if opts.ConnectTimeout == 0 {
+		opts.ConnectTimeout = 10 * time.Second
+	}
+
+	_, _, err := cli.ImageInspectWithRaw(ctx, opts.Image)
+	if client.IsErrNotFound(err) {
+		err = opts.Pull.Pull(ctx, cli, opts.Image, nil)
+		if err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, wrapError("inspect image", err)
+	}
+
+	cont, err := cli.ContainerCreate(ctx,
+		&container.Config{
+			Image:        opts.Image,
+			ExposedPorts: nat.PortSet{reaperPort: struct{}{}},
+			Labels: map[string]string{
+				LabelManaged: "true",
+				LabelOwner:   "reaper",
+			},
+		},
+		&container.HostConfig{
+			AutoRemove: true,
+			Privileged: opts.Privileged,
+			Binds:      []string{opts.DockerSocket + ":/var/run/docker.sock"},
+			PortBindings: nat.PortMap{
+				reaperPort: []nat.PortBinding{{}},
+			},
+		},
+		nil, nil, "")
+	if err != nil {
+		return nil, wrapError("create reaper", err)
+	}
+
+	r := &Reaper{ContainerID: cont.ID}
+
+	err = r.connect(ctx, cli, opts.ConnectTimeout)
+	if err != nil {
+		// Best effort, AutoRemove takes care of the rest
+		_ = cli.ContainerRemove(context.Background(), cont.ID, container.RemoveOptions{Force: true})
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// connect starts the reaper container and registers the session filter with it.
+func (r *Reaper) connect(ctx context.Context, cli Client, timeout time.Duration) error {
+	err := cli.ContainerStart(ctx, r.ContainerID, container.StartOptions{})
+	if err != nil {
+		return wrapError("start reaper", err)
+	}
+
+	inspect, err := cli.ContainerInspect(ctx, r.ContainerID)
+	if err != nil {
+		return wrapError("inspect reaper", err)
+	}
+
+	var hostPort string
+	if inspect.NetworkSettings != nil {
+		for _, binding := range inspect.NetworkSettings.Ports[reaperPort] {
+			hostPort = binding.HostPort
+			break
+		}
+	}
+	if hostPort == "" {
+		return fmt.Errorf("dockerexec: reaper port %s is not published", reaperPort)
+	}
+
+	addr := net.JoinHostPort(daemonHostname(cli.DaemonHost()), hostPort)
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	for {
+		conn, err := dialer.DialContext(ctx, "tcp", addr)
+		if err == nil {
+			err = register(conn)
+			if err == nil {
+				r.conn = conn
+				return nil
+			}
+			conn.Close()
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("dockerexec: connect to reaper at %s: %w", addr, err)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// register sends the session filter over conn and waits for the reaper to acknowledge it.
+func register(conn net.Conn) error {
+	_, err := fmt.Fprintf(conn, "label=%s=%s\n", LabelSession, sessionID)
+	if err != nil {
+		return err
+	}
+
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(line) != "ACK" {
+		return fmt.Errorf("unexpected reply %q", line)
+	}
+	return nil
+}
+
+// daemonHostname returns the hostname that ports published by the daemon at host are reachable
+// at.
+func daemonHostname(host string) string {
+	u, err := url.Parse(host)
+	if err != nil || u.Scheme == "unix" || u.Scheme == "npipe" || u.Hostname() == "" {
+		return "localhost"
+	}
+	return u.Hostname()
+}
+
+// Close closes the connection to the reaper, which then reaps the session's resources and exits.
+func (r *Reaper) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.conn == nil {
+		return nil
+	}
+	err := r.conn.Close()
+	r.conn = nil
+	return err
+}
@@ -0,0 +1,41 @@
+package dockerexec_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestReaper(t *testing.T) {
+	reaper, err := dockerexec.StartReaper(context.Background(), dockerClient, dockerexec.ReaperOptions{})
+	require.NoError(t, err)
+	defer reaper.Close()
+
+	cmd := dockerexec.Command(dockerClient, testImage, "true")
+	cmd.HostConfig.AutoRemove = false
+	err = cmd.Run()
+	require.NoError(t, err)
+
+	inspect, err := dockerClient.ContainerInspect(context.Background(), cmd.ContainerID)
+	require.NoError(t, err)
+	assert.Equal(t, dockerexec.SessionID(), inspect.Config.Labels[dockerexec.LabelSession])
+
+	err = reaper.Close()
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		_, err := dockerClient.ContainerInspect(context.Background(), cmd.ContainerID)
+		return client.IsErrNotFound(err)
+	}, time.Minute, time.Second)
+
+	if t.Failed() {
+		_ = dockerClient.ContainerRemove(context.Background(), cmd.ContainerID, container.RemoveOptions{Force: true})
+	}
+}
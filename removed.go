@@ -0,0 +1,79 @@
+package dockerexec
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+)
+
+// ErrContainerRemoved matches, using errors.Is, the *ContainerRemovedError returned by Wait when
+// the container was removed by someone else while waiting for it.
+var ErrContainerRemoved = errors.New("dockerexec: container removed")
+
+// ContainerRemovedError is returned by Wait when the container was removed by another actor, such
+// as "docker rm -f", while waiting for it, rather than exiting on its own.
+type ContainerRemovedError struct {
+	ContainerID string
+
+	// State is the last known state of the container. For containers started by Start, only
+	// Status, Running, StartedAt, and once known, ExitCode are filled.
+	State *types.ContainerState
+
+	// Err is the error from waiting for the container, if any.
+	Err error
+}
+
+func (e *ContainerRemovedError) Error() string {
+	return "dockerexec: container " + e.ContainerID + " removed while waiting for it"
+}
+
+func (e *ContainerRemovedError) Is(target error) bool {
+	return target == ErrContainerRemoved
+}
+
+func (e *ContainerRemovedError) Unwrap() error {
+	return e.Err
+}
+
+// setStartedState records the last known state of the container once it has been started.
+func (c *Cmd) setStartedState() {
+	c.lastState = &types.ContainerState{
+		Status:    "running",
+		Running:   true,
+		StartedAt: time.Now().UTC().Format(time.RFC3339Nano),
+	}
+}
+
+// checkRemoved returns a *ContainerRemovedError if the result of waiting for the container, err
+// and c.StatusCode, is due to the container being removed by someone else, and err otherwise.
+func (c *Cmd) checkRemoved(err error) error {
+	var we *WaitError
+	if errors.As(err, &we) && client.IsErrNotFound(we.Err) {
+		return &ContainerRemovedError{ContainerID: c.ContainerID, State: c.lastState, Err: we.Err}
+	}
+
+	// Removing a running container kills it, which looks like a normal exit. Unless the container
+	// is expected to be removed on exit, check whether it still exists. A container killed by us
+	// is expected to exit, so there's no need to check.
+	if err != nil || c.StatusCode == 0 || c.deadlineExceeded.Load() || c.context().Err() != nil ||
+		(c.HostConfig != nil && c.HostConfig.AutoRemove) {
+		return err
+	}
+
+	_, inspectErr := c.cli.ContainerInspect(context.Background(), c.ContainerID)
+	if !client.IsErrNotFound(inspectErr) {
+		return err
+	}
+
+	state := types.ContainerState{Status: "exited"}
+	if c.lastState != nil {
+		state = *c.lastState
+		state.Status = "exited"
+		state.Running = false
+	}
+	state.ExitCode = int(c.StatusCode)
+	return &ContainerRemovedError{ContainerID: c.ContainerID, State: &state}
+}
@@ -0,0 +1,96 @@
+package dockerexec
+
+import (
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/moby/term"
+)
+
+// rendererColors are the ANSI colors assigned to containers by a Renderer, in order, like Docker
+// Compose.
+var rendererColors = []string{"36", "33", "32", "35", "34", "96", "93", "92", "95", "94"}
+
+// Renderer interleaves the output of several containers into a single writer, Docker Compose
+// style, prefixing each line with the label of its container, padded to the longest label seen so
+// far, and colored with a color assigned to each container.
+//
+// A Renderer is safe for concurrent use.
+type Renderer struct {
+	// Color enables coloring the labels. NewRenderer enables it if the writer is a terminal and
+	// the NO_COLOR environment variable isn't set.
+	Color bool
+
+	w io.Writer
+
+	mu    sync.Mutex
+	next  int // index of the next color
+	width int // longest label
+}
+
+// NewRenderer returns a Renderer writing to w.
+func NewRenderer(w io.Writer) *Renderer {
+	_, isTerminal := term.GetFdInfo(w)
+	_, noColor := os.LookupEnv("NO_COLOR")
+	return &Renderer{
+		Color: isTerminal && !noColor,
+		w:     &lockedWriter{w: w},
+	}
+}
+
+// Attach sets Stdout and Stderr of c (unless Config.Tty is set) to render its output. If label is
+// empty, the name of the container is used, or its short ID if it has no name.
+func (r *Renderer) Attach(c *Cmd, label string) error {
+	color := r.nextColor()
+	if label != "" {
+		r.growWidth(label)
+	}
+	return c.prefixOutput(r.w, r.w, "", "Attach", func(containerLabel string) string {
+		if label == "" {
+			label = containerLabel
+			r.growWidth(label)
+		}
+		return r.prefix(label, color)
+	})
+}
+
+// Writer returns a PrefixWriter rendering what's written to it with label, for output that
+// doesn't come from a Cmd. Close it once done to write a trailing partial line.
+func (r *Renderer) Writer(label string) *PrefixWriter {
+	color := r.nextColor()
+	r.growWidth(label)
+	return NewPrefixWriter(r.w, r.prefix(label, color))
+}
+
+func (r *Renderer) nextColor() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	color := rendererColors[r.next%len(rendererColors)]
+	r.next++
+	return color
+}
+
+func (r *Renderer) growWidth(label string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(label) > r.width {
+		r.width = len(label)
+	}
+}
+
+// prefix returns the prefix of lines with the given label and color.
+func (r *Renderer) prefix(label, color string) string {
+	r.mu.Lock()
+	width := r.width
+	r.mu.Unlock()
+
+	prefix := label + strings.Repeat(" ", width-len(label)) + "  | "
+	if r.Color {
+		prefix = "\x1b[" + color + "m" + prefix + "\x1b[0m"
+	}
+	return prefix
+}
@@ -0,0 +1,44 @@
+package dockerexec_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestRenderer(t *testing.T) {
+	var buf bytes.Buffer
+	r := dockerexec.NewRenderer(&buf)
+	assert.False(t, r.Color)
+
+	w := r.Writer("longer")
+	_, err := w.Write([]byte("from writer\n"))
+	require.NoError(t, err)
+
+	cmd := dockerexec.Command(dockerClient, testImage, "echo", "hello")
+	require.NoError(t, r.Attach(cmd, "web"))
+	require.NoError(t, cmd.Run())
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	assert.Equal(t, []string{
+		"longer  | from writer",
+		"web     | hello",
+	}, lines)
+}
+
+func TestRendererColor(t *testing.T) {
+	var buf bytes.Buffer
+	r := dockerexec.NewRenderer(&buf)
+	r.Color = true
+
+	a, b := r.Writer("a"), r.Writer("b")
+	_, _ = a.Write([]byte("1\n"))
+	_, _ = b.Write([]byte("2\n"))
+
+	assert.Equal(t, "\x1b[36ma  | \x1b[0m1\n\x1b[33mb  | \x1b[0m2\n", buf.String())
+}
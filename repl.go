@@ -0,0 +1,139 @@
+package dockerexec
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+var errREPLClosed = errors.New("dockerexec: REPL closed")
+
+// REPL drives an interactive interpreter, such as python or psql, running in a TTY session such as
+// a ShellSession, by sending it input and collecting its output up to the next prompt.
+type REPL struct {
+	rw     io.ReadWriter
+	prompt *regexp.Regexp
+	buf    []byte
+	chunks chan []byte
+	err    error // set before chunks is closed
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewREPL returns a REPL over rw, which detects the interpreter's prompt using the prompt regular
+// expression.
+//
+// The prompt is searched for in all output received since the last prompt, so it should be
+// specific enough not to match regular output, e.g. `(?m)^>>> $` for python rather than `>>> `.
+//
+// The REPL reads from rw in the background until it returns an error, or the REPL is closed, see
+// Close.
+func NewREPL(rw io.ReadWriter, prompt *regexp.Regexp) *REPL {
+	r := &REPL{
+		rw:     rw,
+		prompt: prompt,
+		chunks: make(chan []byte),
+		done:   make(chan struct{}),
+	}
+	go r.read()
+	return r
+}
+
+// Close stops the REPL, after which WaitPrompt and Send fail. It doesn't close rw, but the
+// background read stops as soon as the pending read from rw returns, so close rw too, e.g. by
+// closing the ShellSession, to stop it right away. Close always returns nil.
+func (r *REPL) Close() error {
+	r.closeOnce.Do(func() {
+		close(r.done)
+	})
+	return nil
+}
+
+func (r *REPL) read() {
+	for {
+		p := make([]byte, 4096)
+		n, err := r.rw.Read(p)
+		if n > 0 {
+			select {
+			case r.chunks <- p[:n]:
+			case <-r.done:
+				return
+			}
+		}
+		if err != nil {
+			r.err = err
+			close(r.chunks)
+			return
+		}
+	}
+}
+
+// WaitPrompt waits for the next prompt, returning the output received before it. Use it to skip
+// the banner printed by the interpreter on startup.
+//
+// If the interpreter exits before printing a prompt, the output received so far is returned
+// together with io.ErrUnexpectedEOF.
+func (r *REPL) WaitPrompt(ctx context.Context) (string, error) {
+	for {
+		if loc := r.prompt.FindIndex(r.buf); loc != nil {
+			output := string(r.buf[:loc[0]])
+			r.buf = r.buf[loc[1]:]
+			return output, nil
+		}
+
+		select {
+		case chunk, ok := <-r.chunks:
+			if !ok {
+				output := string(r.buf)
+				r.buf = nil
+				err := r.err
+				if err == io.EOF {
+					err = io.ErrUnexpectedEOF
+				}
+				return output, err
+			}
+			r.buf = append(r.buf, chunk...)
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-r.done:
+			return "", errREPLClosed
+		}
+	}
+}
+
+// Send sends input, followed by a newline, to the interpreter and waits for the next prompt,
+// returning the output in between, with the TTY's echo of input removed and line endings
+// normalized to "\n".
+func (r *REPL) Send(ctx context.Context, input string) (string, error) {
+	select {
+	case <-r.done:
+		return "", errREPLClosed
+	default:
+	}
+	if _, err := io.WriteString(r.rw, input+"\n"); err != nil {
+		return "", err
+	}
+
+	output, err := r.WaitPrompt(ctx)
+	output = strings.ReplaceAll(output, "\r\n", "\n")
+	return stripEcho(output, input), err
+}
+
+// stripEcho removes the echo of input by the TTY from the start of output, which the interpreter
+// may interleave with continuation prompts, hence comparing line by line.
+func stripEcho(output, input string) string {
+	rest := []byte(output)
+	for _, line := range strings.Split(input, "\n") {
+		i := bytes.IndexByte(rest, '\n')
+		if i < 0 || !bytes.HasSuffix(rest[:i], []byte(line)) {
+			return output
+		}
+		rest = rest[i+1:]
+	}
+	return string(rest)
+}
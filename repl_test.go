@@ -0,0 +1,70 @@
+package dockerexec_test
+
+import (
+	"context"
+	"regexp"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestREPL(t *testing.T) {
+	session, err := dockerexec.Shell(dockerClient, testImage, dockerexec.ShellOptions{
+		Env: []string{"PS1=repl> "},
+	})
+	require.NoError(t, err)
+	defer session.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	repl := dockerexec.NewREPL(session, regexp.MustCompile(`(?m)^repl> $`))
+	_, err = repl.WaitPrompt(ctx)
+	require.NoError(t, err)
+
+	output, err := repl.Send(ctx, "echo Hello, World!")
+	require.NoError(t, err)
+	assert.Equal(t, "Hello, World!\n", output)
+
+	output, err = repl.Send(ctx, "expr 1 + 2")
+	require.NoError(t, err)
+	assert.Equal(t, "3\n", output)
+}
+
+// chattyReadWriter produces output on every read, and counts the reads.
+type chattyReadWriter struct {
+	reads atomic.Int32
+}
+
+func (rw *chattyReadWriter) Read(p []byte) (int, error) {
+	rw.reads.Add(1)
+	return copy(p, "output\n"), nil
+}
+
+func (rw *chattyReadWriter) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func TestREPLClose(t *testing.T) {
+	rw := &chattyReadWriter{}
+	repl := dockerexec.NewREPL(rw, regexp.MustCompile(`(?m)^never> $`))
+	require.NoError(t, repl.Close())
+	require.NoError(t, repl.Close())
+
+	_, err := repl.WaitPrompt(context.Background())
+	assert.Error(t, err)
+	_, err = repl.Send(context.Background(), "input")
+	assert.Error(t, err)
+
+	// The background read stops instead of blocking forever on delivering output
+	assert.Eventually(t, func() bool {
+		reads := rw.reads.Load()
+		time.Sleep(50 * time.Millisecond)
+		return rw.reads.Load() == reads
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,85 @@
+package dockerexec
+
+import (
+	"errors"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+)
+
+// daemonPollInterval is the interval in which the daemon is pinged while waiting for it to come
+// back, see Cmd.DaemonRestartTolerance.
+const daemonPollInterval = 500 * time.Millisecond
+
+// waitForDaemon waits for the daemon to respond, up to deadline.
+func (c *Cmd) waitForDaemon(deadline time.Time) error {
+	ctx := c.context()
+
+	for {
+		_, err := c.cli.Ping(ctx)
+		if err == nil {
+			return nil
+		}
+		if !time.Now().Before(deadline) {
+			return wrapError("daemon did not come back", err)
+		}
+
+		select {
+		case <-time.After(daemonPollInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// rewait re-establishes waiting for the container once the daemon comes back, if waiting for it
+// failed with err for a reason other than the container being gone, returning the result of
+// waiting for it again, or the original result if it can't.
+func (c *Cmd) rewait(statusCode int64, err error) (int64, error) {
+	deadline := time.Now().Add(c.DaemonRestartTolerance)
+	for c.DaemonRestartTolerance > 0 && time.Now().Before(deadline) {
+		var we *WaitError
+		if !errors.As(err, &we) || client.IsErrNotFound(we.Err) {
+			break
+		}
+
+		if c.waitForDaemon(deadline) != nil {
+			break
+		}
+
+		waitCh, errCh := c.cli.ContainerWait(c.context(), c.ContainerID, container.WaitConditionNotRunning)
+		statusCode, err = receiveWait(waitCh, errCh)
+	}
+	return statusCode, err
+}
+
+// reattach re-attaches to the output of the container, once the daemon comes back, if the output
+// ended because the connection to the daemon was lost, rather than because the container exited.
+// Output written by the container while detached is lost.
+func (c *Cmd) reattach(stdout, stderr bool) (types.HijackedResponse, bool) {
+	if c.DaemonRestartTolerance <= 0 {
+		return types.HijackedResponse{}, false
+	}
+
+	inspect, err := c.cli.ContainerInspect(c.context(), c.ContainerID)
+	if err == nil && !inspect.State.Running {
+		return types.HijackedResponse{}, false
+	}
+	if err != nil {
+		if client.IsErrNotFound(err) || c.waitForDaemon(time.Now().Add(c.DaemonRestartTolerance)) != nil {
+			return types.HijackedResponse{}, false
+		}
+	}
+
+	attach, err := c.cli.ContainerAttach(c.context(), c.ContainerID, container.AttachOptions{
+		Stream: true,
+		Stdout: stdout,
+		Stderr: stderr,
+	})
+	if err != nil {
+		return types.HijackedResponse{}, false
+	}
+	return attach, true
+}
@@ -0,0 +1,186 @@
+package dockerexec
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// RetryOptions configures RunWithRetry.
+type RetryOptions struct {
+	// MaxAttempts is the maximum number of attempts, including the first. Defaults to 3.
+	MaxAttempts int
+
+	// Backoff is the delay before the first retry, doubled after each retry up to MaxBackoff.
+	// Defaults to one second.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+
+	// Retryable reports whether an attempt that failed with err should be retried. Defaults to
+	// retrying any *ExitError, see RetryOnExitCodes.
+	Retryable func(err error) bool
+
+	// OnAttempt, if set, is called after each attempt completes.
+	OnAttempt func(Attempt)
+}
+
+// RetryOnExitCodes returns a RetryOptions.Retryable retrying when the container exits with any of
+// the given status codes.
+func RetryOnExitCodes(codes ...int64) func(err error) bool {
+	return func(err error) bool {
+		var ee *ExitError
+		return errors.As(err, &ee) && slices.Contains(codes, ee.StatusCode)
+	}
+}
+
+// RunWithRetry runs c, and while it fails with an error that opts.Retryable accepts, such as a
+// flaky test exiting with a specific status, runs it again in a new container, with backoff, up to
+// opts.MaxAttempts times. Unlike the retries of transient daemon errors, this retries the run
+// itself.
+//
+// Each attempt runs a copy of c, so c itself is never started, but is updated with the results
+// of the last attempt (ContainerID, Warnings, StatusCode, GateDecision, Usage, the digests of
+// HashOutput and ArtifactErrors). Stdin is read fully first, so that it can be replayed to each
+// attempt. Stdout and Stderr receive the output of all attempts. Pipes, such as from StdoutPipe,
+// are not supported.
+//
+// Unless c was created by CommandContext, ctx is used like CommandContext does for each attempt.
+// RunWithRetry returns the error of the last attempt.
+func (c *Cmd) RunWithRetry(ctx context.Context, opts RetryOptions) error {
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 3
+	}
+	backoff := opts.Backoff
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+	retryable := opts.Retryable
+	if retryable == nil {
+		retryable = func(err error) bool {
+			var ee *ExitError
+			return errors.As(err, &ee)
+		}
+	}
+
+	if len(c.ContainerID) != 0 {
+		return errors.New("dockerexec: already started")
+	}
+
+	var stdin []byte
+	if c.Stdin != nil {
+		var err error
+		stdin, err = io.ReadAll(c.Stdin)
+		if err != nil {
+			return fmt.Errorf("dockerexec: read stdin: %w", err)
+		}
+	}
+
+	// Start mutates the configuration, so each attempt copies the original one
+	template := c.clone()
+
+	for number := 1; ; number++ {
+		cmd := template.clone()
+		if cmd.ctx == nil {
+			cmd.ctx = ctx
+		}
+		if c.Stdin != nil {
+			cmd.Stdin = bytes.NewReader(stdin)
+		}
+
+		attempt := Attempt{Number: number, Cmd: cmd, Started: time.Now()}
+		attempt.Err = cmd.Run()
+		attempt.Finished = time.Now()
+		if opts.OnAttempt != nil {
+			opts.OnAttempt(attempt)
+		}
+
+		c.ContainerID = cmd.ContainerID
+		c.Warnings = cmd.Warnings
+		c.StatusCode = cmd.StatusCode
+		c.GateDecision = cmd.GateDecision
+		c.Usage = cmd.Usage
+		c.StdoutDigest = cmd.StdoutDigest
+		c.StderrDigest = cmd.StderrDigest
+		c.ArtifactErrors = cmd.ArtifactErrors
+
+		if attempt.Err == nil || number >= opts.MaxAttempts || !retryable(attempt.Err) {
+			return attempt.Err
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		backoff *= 2
+		if opts.MaxBackoff > 0 && backoff > opts.MaxBackoff {
+			backoff = opts.MaxBackoff
+		}
+	}
+}
+
+// clone returns a new Cmd with the configuration of c, which can be started even if c was.
+func (c *Cmd) clone() *Cmd {
+	config := *c.Config
+	config.Labels = maps.Clone(c.Config.Labels)
+
+	var hostConfig *container.HostConfig
+	if c.HostConfig != nil {
+		hc := *c.HostConfig
+		hostConfig = &hc
+	}
+
+	return &Cmd{
+		Config:                 &config,
+		HostConfig:             hostConfig,
+		Networkingconfig:       c.Networkingconfig,
+		Platform:               c.Platform,
+		ContainerName:          c.ContainerName,
+		OnNameConflict:         c.OnNameConflict,
+		Hostname:               c.Hostname,
+		EnsurePasswd:           c.EnsurePasswd,
+		DebugOnFailure:         c.DebugOnFailure,
+		Owner:                  c.Owner,
+		Stdin:                  c.Stdin,
+		KeepStdinOpen:          c.KeepStdinOpen,
+		Stdout:                 c.Stdout,
+		Stderr:                 c.Stderr,
+		OutputFrames:           c.OutputFrames,
+		OutputLog:              c.OutputLog,
+		OrderedOutput:          c.OrderedOutput,
+		HashOutput:             c.HashOutput,
+		Artifacts:              c.Artifacts,
+		Inputs:                 c.Inputs,
+		MaxRuntime:             c.MaxRuntime,
+		PidsLimit:              c.PidsLimit,
+		ShmSize:                c.ShmSize,
+		CollectUsage:           c.CollectUsage,
+		DaemonRestartTolerance: c.DaemonRestartTolerance,
+		Dependencies:           c.Dependencies,
+		Pull:                   c.Pull,
+		ImageGate:              c.ImageGate,
+		Policy:                 c.Policy,
+		Audit:                  c.Audit,
+		Principal:              c.Principal,
+		Cache:                  c.Cache,
+		Limiter:                c.Limiter,
+		Quota:                  c.Quota,
+		QuotaKeys:              c.QuotaKeys,
+		OnWarning:              c.OnWarning,
+		Trace:                  c.Trace,
+		Err:                    c.Err,
+
+		StatusCode: -1,
+
+		ctx: c.ctx,
+		cli: c.cli,
+	}
+}
@@ -0,0 +1,63 @@
+package dockerexec_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestRunWithRetry(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "sh", "-c", "cat; exit 3")
+	cmd.Stdin = strings.NewReader("hello\n")
+	var stdout strings.Builder
+	cmd.Stdout = &stdout
+
+	var attempts []dockerexec.Attempt
+	err := cmd.RunWithRetry(context.Background(), dockerexec.RetryOptions{
+		Backoff:   10 * time.Millisecond,
+		Retryable: dockerexec.RetryOnExitCodes(3),
+		OnAttempt: func(attempt dockerexec.Attempt) {
+			attempts = append(attempts, attempt)
+		},
+	})
+
+	var exitErr *dockerexec.ExitError
+	require.ErrorAs(t, err, &exitErr)
+	assert.EqualValues(t, 3, exitErr.StatusCode)
+	require.Len(t, attempts, 3)
+	assert.NotEqual(t, attempts[0].Cmd.ContainerID, attempts[1].Cmd.ContainerID)
+	assert.Equal(t, attempts[2].Cmd.ContainerID, cmd.ContainerID)
+	assert.EqualValues(t, 3, cmd.StatusCode)
+	assert.Equal(t, "hello\nhello\nhello\n", stdout.String())
+}
+
+func TestRunWithRetryNotRetryable(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "sh", "-c", "exit 4")
+
+	attempts := 0
+	err := cmd.RunWithRetry(context.Background(), dockerexec.RetryOptions{
+		Retryable: dockerexec.RetryOnExitCodes(3),
+		OnAttempt: func(dockerexec.Attempt) {
+			attempts++
+		},
+	})
+
+	var exitErr *dockerexec.ExitError
+	require.ErrorAs(t, err, &exitErr)
+	assert.EqualValues(t, 4, exitErr.StatusCode)
+	assert.Equal(t, 1, attempts)
+}
+
+func TestRunWithRetrySuccess(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "true")
+	err := cmd.RunWithRetry(context.Background(), dockerexec.RetryOptions{})
+	require.NoError(t, err)
+	assert.EqualValues(t, 0, cmd.StatusCode)
+	assert.NotEmpty(t, cmd.ContainerID)
+}
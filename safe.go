@@ -0,0 +1,80 @@
+package dockerexec
+
+import (
+	"context"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// RunSafe starts the specified container, calls fn, and then waits for the container to complete,
+// like calling Start, fn and Wait in sequence.
+//
+// If fn panics, the container is force-removed before the panic is propagated, so that it isn't
+// orphaned, see Guard. If fn returns an error, the container is killed and that error is returned
+// after waiting for it.
+func (c *Cmd) RunSafe(fn func() error) error {
+	if err := c.Start(); err != nil {
+		return err
+	}
+
+	err := c.runGuarded(fn)
+	if err != nil {
+		_ = c.Kill("SIGKILL")
+		_ = c.Wait()
+		return err
+	}
+
+	return c.Wait()
+}
+
+func (c *Cmd) runGuarded(fn func() error) error {
+	defer c.Guard()
+	return fn()
+}
+
+// Guard force-removes the container, along with its anonymous volumes, if the calling goroutine is
+// panicking, and then continues panicking. It must be deferred directly after a successful Start:
+//
+//	if err := cmd.Start(); err != nil {
+//		return err
+//	}
+//	defer cmd.Guard()
+//
+// Guard also releases the resources Wait would have, such as the slot of Limiter, the reservation
+// of Quota, and the connection and pipes used for copying the standard streams. It does nothing
+// when not panicking. Wait must not be called after Guard removed the container.
+func (c *Cmd) Guard() {
+	r := recover()
+	if r == nil {
+		return
+	}
+
+	if c.ContainerID != "" && !c.finished {
+		c.finished = true
+
+		// Best effort, the context of the Cmd might be the reason for the panic
+		_ = c.cli.ContainerRemove(context.Background(), c.ContainerID, container.RemoveOptions{
+			RemoveVolumes: true,
+			Force:         true,
+		})
+
+		if c.waitDone != nil {
+			close(c.waitDone)
+		}
+		if c.deadlineTimer != nil {
+			c.deadlineTimer.Stop()
+		}
+		c.releaseLimiter()
+		c.releaseQuota()
+		c.stopCollectingUsage()
+
+		// Closing the attach connection, which is closed after Wait, ends the goroutines copying
+		// the standard streams
+		c.closeDescriptors(c.closeAfterStdin)
+		c.closeDescriptors(c.closeAfterOutput)
+		c.closeDescriptors(c.closeAfterWait)
+		c.finishLifecycle()
+	}
+
+	panic(r)
+}
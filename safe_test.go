@@ -0,0 +1,73 @@
+package dockerexec_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/docker/docker/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestRunSafe(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "echo", "hello")
+	called := false
+	err := cmd.RunSafe(func() error {
+		called = true
+		return nil
+	})
+	require.NoError(t, err)
+	assert.True(t, called)
+}
+
+func TestRunSafeError(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "sleep", "120")
+	fnErr := errors.New("failed")
+	err := cmd.RunSafe(func() error {
+		return fnErr
+	})
+	assert.ErrorIs(t, err, fnErr)
+}
+
+func TestRunSafePanic(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "sleep", "120")
+	cmd.HostConfig.AutoRemove = false
+
+	assert.PanicsWithValue(t, "boom", func() {
+		_ = cmd.RunSafe(func() error {
+			panic("boom")
+		})
+	})
+
+	require.NotEmpty(t, cmd.ContainerID)
+	_, err := dockerClient.ContainerInspect(context.Background(), cmd.ContainerID)
+	assert.True(t, client.IsErrNotFound(err), "container should have been removed, got: %v", err)
+}
+
+func TestRunSafePanicReleasesLimiter(t *testing.T) {
+	limiter := dockerexec.NewLimiter(1)
+	limiter.FailFast = true
+
+	cmd := dockerexec.Command(dockerClient, testImage, "sleep", "120")
+	cmd.Limiter = limiter
+	stdout, err := cmd.StdoutPipe()
+	require.NoError(t, err)
+
+	assert.PanicsWithValue(t, "boom", func() {
+		_ = cmd.RunSafe(func() error {
+			panic("boom")
+		})
+	})
+	assert.Equal(t, 0, limiter.Running())
+
+	// The pipe was closed, rather than left waiting for output forever
+	_, err = stdout.Read(make([]byte, 1))
+	assert.Error(t, err)
+
+	cmd = dockerexec.Command(dockerClient, testImage, "true")
+	cmd.Limiter = limiter
+	require.NoError(t, cmd.Run())
+}
@@ -0,0 +1,257 @@
+package dockerexec
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+)
+
+// serviceHealthPollInterval is the interval in which WaitHealthy polls the health of a service.
+const serviceHealthPollInterval = 100 * time.Millisecond
+
+// ErrServiceExited is returned by WaitHealthy when the container of the service exits before
+// becoming healthy.
+var ErrServiceExited = errors.New("dockerexec: service exited")
+
+// ErrServiceUnhealthy is returned by WaitHealthy when the container of the service is reported
+// unhealthy by its health check.
+var ErrServiceUnhealthy = errors.New("dockerexec: service is unhealthy")
+
+// Service manages a container intended to run indefinitely, such as a server, rather than to run
+// to completion like a Cmd. It can be started, gracefully stopped, restarted and monitored.
+//
+// Each start of the service runs a fresh container from the Cmd returned by the template function
+// given to NewService. Configure Config.Healthcheck in it for Status and WaitHealthy to report
+// the health of the container.
+type Service struct {
+	// StopTimeout is how long Stop waits for the container to exit after sending it its stop
+	// signal before killing it. If nil, the container's own stop timeout is used.
+	StopTimeout *time.Duration
+
+	template func() *Cmd
+
+	mu   sync.Mutex
+	cmd  *Cmd
+	done chan struct{}
+	err  error // the error of Wait, once done is closed
+}
+
+// ServiceStatus is the status of a Service.
+type ServiceStatus struct {
+	// State is the state of the current container of the service, StateNew if it was never
+	// started.
+	State State
+
+	// ContainerID is the ID of the current container of the service.
+	ContainerID string
+
+	// Health is the health status of the container reported by its health check (One of
+	// types.Starting, types.Healthy or types.Unhealthy), or types.NoHealthcheck if it has none.
+	// It is empty if the container isn't running.
+	Health string
+
+	// StartedAt is the time the current container was started.
+	StartedAt time.Time
+
+	// Err is the error the container exited with, if it exited, like returned by Cmd.Wait.
+	Err error
+}
+
+// NewService returns a Service running the Cmds returned by template.
+func NewService(template func() *Cmd) *Service {
+	return &Service{template: template}
+}
+
+// Start starts a container for the service. It fails if the service is already running.
+func (s *Service) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running() {
+		return errors.New("dockerexec: service already running")
+	}
+
+	cmd := s.template()
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	s.cmd = cmd
+	s.done = done
+	go func() {
+		err := cmd.Wait()
+		s.mu.Lock()
+		s.err = err
+		s.mu.Unlock()
+		close(done)
+	}()
+	return nil
+}
+
+// running returns whether the container of the service is running. s.mu must be held.
+func (s *Service) running() bool {
+	if s.done == nil {
+		return false
+	}
+	select {
+	case <-s.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// Stop gracefully stops the container of the service by sending it its stop signal, and killing
+// it if it doesn't exit within StopTimeout, and waits for it to exit. The exit status of the
+// container is not reported as an error, as it's expected to exit due to the stop signal.
+//
+// If ctx becomes done before the container exits, Stop returns ctx.Err().
+func (s *Service) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	cmd, done := s.cmd, s.done
+	s.mu.Unlock()
+
+	if cmd == nil {
+		return errors.New("dockerexec: service not started")
+	}
+
+	select {
+	case <-done:
+	default:
+		var options container.StopOptions
+		if s.StopTimeout != nil {
+			timeout := int(s.StopTimeout.Round(time.Second) / time.Second)
+			options.Timeout = &timeout
+		}
+		if err := cmd.cli.ContainerStop(ctx, cmd.ContainerID, options); err != nil {
+			return wrapError("stop container", err)
+		}
+	}
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	s.mu.Lock()
+	err := s.err
+	s.mu.Unlock()
+
+	var ee *ExitError
+	if errors.As(err, &ee) {
+		return nil
+	}
+	return err
+}
+
+// Restart stops the container of the service, if it's running, and starts a new one.
+func (s *Service) Restart(ctx context.Context) error {
+	s.mu.Lock()
+	started := s.cmd != nil
+	s.mu.Unlock()
+
+	if started {
+		if err := s.Stop(ctx); err != nil {
+			return err
+		}
+	}
+	return s.Start()
+}
+
+// Done returns a channel that's closed when the current container of the service exits, or nil
+// if the service was never started.
+func (s *Service) Done() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.done
+}
+
+// Status returns the status of the service, inspecting its container for its health if it's
+// running.
+func (s *Service) Status(ctx context.Context) (ServiceStatus, error) {
+	s.mu.Lock()
+	cmd, running, err := s.cmd, s.running(), s.err
+	s.mu.Unlock()
+
+	if cmd == nil {
+		return ServiceStatus{State: StateNew}, nil
+	}
+
+	status := ServiceStatus{
+		State:       cmd.State(),
+		ContainerID: cmd.ContainerID,
+		StartedAt:   cmd.startTime,
+	}
+	if !running {
+		status.Err = err
+		return status, nil
+	}
+
+	inspect, err := cmd.cli.ContainerInspect(ctx, cmd.ContainerID)
+	if err != nil {
+		return status, wrapError("inspect container", err)
+	}
+	if inspect.State != nil {
+		if t, err := time.Parse(time.RFC3339Nano, inspect.State.StartedAt); err == nil {
+			status.StartedAt = t
+		}
+		if inspect.State.Health != nil {
+			status.Health = inspect.State.Health.Status
+		} else {
+			status.Health = types.NoHealthcheck
+		}
+	}
+	return status, nil
+}
+
+// WaitHealthy waits for the container of the service to be reported healthy by its health check.
+// It returns ErrServiceUnhealthy if it's reported unhealthy instead, and ErrServiceExited if it
+// exits. It returns immediately if the container has no health check.
+func (s *Service) WaitHealthy(ctx context.Context) error {
+	ticker := time.NewTicker(serviceHealthPollInterval)
+	defer ticker.Stop()
+
+	for {
+		status, err := s.Status(ctx)
+		if err != nil {
+			return err
+		}
+		if status.State == StateNew {
+			return errors.New("dockerexec: service not started")
+		}
+
+		switch status.Health {
+		case types.Healthy, types.NoHealthcheck:
+			return nil
+		case types.Unhealthy:
+			return ErrServiceUnhealthy
+		case "":
+			return ErrServiceExited
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Logs returns the logs of the current container of the service. See Cmd.Logs.
+func (s *Service) Logs(options container.LogsOptions) (io.ReadCloser, error) {
+	s.mu.Lock()
+	cmd := s.cmd
+	s.mu.Unlock()
+
+	if cmd == nil {
+		return nil, errors.New("dockerexec: service not started")
+	}
+	return cmd.Logs(options)
+}
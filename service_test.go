@@ -0,0 +1,60 @@
+package dockerexec_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestService(t *testing.T) {
+	svc := dockerexec.NewService(func() *dockerexec.Cmd {
+		cmd := dockerexec.Command(dockerClient, testImage, "sleep", "infinity")
+		cmd.Config.Healthcheck = &container.HealthConfig{
+			Test:     []string{"CMD", "true"},
+			Interval: 100 * time.Millisecond,
+		}
+		return cmd
+	})
+	timeout := time.Second
+	svc.StopTimeout = &timeout
+
+	ctx := context.Background()
+
+	status, err := svc.Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, dockerexec.StateNew, status.State)
+
+	require.NoError(t, svc.Start())
+	assert.Error(t, svc.Start())
+
+	waitCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	require.NoError(t, svc.WaitHealthy(waitCtx))
+
+	status, err = svc.Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, dockerexec.StateStarted, status.State)
+	assert.Equal(t, types.Healthy, status.Health)
+	first := status.ContainerID
+
+	require.NoError(t, svc.Restart(ctx))
+
+	status, err = svc.Status(ctx)
+	require.NoError(t, err)
+	assert.NotEqual(t, first, status.ContainerID)
+
+	require.NoError(t, svc.Stop(ctx))
+	<-svc.Done()
+
+	status, err = svc.Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, dockerexec.StateExited, status.State)
+	assert.Empty(t, status.Health)
+}
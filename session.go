@@ -0,0 +1,72 @@
+package dockerexec
+
+import (
+	"context"
+	"errors"
+)
+
+// Session runs a sequence of commands inside a single long-running container using exec, each
+// with its own standard I/O and exit code, while sharing the container's filesystem and other
+// state. This is much faster than starting a container per command.
+//
+// The container runs "sleep infinity" by default, which requires the image to have a sleep that
+// supports it, such as the one from GNU coreutils or BusyBox.
+type Session struct {
+	// Cmd is the Cmd of the container of the session. It may be configured before calling Start,
+	// but its standard I/O must be left unset.
+	Cmd *Cmd
+}
+
+// NewSession returns a Session that runs its commands inside a container of the given image.
+func NewSession(cli Client, image string) *Session {
+	return &Session{Cmd: Command(cli, image, "sleep", "infinity")}
+}
+
+// NewSessionContext is like NewSession but includes a context, which is used to kill the container
+// of the session when done.
+func NewSessionContext(ctx context.Context, cli Client, image string) *Session {
+	return &Session{Cmd: CommandContext(ctx, cli, image, "sleep", "infinity")}
+}
+
+// Start starts the container of the session.
+func (s *Session) Start() error {
+	return s.Cmd.Start()
+}
+
+// Command returns the ExecCmd struct to execute the named program with the given arguments
+// inside the container of the session. The session must have been started by Start.
+//
+// Commands may run concurrently, see Cmd.ExecCommand.
+func (s *Session) Command(name string, arg ...string) *ExecCmd {
+	return s.Cmd.ExecCommand(name, arg...)
+}
+
+// CommandContext is like Command but includes a context.
+func (s *Session) CommandContext(ctx context.Context, name string, arg ...string) *ExecCmd {
+	return s.Cmd.ExecCommandContext(ctx, name, arg...)
+}
+
+// Execs returns the commands of the session that were started but not yet waited for.
+func (s *Session) Execs() []*ExecCmd {
+	return s.Cmd.Execs()
+}
+
+// Close kills the container of the session and waits for it to exit. Commands still running
+// inside it are killed as well.
+func (s *Session) Close() error {
+	if len(s.Cmd.ContainerID) == 0 {
+		return errors.New("dockerexec: not started")
+	}
+
+	if err := s.Cmd.Kill("SIGKILL"); err != nil {
+		return err
+	}
+
+	err := s.Cmd.Wait()
+	var ee *ExitError
+	if errors.As(err, &ee) {
+		// Killed by us.
+		return nil
+	}
+	return err
+}
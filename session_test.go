@@ -0,0 +1,112 @@
+package dockerexec_test
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestSession(t *testing.T) {
+	session := dockerexec.NewSession(dockerClient, testImage)
+	require.NoError(t, session.Start())
+	defer func() {
+		assert.NoError(t, session.Close())
+	}()
+
+	require.NoError(t, session.Command("sh", "-c", "echo Hello, World! > /tmp/hello.txt").Run())
+
+	output, err := session.Command("cat", "/tmp/hello.txt").Output()
+	require.NoError(t, err)
+	assert.Equal(t, "Hello, World!\n", string(output))
+
+	cmd := session.Command("cat")
+	cmd.Stdin = strings.NewReader("stdin\n")
+	output, err = cmd.Output()
+	require.NoError(t, err)
+	assert.Equal(t, "stdin\n", string(output))
+
+	cmd = session.Command("sh", "-c", "echo stderr >&2; exit 3")
+	_, err = cmd.Output()
+	var exitErr *dockerexec.ExitError
+	require.ErrorAs(t, err, &exitErr)
+	assert.EqualValues(t, 3, exitErr.StatusCode)
+	assert.Equal(t, "stderr\n", string(exitErr.Stderr))
+	assert.EqualValues(t, 3, cmd.StatusCode)
+}
+
+func TestExecOptions(t *testing.T) {
+	session := dockerexec.NewSession(dockerClient, testImage)
+	session.Cmd.Config.User = "nobody"
+	require.NoError(t, session.Start())
+	defer func() {
+		assert.NoError(t, session.Close())
+	}()
+
+	output, err := session.Command("id", "-un").Output()
+	require.NoError(t, err)
+	assert.Equal(t, "nobody\n", string(output))
+
+	cmd := session.Command("sh", "-c", "id -un; pwd; echo $FOO")
+	cmd.User = "root"
+	cmd.WorkingDir = "/tmp"
+	cmd.Env = []string{"FOO=bar"}
+	output, err = cmd.Output()
+	require.NoError(t, err)
+	assert.Equal(t, "root\n/tmp\nbar\n", string(output))
+}
+
+func TestExecInspect(t *testing.T) {
+	session := dockerexec.NewSession(dockerClient, testImage)
+	require.NoError(t, session.Start())
+	defer func() {
+		assert.NoError(t, session.Close())
+	}()
+
+	cmd := session.Command("sleep", "0.5")
+	require.NoError(t, cmd.Start())
+	inspect, err := cmd.Inspect()
+	require.NoError(t, err)
+	assert.True(t, inspect.Running)
+	assert.NotZero(t, inspect.Pid)
+	require.NoError(t, cmd.Wait())
+	require.NotNil(t, cmd.ExecInspect)
+	assert.False(t, cmd.ExecInspect.Running)
+	assert.True(t, cmd.Started())
+
+	cmd = session.Command("does-not-exist")
+	err = cmd.Run()
+	var exitErr *dockerexec.ExitError
+	require.ErrorAs(t, err, &exitErr)
+	assert.False(t, cmd.Started())
+}
+
+func TestConcurrentExecs(t *testing.T) {
+	session := dockerexec.NewSession(dockerClient, testImage)
+	require.NoError(t, session.Start())
+	defer func() {
+		assert.NoError(t, session.Close())
+	}()
+
+	var cmds []*dockerexec.ExecCmd
+	var outputs []*strings.Builder
+	for i := 0; i < 3; i++ {
+		var output strings.Builder
+		cmd := session.Command("sh", "-c", "sleep 0.2; echo $0", strconv.Itoa(i))
+		cmd.Stdout = &output
+		require.NoError(t, cmd.Start())
+		cmds = append(cmds, cmd)
+		outputs = append(outputs, &output)
+	}
+	assert.Equal(t, cmds, session.Execs())
+
+	for i, cmd := range cmds {
+		require.NoError(t, cmd.Wait())
+		assert.Equal(t, strconv.Itoa(i)+"\n", outputs[i].String())
+	}
+	assert.Empty(t, session.Execs())
+}
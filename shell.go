@@ -0,0 +1,160 @@
+package dockerexec
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// shellBufferSize is the amount of output of a ShellSession buffered in memory before spilling to
+// disk, see BufferedStdoutPipe.
+const shellBufferSize = 64 << 10
+
+// ShellOptions configures the interactive shell started by Shell.
+type ShellOptions struct {
+	// Command is the shell to run, defaults to /bin/sh.
+	Command []string
+
+	// Env, User and WorkingDir are set on the container's Config.
+	Env        []string
+	User       string
+	WorkingDir string
+
+	// Height and Width are the initial size of the terminal, defaults to the daemon's default.
+	Height uint
+	Width  uint
+}
+
+// ShellSession is an interactive shell running in a container with a TTY, as started by Shell.
+//
+// It's an io.ReadWriteCloser, where writes send input to the shell, and reads return its output.
+// Output is buffered, so the session keeps running even if it isn't read promptly.
+type ShellSession struct {
+	// Cmd is the underlying Cmd running the shell. It must not be waited on directly.
+	Cmd *Cmd
+
+	stdin   io.WriteCloser
+	stdout  io.ReadCloser
+	done    chan struct{}
+	err     error
+	closing bool
+	mu      sync.Mutex
+}
+
+// Shell starts an interactive shell inside the given image, attached to a TTY, as a building block
+// for web terminals and debugging tools.
+func Shell(cli Client, image string, opts ShellOptions) (*ShellSession, error) {
+	return ShellContext(context.Background(), cli, image, opts)
+}
+
+// ShellContext is like Shell but includes a context, which kills the shell when done.
+func ShellContext(ctx context.Context, cli Client, image string, opts ShellOptions) (*ShellSession, error) {
+	command := opts.Command
+	if len(command) == 0 {
+		command = []string{"/bin/sh"}
+	}
+
+	cmd := CommandContext(ctx, cli, image, command[0], command[1:]...)
+	cmd.Config.Tty = true
+	cmd.Config.Env = opts.Env
+	cmd.Config.User = opts.User
+	cmd.Config.WorkingDir = opts.WorkingDir
+	if opts.Height != 0 && opts.Width != 0 {
+		cmd.HostConfig.ConsoleSize = [2]uint{opts.Height, opts.Width}
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.BufferedStdoutPipe(shellBufferSize)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		stdout.Close()
+		return nil, err
+	}
+
+	s := &ShellSession{
+		Cmd:    cmd,
+		stdin:  stdin,
+		stdout: stdout,
+		done:   make(chan struct{}),
+	}
+	go func() {
+		s.err = cmd.Wait()
+		close(s.done)
+	}()
+	return s, nil
+}
+
+// Write sends input to the shell.
+func (s *ShellSession) Write(p []byte) (int, error) {
+	return s.stdin.Write(p)
+}
+
+// Read reads output from the shell. It returns io.EOF once the shell exits and all of its output
+// has been read.
+func (s *ShellSession) Read(p []byte) (int, error) {
+	return s.stdout.Read(p)
+}
+
+// Resize resizes the terminal of the shell.
+func (s *ShellSession) Resize(height, width uint) error {
+	err := s.Cmd.cli.ContainerResize(s.Cmd.context(), s.Cmd.ContainerID, container.ResizeOptions{
+		Height: height,
+		Width:  width,
+	})
+	return wrapError("resize container", err)
+}
+
+// Done returns a channel that's closed once the shell exits.
+func (s *ShellSession) Done() <-chan struct{} {
+	return s.done
+}
+
+// Wait waits for the shell to exit, returning the same errors as Cmd.Wait. Unread output remains
+// available for reading until the session is closed.
+func (s *ShellSession) Wait() error {
+	<-s.done
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closing {
+		return nil
+	}
+	return s.err
+}
+
+// Close terminates the shell, if still running, waits for it to exit and releases the buffered
+// output. The shell being killed by Close isn't reported as an error.
+func (s *ShellSession) Close() error {
+	s.mu.Lock()
+	select {
+	case <-s.done:
+	default:
+		s.closing = true
+	}
+	kill := s.closing
+	s.mu.Unlock()
+
+	s.stdin.Close()
+	if kill {
+		if err := s.Cmd.Kill("SIGKILL"); err != nil {
+			select {
+			case <-s.done:
+				// Exited on its own meanwhile.
+			default:
+				return err
+			}
+		}
+	}
+	<-s.done
+
+	s.stdout.Close()
+	return s.Wait()
+}
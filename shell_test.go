@@ -0,0 +1,48 @@
+package dockerexec_test
+
+import (
+	"bufio"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestShell(t *testing.T) {
+	session, err := dockerexec.Shell(dockerClient, testImage, dockerexec.ShellOptions{Height: 24, Width: 80})
+	require.NoError(t, err)
+	defer session.Close()
+
+	require.NoError(t, session.Resize(40, 120))
+
+	_, err = io.WriteString(session, "stty size; exit 3\n")
+	require.NoError(t, err)
+
+	r := bufio.NewReader(session)
+	found := false
+	for {
+		line, err := r.ReadString('\n')
+		if line == "40 120\r\n" {
+			found = true
+		}
+		if err != nil {
+			break
+		}
+	}
+	assert.True(t, found)
+
+	var exitErr *dockerexec.ExitError
+	require.ErrorAs(t, session.Wait(), &exitErr)
+	assert.EqualValues(t, 3, exitErr.StatusCode)
+}
+
+func TestShellClose(t *testing.T) {
+	session, err := dockerexec.Shell(dockerClient, testImage, dockerexec.ShellOptions{})
+	require.NoError(t, err)
+
+	assert.NoError(t, session.Close())
+	<-session.Done()
+}
@@ -0,0 +1,169 @@
+package dockerexec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// CommandString is like Command, but takes the command as a single string, such as
+// "grep -r 'foo bar' /data", which is split into the name and arguments following the quoting
+// rules of a POSIX shell, without invoking a shell. This is convenient for tools receiving
+// commands as strings, e.g. from configuration files.
+//
+// Shell syntax beyond quoting and escaping, such as variables, globs, pipes, and redirections,
+// isn't supported. Unquoted variables, pipes, redirections and the like make Start fail rather
+// than being passed on literally.
+func CommandString(cli Client, image string, command string) *Cmd {
+	args, err := splitShellWords(command)
+	if err != nil {
+		cmd := Command(cli, image, "")
+		cmd.Err = err
+		return cmd
+	}
+	if len(args) == 0 {
+		return Command(cli, image, "")
+	}
+	return Command(cli, image, args[0], args[1:]...)
+}
+
+// CommandStringContext is like CommandString but includes a context, see CommandContext.
+func CommandStringContext(ctx context.Context, cli Client, image string, command string) *Cmd {
+	if ctx == nil {
+		panic("nil Context")
+	}
+	cmd := CommandString(cli, image, command)
+	cmd.ctx = ctx
+	return cmd
+}
+
+// shellSpecial are the characters with a special meaning to a shell that splitShellWords
+// doesn't support when unquoted.
+const shellSpecial = "|&;<>()$`"
+
+// splitShellWords splits s into words following the quoting rules of a POSIX shell.
+func splitShellWords(s string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+
+		case ch == '\\':
+			i++
+			if i == len(s) {
+				return nil, errors.New("dockerexec: trailing backslash in command")
+			}
+			// A backslash-newline is a line continuation
+			if s[i] != '\n' {
+				word.WriteByte(s[i])
+				inWord = true
+			}
+
+		case ch == '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				return nil, errors.New("dockerexec: unterminated single quote in command")
+			}
+			word.WriteString(s[i+1 : i+1+end])
+			i += 1 + end
+			inWord = true
+
+		case ch == '"':
+			i++
+			for ; i < len(s) && s[i] != '"'; i++ {
+				switch s[i] {
+				case '\\':
+					// Inside double quotes, a backslash only escapes characters special there
+					if i+1 < len(s) && strings.IndexByte("$`\"\\\n", s[i+1]) >= 0 {
+						i++
+						if s[i] != '\n' {
+							word.WriteByte(s[i])
+						}
+						continue
+					}
+					word.WriteByte('\\')
+				case '$', '`':
+					return nil, fmt.Errorf("dockerexec: unsupported shell syntax %q in command", s[i])
+				default:
+					word.WriteByte(s[i])
+				}
+			}
+			if i == len(s) {
+				return nil, errors.New("dockerexec: unterminated double quote in command")
+			}
+			inWord = true
+
+		case strings.IndexByte(shellSpecial, ch) >= 0, ch == '#' && !inWord:
+			return nil, fmt.Errorf("dockerexec: unsupported shell syntax %q in command", ch)
+
+		default:
+			word.WriteByte(ch)
+			inWord = true
+		}
+	}
+
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// ShellQuote quotes s for a POSIX shell, so that it's parsed back as a single word with the same
+// value. Single quotes are used, with each single quote in s closing the quoted part, adding an
+// escaped quote, and reopening it:
+//
+//	it's -> 'it'\''s'
+//
+// Words with only characters that are never special, such as "-la" or "/tmp/file.txt", are
+// returned as is.
+func ShellQuote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	if strings.IndexFunc(s, func(r rune) bool { return !isShellSafe(r) }) < 0 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func isShellSafe(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("@%+=:,./_-", r)
+}
+
+// ShellJoin quotes each of args with ShellQuote and joins them with spaces, forming a command
+// line a shell parses back to args.
+func ShellJoin(args ...string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = ShellQuote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// ShellCommand returns a command running script with "sh -c", after which the arguments in args
+// are executed, without being interpreted by the shell, e.g. to set up the environment before
+// running a command given as an argument vector:
+//
+//	argv := dockerexec.ShellCommand("cd /src && umask 077", args...)
+//	cmd := dockerexec.Command(cli, image, argv[0], argv[1:]...)
+//
+// An empty script just executes args.
+func ShellCommand(script string, args ...string) []string {
+	if len(args) == 0 {
+		return []string{"sh", "-c", script}
+	}
+	if script == "" {
+		return []string{"sh", "-c", "exec " + ShellJoin(args...)}
+	}
+	return []string{"sh", "-c", script + "\nexec " + ShellJoin(args...)}
+}
@@ -0,0 +1,74 @@
+package dockerexec_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestCommandStringParsing(t *testing.T) {
+	for command, expected := range map[string][]string{
+		"grep -r 'foo bar' /data":     {"grep", "-r", "foo bar", "/data"},
+		`echo "a \"b\" \c" d\ e`:      {"echo", `a "b" \c`, "d e"},
+		"  echo\t a  \\\n b ":         {"echo", "a", "b"},
+		`echo '' "" x`:                {"echo", "", "", "x"},
+		`echo it"'"s 'a b'c`:          {"echo", "it's", "a bc"},
+		"echo a#b":                    {"echo", "a#b"},
+		`printf '%s\n' "it's"`:        {"printf", `%s\n`, "it's"},
+		`sh -c 'echo $HOME | cat'`:    {"sh", "-c", "echo $HOME | cat"},
+		`echo "line1` + "\n" + `two"`: {"echo", "line1\ntwo"},
+	} {
+		cmd := dockerexec.CommandString(dockerClient, testImage, command)
+		if assert.NoError(t, cmd.Err, command) {
+			assert.Equal(t, expected, []string(cmd.Config.Cmd), command)
+		}
+	}
+
+	for _, command := range []string{
+		"", "   ", "echo 'a", `echo "a`, `echo a\`, "echo $HOME", `echo "$HOME"`,
+		"echo a | cat", "echo a > b", "echo a; echo b", "echo `id`", "echo # comment",
+	} {
+		cmd := dockerexec.CommandString(dockerClient, testImage, command)
+		assert.Error(t, cmd.Err, command)
+	}
+}
+
+func TestCommandString(t *testing.T) {
+	cmd := dockerexec.CommandString(dockerClient, testImage, `sh -c 'echo "$0"' 'hello world'`)
+	output, err := cmd.Output()
+	require.NoError(t, err)
+	assert.Equal(t, "hello world\n", string(output))
+}
+
+func TestShellQuote(t *testing.T) {
+	for s, expected := range map[string]string{
+		"":              "''",
+		"-la":           "-la",
+		"/tmp/file.txt": "/tmp/file.txt",
+		"foo bar":       "'foo bar'",
+		"it's":          `'it'\''s'`,
+		"a\nb":          "'a\nb'",
+		"$HOME":         "'$HOME'",
+		`"quoted"`:      `'"quoted"'`,
+	} {
+		assert.Equal(t, expected, dockerexec.ShellQuote(s), s)
+	}
+}
+
+func TestShellJoinRoundTrip(t *testing.T) {
+	args := []string{"printf", "%s|", "foo bar", "it's", "a\nb", "", `"q"`, `back\slash`}
+	cmd := dockerexec.CommandString(dockerClient, testImage, dockerexec.ShellJoin(args...))
+	require.NoError(t, cmd.Err)
+	assert.Equal(t, args, []string(cmd.Config.Cmd))
+}
+
+func TestShellCommand(t *testing.T) {
+	argv := dockerexec.ShellCommand("cd /tmp", "sh", "-c", `echo "$(pwd) $0"`, "it's $HOME")
+	cmd := dockerexec.Command(dockerClient, testImage, argv[0], argv[1:]...)
+	output, err := cmd.Output()
+	require.NoError(t, err)
+	assert.Equal(t, "/tmp it's $HOME\n", string(output))
+}
@@ -0,0 +1,61 @@
+package dockerexec
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+)
+
+// MountSocket exposes the host Unix socket hostPath, such as an ssh-agent socket or
+// /var/run/docker.sock, in the container at containerPath by bind-mounting it. It requires the
+// daemon to run on this host.
+//
+// When the container runs as a user other than root (See Config.User), the group owning the socket
+// is added to HostConfig.GroupAdd, so that the user can access sockets that are only accessible to
+// their group, which is typical of the Docker socket.
+func (c *Cmd) MountSocket(hostPath, containerPath string) error {
+	fi, err := os.Stat(hostPath)
+	if err != nil {
+		return fmt.Errorf("dockerexec: socket: %w", err)
+	}
+	if fi.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("dockerexec: %s is not a socket", hostPath)
+	}
+
+	if err := c.AddBind(hostPath, containerPath, false); err != nil {
+		return err
+	}
+
+	if gid, ok := fileGID(fi); ok && gid != 0 && c.Config.User != "" && c.Config.User != "root" && c.Config.User != "0" {
+		hc := c.hostConfig()
+		group := strconv.Itoa(gid)
+		if !slices.Contains(hc.GroupAdd, group) {
+			hc.GroupAdd = append(hc.GroupAdd, group)
+		}
+	}
+	return nil
+}
+
+// ForwardSocket listens on the host Unix socket localPath and proxies each accepted connection to
+// the Unix socket containerPath in the container, through an exec of a relay in the container,
+// which requires socat or a netcat supporting -U in the image. This gives the host access to
+// socket based services of the container, such as the Docker daemon of a Docker in Docker
+// container.
+//
+// The container must have been started by Start. Close the returned PortForward when done, which
+// also removes localPath.
+func (c *Cmd) ForwardSocket(localPath, containerPath string) (*PortForward, error) {
+	if len(c.ContainerID) == 0 {
+		return nil, errors.New("dockerexec: not started")
+	}
+
+	l, err := net.Listen("unix", localPath)
+	if err != nil {
+		return nil, fmt.Errorf("dockerexec: forward socket: %w", err)
+	}
+
+	return c.startForward(l, 0, []string{"sh", "-c", socketForwardScript, containerPath}), nil
+}
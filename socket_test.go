@@ -0,0 +1,87 @@
+//go:build !windows
+
+package dockerexec_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestMountSocket(t *testing.T) {
+	sockPath := filepath.Join(t.TempDir(), "test.sock")
+	l, err := net.Listen("unix", sockPath)
+	require.NoError(t, err)
+	defer l.Close()
+
+	cmd := dockerexec.Command(dockerClient, testImage, "test", "-S", "/run/test.sock")
+	cmd.Config.User = "1000"
+	require.NoError(t, cmd.MountSocket(sockPath, "/run/test.sock"))
+
+	fi, err := os.Stat(sockPath)
+	require.NoError(t, err)
+	if gid := fi.Sys().(*syscall.Stat_t).Gid; gid != 0 {
+		assert.Contains(t, cmd.HostConfig.GroupAdd, strconv.Itoa(int(gid)))
+	}
+
+	err = cmd.Run()
+	require.NoError(t, err)
+}
+
+func TestMountSocketNotSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(path, nil, 0o644))
+
+	cmd := dockerexec.Command(dockerClient, testImage, "true")
+	err := cmd.MountSocket(path, "/run/test.sock")
+	assert.ErrorContains(t, err, "is not a socket")
+}
+
+func TestForwardSocket(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cmd := dockerexec.CommandContext(ctx, dockerClient, "alpine/socat:1.8.0.0",
+		"UNIX-LISTEN:/tmp/echo.sock,fork", "EXEC:cat")
+	cmd.Config.Entrypoint = []string{"socat"}
+	cmd.Pull = &dockerexec.PullConfig{}
+	require.NoError(t, cmd.Start())
+	defer func() {
+		cancel()
+		_ = cmd.Wait()
+	}()
+
+	sockPath := filepath.Join(t.TempDir(), "echo.sock")
+	f, err := cmd.ForwardSocket(sockPath, "/tmp/echo.sock")
+	require.NoError(t, err)
+	defer f.Close()
+
+	var reply []byte
+	require.Eventually(t, func() bool {
+		conn, err := net.Dial("unix", sockPath)
+		if err != nil {
+			return false
+		}
+		defer conn.Close()
+
+		_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+		if _, err := conn.Write([]byte("hello\n")); err != nil {
+			return false
+		}
+		_ = conn.(*net.UnixConn).CloseWrite()
+		reply, err = io.ReadAll(conn)
+		return err == nil && len(reply) > 0
+	}, 30*time.Second, 200*time.Millisecond)
+	assert.Equal(t, "hello\n", string(reply))
+}
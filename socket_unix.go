@@ -0,0 +1,17 @@
+//go:build !windows
+
+package dockerexec
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileGID returns the ID of the group owning the file described by fi.
+func fileGID(fi os.FileInfo) (int, bool) {
+	stat, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return int(stat.Gid), true
+}
@@ -0,0 +1,60 @@
+package dockerexec
+
+import (
+	"errors"
+	"io"
+)
+
+// OutputSpill is like Output, but for commands producing more output than is reasonable to keep
+// in memory. Up to memoryLimit bytes of output are buffered in memory, and beyond that, all of
+// it is spilled to a temporary file. The returned reader reads the output from the start, and
+// the caller must close it once done with it, removing the temporary file.
+func (c *Cmd) OutputSpill(memoryLimit int) (io.ReadSeekCloser, error) {
+	if c.Stdout != nil {
+		return nil, errors.New("dockerexec: Stdout already set")
+	}
+	stdout := newSpillBuffer(memoryLimit)
+	c.Stdout = stdout
+
+	captureErr := c.Stderr == nil && !c.Config.Tty
+	if captureErr {
+		c.Stderr = &prefixSuffixSaver{N: 32 << 10}
+	}
+
+	err := c.Run()
+	if err != nil && captureErr {
+		// The ExitError might be wrapped, such as by a DebugError.
+		var ee *ExitError
+		if errors.As(err, &ee) {
+			ee.Stderr = c.Stderr.(*prefixSuffixSaver).Bytes()
+		}
+	}
+	return spillResult(stdout, err)
+}
+
+// CombinedOutputSpill is like CombinedOutput, but spills the output to a temporary file beyond
+// memoryLimit bytes, see OutputSpill.
+func (c *Cmd) CombinedOutputSpill(memoryLimit int) (io.ReadSeekCloser, error) {
+	if c.Stdout != nil {
+		return nil, errors.New("dockerexec: Stdout already set")
+	}
+	if c.Stderr != nil {
+		return nil, errors.New("dockerexec: Stderr already set")
+	}
+	b := newSpillBuffer(memoryLimit)
+	c.Stdout = b
+	if !c.Config.Tty {
+		c.Stderr = b
+	}
+	return spillResult(b, c.Run())
+}
+
+// spillResult returns a reader of the output buffered in b along with err, the error of the run,
+// which takes precedence over an error getting the reader.
+func spillResult(b *spillBuffer, err error) (io.ReadSeekCloser, error) {
+	r, readerErr := b.readSeeker()
+	if readerErr != nil && err == nil {
+		err = readerErr
+	}
+	return r, err
+}
@@ -0,0 +1,65 @@
+package dockerexec_test
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestOutputSpill(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "sh", "-c", "head -c 1048576 /dev/zero")
+	r, err := cmd.OutputSpill(1024)
+	require.NoError(t, err)
+	defer r.Close()
+
+	size, err := r.Seek(0, io.SeekEnd)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1<<20, size)
+
+	_, err = r.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Len(t, data, 1<<20)
+	assert.NoError(t, r.Close())
+}
+
+func TestOutputSpillInMemory(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "echo", "hello")
+	r, err := cmd.OutputSpill(1024)
+	require.NoError(t, err)
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, "hello\n", string(data))
+}
+
+func TestOutputSpillExitError(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "sh", "-c", "echo out; echo err >&2; exit 3")
+	r, err := cmd.OutputSpill(1)
+	var exitErr *dockerexec.ExitError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, "err\n", string(exitErr.Stderr))
+	require.NotNil(t, r)
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, "out\n", string(data))
+}
+
+func TestCombinedOutputSpill(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "sh", "-c", "echo out; sleep 0.1; echo err >&2")
+	r, err := cmd.CombinedOutputSpill(2)
+	require.NoError(t, err)
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, "out\nerr\n", string(data))
+}
@@ -0,0 +1,158 @@
+package dockerexec
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/errdefs"
+)
+
+// standbyRetryInterval is the interval in which a StandbyPool retries creating a container after
+// failing to.
+const standbyRetryInterval = time.Second
+
+// StandbyPool keeps a number of created, but not yet started, containers on standby, so that
+// starting a Cmd taken from it only needs to attach to and start its container, cutting the
+// latency of Start for latency-sensitive request/response execution.
+//
+// Standby containers are created from the Cmds returned by the template function given to
+// NewStandbyPool, which must all be configured identically, including Stdout and Stderr being set
+// or not. The Cmds are created with an open stdin, which is closed on Start if Stdin isn't set.
+// Policy is applied when a container is created, while ImageGate, Limiter and Quota are applied on
+// Start. The LabelDeadline label isn't set on standby containers.
+type StandbyPool struct {
+	template func() *Cmd
+	standby  chan *Cmd
+	tokens   chan struct{} // one per missing standby container
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// NewStandbyPool returns a StandbyPool keeping size containers created from template on standby.
+// It starts creating them in the background.
+func NewStandbyPool(size int, template func() *Cmd) *StandbyPool {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &StandbyPool{
+		template: template,
+		standby:  make(chan *Cmd, size),
+		tokens:   make(chan struct{}, size),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+	for i := 0; i < size; i++ {
+		p.tokens <- struct{}{}
+	}
+
+	p.wg.Add(1)
+	go p.fill()
+	return p
+}
+
+// fill creates a standby container for each token.
+func (p *StandbyPool) fill() {
+	defer p.wg.Done()
+
+	for {
+		select {
+		case <-p.tokens:
+		case <-p.ctx.Done():
+			return
+		}
+
+		for {
+			cmd, err := p.create()
+			if err == nil {
+				p.standby <- cmd
+				break
+			}
+
+			select {
+			case <-time.After(standbyRetryInterval):
+			case <-p.ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+func (p *StandbyPool) create() (*Cmd, error) {
+	cmd := p.template()
+	cmd.standby = true
+	if err := cmd.ensureClient(); err != nil {
+		return nil, err
+	}
+	if err := cmd.prepare(); err != nil {
+		return nil, err
+	}
+	cont, err := cmd.create(p.ctx)
+	if err != nil {
+		return nil, err
+	}
+	cmd.standby = false
+	cmd.standbyID = cont.ID
+	return cmd, nil
+}
+
+// Get returns a Cmd whose container is on standby, and starts creating a replacement in the
+// background. If none is available, it returns a new Cmd from the template, which creates its
+// container on Start as usual.
+//
+// The container of the returned Cmd already exists, so a Cmd that ends up not being started, or
+// fails to start, must be passed to Discard, or its container might be left behind.
+func (p *StandbyPool) Get() *Cmd {
+	select {
+	case cmd := <-p.standby:
+		p.tokens <- struct{}{}
+		return cmd
+	default:
+		return p.template()
+	}
+}
+
+// Discard removes the standby container of cmd, returned by Get, if it wasn't started. It does
+// nothing otherwise.
+func (p *StandbyPool) Discard(cmd *Cmd) error {
+	if cmd.standbyID == "" || len(cmd.ContainerID) != 0 {
+		return nil
+	}
+	err := cmd.cli.ContainerRemove(context.Background(), cmd.standbyID, container.RemoveOptions{
+		RemoveVolumes: true,
+		Force:         true,
+	})
+	cmd.standbyID = ""
+	if errdefs.IsNotFound(err) {
+		return nil
+	}
+	return wrapError("remove container", err)
+}
+
+// Ready returns the number of containers currently on standby.
+func (p *StandbyPool) Ready() int {
+	return len(p.standby)
+}
+
+// Close stops creating standby containers and removes those on standby.
+func (p *StandbyPool) Close() error {
+	p.cancel()
+	p.wg.Wait()
+
+	var errs []error
+	for {
+		select {
+		case cmd := <-p.standby:
+			err := cmd.cli.ContainerRemove(context.Background(), cmd.standbyID, container.RemoveOptions{
+				RemoveVolumes: true,
+				Force:         true,
+			})
+			if err != nil {
+				errs = append(errs, wrapError("remove container", err))
+			}
+		default:
+			return errors.Join(errs...)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package dockerexec_test
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+// traceCreated reports whether trace recorded creating a container.
+func traceCreated(trace *dockerexec.Trace) bool {
+	for _, call := range trace.Calls() {
+		if call.Method == "ContainerCreate" {
+			return true
+		}
+	}
+	return false
+}
+
+func TestStandbyPool(t *testing.T) {
+	pool := dockerexec.NewStandbyPool(2, func() *dockerexec.Cmd {
+		return dockerexec.Command(dockerClient, testImage, "cat")
+	})
+	defer func() {
+		assert.NoError(t, pool.Close())
+	}()
+
+	require.Eventually(t, func() bool {
+		return pool.Ready() == 2
+	}, 30*time.Second, 50*time.Millisecond)
+
+	cmd := pool.Get()
+	var trace dockerexec.Trace
+	cmd.Trace = &trace
+	cmd.Stdin = strings.NewReader("Hello, World!\n")
+	output, err := cmd.Output()
+	require.NoError(t, err)
+	assert.Equal(t, "Hello, World!\n", string(output))
+	assert.False(t, traceCreated(&trace), "should have used a standby container:\n%s", &trace)
+
+	// Without Stdin, the program sees EOF.
+	cmd = pool.Get()
+	trace = dockerexec.Trace{}
+	cmd.Trace = &trace
+	output, err = cmd.Output()
+	require.NoError(t, err)
+	assert.Empty(t, output)
+	assert.False(t, traceCreated(&trace), "should have used a standby container:\n%s", &trace)
+}
+
+func TestStandbyPoolDiscard(t *testing.T) {
+	var n atomic.Int32
+	pool := dockerexec.NewStandbyPool(1, func() *dockerexec.Cmd {
+		cmd := dockerexec.Command(dockerClient, testImage, "true")
+		cmd.Config.Labels = map[string]string{"dockerexec.test.standby": strconv.Itoa(int(n.Add(1)))}
+		return cmd
+	})
+	defer func() {
+		assert.NoError(t, pool.Close())
+	}()
+
+	require.Eventually(t, func() bool {
+		return pool.Ready() == 1
+	}, 30*time.Second, 50*time.Millisecond)
+
+	cmd := pool.Get()
+	label := "dockerexec.test.standby=" + cmd.Config.Labels["dockerexec.test.standby"]
+	require.NoError(t, pool.Discard(cmd))
+
+	containers, err := dockerClient.ContainerList(context.Background(), container.ListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("label", label)),
+	})
+	require.NoError(t, err)
+	assert.Empty(t, containers, "container should have been removed")
+}
@@ -0,0 +1,255 @@
+package dockerexec
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// Stats is a normalized view of a container's stats, abstracting away the differences between
+// cgroup v1, cgroup v2 and Windows in the raw stats returned by the daemon.
+type Stats struct {
+	// Read is the time the stats were read by the daemon.
+	Read time.Time
+
+	// CPUPercent is the CPU usage since the previous sample as a percentage of a single CPU, so it
+	// can exceed 100 on multi-core hosts, like in "docker stats".
+	CPUPercent float64
+
+	// CPUTime is the total CPU time consumed by the container.
+	CPUTime time.Duration
+
+	// MemoryUsage is the raw memory usage of the container, which includes the page cache.
+	MemoryUsage uint64
+
+	// MemoryWorkingSet is the memory usage of the container excluding inactive page cache, which
+	// is what "docker stats" reports as usage and what the OOM killer cares about.
+	MemoryWorkingSet uint64
+
+	// MemoryLimit is the memory limit of the container, or the total memory of the host if the
+	// container has no limit. Not available on Windows.
+	MemoryLimit uint64
+
+	// MemoryPercent is MemoryWorkingSet as a percentage of MemoryLimit.
+	MemoryPercent float64
+
+	// ThrottledPeriods is the number of periods in which the container was throttled for
+	// exceeding its CPU quota, and ThrottledTime is the total time it was throttled for. Not
+	// available on Windows.
+	ThrottledPeriods uint64
+	ThrottledTime    time.Duration
+
+	// PIDs is the number of processes or threads in the container. Not available on Windows.
+	PIDs uint64
+
+	// BlockReadBytes and BlockWriteBytes are the total number of bytes read from and written to
+	// block devices by the container.
+	BlockReadBytes  uint64
+	BlockWriteBytes uint64
+
+	// Networks contains the total amount of data transferred on each of the container's network
+	// interfaces, keyed by interface name.
+	Networks map[string]NetworkIO
+}
+
+// NetworkIO is the amount of data transferred on a network interface.
+type NetworkIO struct {
+	RxBytes uint64
+	TxBytes uint64
+}
+
+// NormalizeStats normalizes raw stats from ContainerStats, where osType is the OS type reported
+// by ContainerStats in container.StatsResponseReader.
+func NormalizeStats(osType string, raw *container.StatsResponse) Stats {
+	stats := Stats{
+		Read:             raw.Read,
+		ThrottledPeriods: raw.CPUStats.ThrottlingData.ThrottledPeriods,
+		ThrottledTime:    time.Duration(raw.CPUStats.ThrottlingData.ThrottledTime),
+		PIDs:             raw.PidsStats.Current,
+	}
+
+	if len(raw.Networks) > 0 {
+		stats.Networks = make(map[string]NetworkIO, len(raw.Networks))
+		for name, network := range raw.Networks {
+			stats.Networks[name] = NetworkIO{RxBytes: network.RxBytes, TxBytes: network.TxBytes}
+		}
+	}
+
+	if osType == "windows" {
+		// Windows reports CPU usage in units of 100ns.
+		stats.CPUTime = time.Duration(raw.CPUStats.CPUUsage.TotalUsage) * 100
+		stats.MemoryUsage = raw.MemoryStats.Commit
+		stats.MemoryWorkingSet = raw.MemoryStats.PrivateWorkingSet
+		stats.BlockReadBytes = raw.StorageStats.ReadSizeBytes
+		stats.BlockWriteBytes = raw.StorageStats.WriteSizeBytes
+
+		possIntervals := uint64(raw.Read.Sub(raw.PreRead).Nanoseconds()) / 100 * uint64(raw.NumProcs)
+		intervalsUsed := raw.CPUStats.CPUUsage.TotalUsage - raw.PreCPUStats.CPUUsage.TotalUsage
+		if possIntervals > 0 && raw.CPUStats.CPUUsage.TotalUsage > raw.PreCPUStats.CPUUsage.TotalUsage {
+			stats.CPUPercent = float64(intervalsUsed) / float64(possIntervals) * 100
+		}
+
+		return stats
+	}
+
+	stats.CPUTime = time.Duration(raw.CPUStats.CPUUsage.TotalUsage)
+	stats.MemoryUsage = raw.MemoryStats.Usage
+	stats.MemoryLimit = raw.MemoryStats.Limit
+
+	// cgroup v1 reports total_inactive_file (including descendant cgroups) while cgroup v2 only
+	// has inactive_file.
+	stats.MemoryWorkingSet = raw.MemoryStats.Usage
+	if v, ok := raw.MemoryStats.Stats["total_inactive_file"]; ok && v < raw.MemoryStats.Usage {
+		stats.MemoryWorkingSet = raw.MemoryStats.Usage - v
+	} else if v, ok := raw.MemoryStats.Stats["inactive_file"]; ok && v < raw.MemoryStats.Usage {
+		stats.MemoryWorkingSet = raw.MemoryStats.Usage - v
+	}
+	if stats.MemoryLimit > 0 {
+		stats.MemoryPercent = float64(stats.MemoryWorkingSet) / float64(stats.MemoryLimit) * 100
+	}
+
+	// cgroup v1 reports capitalized ops while cgroup v2 reports them in lowercase.
+	for _, entry := range raw.BlkioStats.IoServiceBytesRecursive {
+		switch strings.ToLower(entry.Op) {
+		case "read":
+			stats.BlockReadBytes += entry.Value
+		case "write":
+			stats.BlockWriteBytes += entry.Value
+		}
+	}
+
+	// cgroup v2 doesn't report per-CPU usage, so fall back to OnlineCPUs, which older daemons don't
+	// report.
+	onlineCPUs := float64(raw.CPUStats.OnlineCPUs)
+	if onlineCPUs == 0 {
+		onlineCPUs = float64(len(raw.CPUStats.CPUUsage.PercpuUsage))
+	}
+	cpuDelta := float64(raw.CPUStats.CPUUsage.TotalUsage) - float64(raw.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(raw.CPUStats.SystemUsage) - float64(raw.PreCPUStats.SystemUsage)
+	if cpuDelta > 0 && systemDelta > 0 {
+		stats.CPUPercent = cpuDelta / systemDelta * onlineCPUs * 100
+	}
+
+	return stats
+}
+
+// Stats returns a single sample of the container's stats.
+//
+// The container must have been started by Start.
+func (c *Cmd) Stats() (Stats, error) {
+	if len(c.ContainerID) == 0 {
+		return Stats{}, errors.New("dockerexec: not started")
+	}
+
+	// Not using ContainerStatsOneShot as it doesn't include the previous CPU sample needed to
+	// calculate CPUPercent.
+	resp, err := containerStats(c.context(), c.cli, c.ContainerID, false)
+	if err != nil {
+		return Stats{}, wrapError("container stats", err)
+	}
+	defer resp.Body.Close()
+
+	var raw container.StatsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return Stats{}, wrapError("decode container stats", err)
+	}
+	return NormalizeStats(resp.OSType, &raw), nil
+}
+
+// ResourceUsage summarizes the resources used by a container over its run, as sampled from
+// ContainerStats.
+//
+// The daemon samples stats about once a second, so the usage of very short runs may be partial or
+// missing entirely.
+type ResourceUsage struct {
+	// PeakMemory is the peak memory working set of the container in bytes, see
+	// Stats.MemoryWorkingSet.
+	PeakMemory uint64
+
+	// CPUTime is the total CPU time consumed by the container.
+	CPUTime time.Duration
+
+	// NetworkRxBytes and NetworkTxBytes are the total number of bytes received and transmitted on
+	// all of the container's network interfaces.
+	NetworkRxBytes uint64
+	NetworkTxBytes uint64
+
+	// Networks contains the amount of data transferred on each of the container's network
+	// interfaces, keyed by interface name.
+	Networks map[string]NetworkIO
+
+	// BlockReadBytes and BlockWriteBytes are the total number of bytes read from and written to
+	// block devices by the container.
+	BlockReadBytes  uint64
+	BlockWriteBytes uint64
+}
+
+func (u *ResourceUsage) update(osType string, raw *container.StatsResponse) {
+	stats := NormalizeStats(osType, raw)
+
+	u.PeakMemory = max(u.PeakMemory, stats.MemoryWorkingSet)
+
+	// CPU usage is cumulative since the container started.
+	u.CPUTime = max(u.CPUTime, stats.CPUTime)
+
+	// So are the I/O counters, but an interface might disappear before the container exits.
+	u.BlockReadBytes = max(u.BlockReadBytes, stats.BlockReadBytes)
+	u.BlockWriteBytes = max(u.BlockWriteBytes, stats.BlockWriteBytes)
+
+	if u.Networks == nil && len(stats.Networks) > 0 {
+		u.Networks = make(map[string]NetworkIO, len(stats.Networks))
+	}
+	for name, network := range stats.Networks {
+		prev := u.Networks[name]
+		u.Networks[name] = NetworkIO{
+			RxBytes: max(prev.RxBytes, network.RxBytes),
+			TxBytes: max(prev.TxBytes, network.TxBytes),
+		}
+	}
+
+	u.NetworkRxBytes, u.NetworkTxBytes = 0, 0
+	for _, network := range u.Networks {
+		u.NetworkRxBytes += network.RxBytes
+		u.NetworkTxBytes += network.TxBytes
+	}
+}
+
+// collectUsage starts collecting the resource usage of the container into c.Usage, until
+// stopCollectingUsage is called.
+func (c *Cmd) collectUsage(id string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c.usageCancel = cancel
+	c.usageDone = make(chan struct{})
+	c.Usage = &ResourceUsage{}
+
+	go func() {
+		defer close(c.usageDone)
+
+		stats, err := containerStats(ctx, c.cli, id, true)
+		if err != nil {
+			return
+		}
+		defer stats.Body.Close()
+
+		dec := json.NewDecoder(stats.Body)
+		for {
+			var s container.StatsResponse
+			if err := dec.Decode(&s); err != nil {
+				return
+			}
+			c.Usage.update(stats.OSType, &s)
+		}
+	}()
+}
+
+func (c *Cmd) stopCollectingUsage() {
+	if c.usageCancel == nil {
+		return
+	}
+	c.usageCancel()
+	<-c.usageDone
+}
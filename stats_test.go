@@ -0,0 +1,130 @@
+package dockerexec_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestCollectUsage(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "sh", "-c", "head -c 100000000 /dev/urandom | md5sum; sleep 3")
+	cmd.CollectUsage = true
+
+	err := cmd.Run()
+	require.NoError(t, err)
+
+	require.NotNil(t, cmd.Usage)
+	assert.NotZero(t, cmd.Usage.PeakMemory)
+	assert.NotZero(t, cmd.Usage.CPUTime)
+	assert.Contains(t, cmd.Usage.Networks, "eth0")
+}
+
+func TestNoCollectUsage(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "true")
+
+	err := cmd.Run()
+	require.NoError(t, err)
+	assert.Nil(t, cmd.Usage)
+}
+
+func TestNormalizeStatsCgroupV1(t *testing.T) {
+	raw := &container.StatsResponse{
+		Stats: container.Stats{
+			CPUStats: container.CPUStats{
+				CPUUsage:    container.CPUUsage{TotalUsage: 3000, PercpuUsage: []uint64{1000, 2000}},
+				SystemUsage: 20000,
+			},
+			PreCPUStats: container.CPUStats{
+				CPUUsage:    container.CPUUsage{TotalUsage: 2000},
+				SystemUsage: 10000,
+			},
+			MemoryStats: container.MemoryStats{
+				Usage: 1000,
+				Limit: 4000,
+				Stats: map[string]uint64{"total_inactive_file": 200, "inactive_file": 100},
+			},
+		},
+	}
+
+	raw.BlkioStats.IoServiceBytesRecursive = []container.BlkioStatEntry{
+		{Major: 8, Minor: 0, Op: "Read", Value: 100},
+		{Major: 8, Minor: 0, Op: "Write", Value: 200},
+		{Major: 8, Minor: 0, Op: "Total", Value: 300},
+		{Major: 8, Minor: 16, Op: "Read", Value: 10},
+	}
+	raw.Networks = map[string]container.NetworkStats{
+		"eth0": {RxBytes: 1, TxBytes: 2},
+	}
+
+	stats := dockerexec.NormalizeStats("linux", raw)
+	assert.Equal(t, uint64(110), stats.BlockReadBytes)
+	assert.Equal(t, uint64(200), stats.BlockWriteBytes)
+	assert.Equal(t, map[string]dockerexec.NetworkIO{"eth0": {RxBytes: 1, TxBytes: 2}}, stats.Networks)
+	assert.Equal(t, 3000*time.Nanosecond, stats.CPUTime)
+	assert.InDelta(t, 20.0, stats.CPUPercent, 0.001)
+	assert.Equal(t, uint64(1000), stats.MemoryUsage)
+	assert.Equal(t, uint64(800), stats.MemoryWorkingSet)
+	assert.InDelta(t, 20.0, stats.MemoryPercent, 0.001)
+}
+
+func TestNormalizeStatsCgroupV2(t *testing.T) {
+	raw := &container.StatsResponse{
+		Stats: container.Stats{
+			CPUStats: container.CPUStats{
+				CPUUsage:    container.CPUUsage{TotalUsage: 3000},
+				SystemUsage: 20000,
+				OnlineCPUs:  4,
+				ThrottlingData: container.ThrottlingData{
+					ThrottledPeriods: 5,
+					ThrottledTime:    1000,
+				},
+			},
+			PreCPUStats: container.CPUStats{
+				CPUUsage:    container.CPUUsage{TotalUsage: 2000},
+				SystemUsage: 10000,
+			},
+			MemoryStats: container.MemoryStats{
+				Usage: 1000,
+				Limit: 4000,
+				Stats: map[string]uint64{"inactive_file": 100},
+			},
+		},
+	}
+
+	raw.BlkioStats.IoServiceBytesRecursive = []container.BlkioStatEntry{
+		{Major: 8, Minor: 0, Op: "read", Value: 100},
+		{Major: 8, Minor: 0, Op: "write", Value: 200},
+	}
+
+	stats := dockerexec.NormalizeStats("linux", raw)
+	assert.Equal(t, uint64(100), stats.BlockReadBytes)
+	assert.Equal(t, uint64(200), stats.BlockWriteBytes)
+	assert.InDelta(t, 40.0, stats.CPUPercent, 0.001)
+	assert.Equal(t, uint64(900), stats.MemoryWorkingSet)
+	assert.Equal(t, uint64(5), stats.ThrottledPeriods)
+	assert.Equal(t, 1000*time.Nanosecond, stats.ThrottledTime)
+}
+
+func TestCmdStats(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "sleep", "120")
+
+	_, err := cmd.Stats()
+	assert.EqualError(t, err, "dockerexec: not started")
+
+	err = cmd.Start()
+	require.NoError(t, err)
+	defer func() {
+		_ = cmd.Kill("SIGKILL")
+		_ = cmd.Wait()
+	}()
+
+	stats, err := cmd.Stats()
+	require.NoError(t, err)
+	assert.NotZero(t, stats.MemoryWorkingSet)
+	assert.NotZero(t, stats.PIDs)
+}
@@ -0,0 +1,148 @@
+package dockerexec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// StepConfig configures a CI-style step, see NewStep.
+type StepConfig struct {
+	// Name is the name of the step, used as the container name if set.
+	Name string
+
+	// Image and Command are the image of the step, and the command it runs in it.
+	Image   string
+	Command []string
+
+	// Env contains the environment variables of the step.
+	Env map[string]string
+
+	// Workspace, if set, is a host directory mounted as the working directory of the step, see
+	// Cmd.MountWorkspace.
+	Workspace string
+
+	// Timeout, if non-zero, bounds the runtime of the step, see Cmd.MaxRuntime.
+	Timeout time.Duration
+
+	// Log, if set, receives the output of the step as it runs, with each line prefixed by its
+	// timestamp and stream, e.g. "2024-01-02T15:04:05.000Z stdout hello".
+	Log io.Writer
+}
+
+// Step is a CI-style step running a command in a container, capturing its output line by line
+// with timestamps, and reporting a structured StepResult.
+type Step struct {
+	// Cmd is the Cmd of the step, which may be further configured before calling Run, except for
+	// its Stdout and Stderr.
+	Cmd *Cmd
+
+	name string
+	sink *stepSink
+}
+
+// StepResult is the result of running a Step.
+type StepResult struct {
+	Name        string
+	ContainerID string
+
+	// StatusCode is the status code of the step, or -1 if it didn't exit.
+	StatusCode int64
+
+	// Err is the error the step failed with, if any, as returned by Cmd.Run.
+	Err error
+
+	// TimedOut is set if the step was stopped for exceeding its Timeout.
+	TimedOut bool
+
+	Started  time.Time
+	Finished time.Time
+	Duration time.Duration
+
+	// Log contains the lines of output of the step, in order.
+	Log []LogRecord
+}
+
+// Success reports whether the step succeeded.
+func (r *StepResult) Success() bool {
+	return r.Err == nil
+}
+
+// NewStep returns a Step configured per config.
+func NewStep(ctx context.Context, cli Client, config StepConfig) (*Step, error) {
+	if len(config.Command) == 0 {
+		return nil, errors.New("dockerexec: no command specified")
+	}
+
+	cmd := CommandContext(ctx, cli, config.Image, config.Command[0], config.Command[1:]...)
+	if cmd.Err != nil {
+		return nil, cmd.Err
+	}
+	cmd.ContainerName = config.Name
+	cmd.MaxRuntime = config.Timeout
+
+	keys := make([]string, 0, len(config.Env))
+	for key := range config.Env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		cmd.Config.Env = append(cmd.Config.Env, key+"="+config.Env[key])
+	}
+
+	if config.Workspace != "" {
+		if err := cmd.MountWorkspace(config.Workspace); err != nil {
+			return nil, err
+		}
+	}
+
+	sink := &stepSink{log: config.Log}
+	if err := cmd.ForwardOutput(sink); err != nil {
+		return nil, err
+	}
+
+	return &Step{Cmd: cmd, name: config.Name, sink: sink}, nil
+}
+
+// Run runs the step and returns its result.
+func (s *Step) Run() *StepResult {
+	started := time.Now()
+	err := s.Cmd.Run()
+	finished := time.Now()
+
+	return &StepResult{
+		Name:        s.name,
+		ContainerID: s.Cmd.ContainerID,
+		StatusCode:  s.Cmd.StatusCode,
+		Err:         err,
+		TimedOut:    errors.Is(err, ErrMaxRuntimeExceeded),
+		Started:     started,
+		Finished:    finished,
+		Duration:    finished.Sub(started),
+		Log:         s.sink.records,
+	}
+}
+
+// stepSink is the LogSink of a Step, collecting its records and writing them to its log.
+type stepSink struct {
+	log io.Writer
+
+	mu      sync.Mutex
+	records []LogRecord
+}
+
+func (s *stepSink) Send(record LogRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records = append(s.records, record)
+	if s.log != nil {
+		_, err := fmt.Fprintf(s.log, "%s %s %s\n", record.Time.UTC().Format("2006-01-02T15:04:05.000Z"), record.Stream, record.Line)
+		return err
+	}
+	return nil
+}
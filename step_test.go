@@ -0,0 +1,59 @@
+package dockerexec_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestStep(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "input.txt"), []byte("hello\n"), 0o644))
+
+	var log bytes.Buffer
+	step, err := dockerexec.NewStep(context.Background(), dockerClient, dockerexec.StepConfig{
+		Image:     testImage,
+		Command:   []string{"sh", "-c", "cat input.txt; echo $GREETING >&2; exit 2"},
+		Env:       map[string]string{"GREETING": "hi"},
+		Workspace: dir,
+		Log:       &log,
+	})
+	require.NoError(t, err)
+
+	result := step.Run()
+	assert.False(t, result.Success())
+	assert.EqualValues(t, 2, result.StatusCode)
+	assert.False(t, result.TimedOut)
+	assert.NotEmpty(t, result.ContainerID)
+	require.Len(t, result.Log, 2)
+
+	lines := map[string]dockerexec.Stream{}
+	for _, record := range result.Log {
+		lines[record.Line] = record.Stream
+	}
+	assert.Equal(t, map[string]dockerexec.Stream{"hello": dockerexec.StreamStdout, "hi": dockerexec.StreamStderr}, lines)
+	assert.Regexp(t, regexp.MustCompile(`(?m)^\d{4}-\d\d-\d\dT\d\d:\d\d:\d\d\.\d{3}Z stdout hello$`), log.String())
+}
+
+func TestStepTimeout(t *testing.T) {
+	step, err := dockerexec.NewStep(context.Background(), dockerClient, dockerexec.StepConfig{
+		Image:   testImage,
+		Command: []string{"sleep", "10"},
+		Timeout: 500 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	step.Cmd.Config.StopTimeout = new(int)
+
+	result := step.Run()
+	assert.True(t, result.TimedOut)
+	assert.Less(t, result.Duration, 10*time.Second)
+}
@@ -0,0 +1,81 @@
+package dockerexec
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// SuperviseOptions configures Supervise.
+type SuperviseOptions struct {
+	// MaxRestarts is the maximum number of times the command is restarted. Negative means
+	// restarting indefinitely.
+	MaxRestarts int
+
+	// Backoff is the delay before the first restart, doubled after each restart up to MaxBackoff.
+	// Defaults to one second.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+
+	// OnAttempt, if set, is called after each attempt completes.
+	OnAttempt func(Attempt)
+}
+
+// Attempt describes an attempt of Supervise at running the command.
+type Attempt struct {
+	// Number is the number of the attempt, starting from 1.
+	Number int
+
+	// Cmd is the Cmd of the attempt.
+	Cmd *Cmd
+
+	// Err is the error returned by Run.
+	Err error
+
+	Started  time.Time
+	Finished time.Time
+}
+
+// Supervise runs the Cmd returned by template, and restarts it, re-creating its container with a
+// new Cmd from template, with backoff when it exits with a non-zero status, up to
+// opts.MaxRestarts times. It's a minimal process supervisor for containerized workers.
+//
+// Supervise returns nil once an attempt exits successfully, or the error of the last attempt once
+// it gives up. Errors other than an *ExitError, such as failing to start the container, are
+// returned immediately without restarting. The template should use ctx, such as by using
+// CommandContext, for ctx to also stop a running attempt.
+func Supervise(ctx context.Context, template func() *Cmd, opts SuperviseOptions) error {
+	backoff := opts.Backoff
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+
+	for number := 1; ; number++ {
+		cmd := template()
+		attempt := Attempt{Number: number, Cmd: cmd, Started: time.Now()}
+		attempt.Err = cmd.Run()
+		attempt.Finished = time.Now()
+		if opts.OnAttempt != nil {
+			opts.OnAttempt(attempt)
+		}
+
+		var ee *ExitError
+		if !errors.As(attempt.Err, &ee) {
+			return attempt.Err
+		}
+		if opts.MaxRestarts >= 0 && number > opts.MaxRestarts {
+			return attempt.Err
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		backoff *= 2
+		if opts.MaxBackoff > 0 && backoff > opts.MaxBackoff {
+			backoff = opts.MaxBackoff
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package dockerexec_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestSupervise(t *testing.T) {
+	var attempts []dockerexec.Attempt
+	err := dockerexec.Supervise(context.Background(), func() *dockerexec.Cmd {
+		return dockerexec.Command(dockerClient, testImage, "sh", "-c", "exit 3")
+	}, dockerexec.SuperviseOptions{
+		MaxRestarts: 2,
+		Backoff:     10 * time.Millisecond,
+		OnAttempt: func(attempt dockerexec.Attempt) {
+			attempts = append(attempts, attempt)
+		},
+	})
+
+	var exitErr *dockerexec.ExitError
+	require.ErrorAs(t, err, &exitErr)
+	assert.EqualValues(t, 3, exitErr.StatusCode)
+	require.Len(t, attempts, 3)
+	assert.Equal(t, 3, attempts[2].Number)
+	assert.NotEqual(t, attempts[0].Cmd.ContainerID, attempts[1].Cmd.ContainerID)
+
+	attempts = nil
+	err = dockerexec.Supervise(context.Background(), func() *dockerexec.Cmd {
+		return dockerexec.Command(dockerClient, testImage, "true")
+	}, dockerexec.SuperviseOptions{
+		OnAttempt: func(attempt dockerexec.Attempt) {
+			attempts = append(attempts, attempt)
+		},
+	})
+	require.NoError(t, err)
+	assert.Len(t, attempts, 1)
+}
@@ -0,0 +1,322 @@
+package dockerexec
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// swarmPollInterval is the interval in which SwarmCmd polls the state of its task.
+const swarmPollInterval = 500 * time.Millisecond
+
+// SwarmCmd represents a command being prepared or run as a one-shot Swarm job, a replicated job
+// service with a single task, which allows targeting a Swarm cluster rather than a single daemon.
+//
+// Its interface mirrors that of Cmd, with the notable differences that standard input isn't
+// supported, and that output is only copied to Stdout and Stderr once the task completes, as it's
+// retrieved from the service logs. Hence, Start fails with a *LogDriverError if Stdout or Stderr
+// is set while TaskTemplate.LogDriver doesn't support reading logs back.
+//
+// A SwarmCmd cannot be reused after calling its Run, Output or CombinedOutput methods.
+type SwarmCmd struct {
+	// The specification of the service to be created.
+	//
+	// Some properties are handled specially:
+	//     * Mode defaults to a replicated job with a single task.
+	//     * TaskTemplate.RestartPolicy defaults to never restarting.
+	Spec *swarm.ServiceSpec
+
+	// Stdout and Stderr specify the task's standard output and error.
+	//
+	// If either is nil, the corresponding output will be discarded.
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// ServiceID is the ID of the service, once started.
+	ServiceID string
+
+	// Warnings contains any warnings from creating the service, with the WarningDaemon code.
+	//
+	// You should consider logging these, or set OnWarning.
+	Warnings []Warning
+
+	// OnWarning, if set, is called with each warning as it's added to Warnings, e.g. to route them
+	// to structured logs.
+	OnWarning func(Warning)
+
+	// StatusCode contains the status code of the task, available after a call to Wait or Run.
+	StatusCode int64
+
+	ctx      context.Context // nil means None
+	cli      client.APIClient
+	finished bool // when Wait was called
+}
+
+// SwarmCommand returns the SwarmCmd struct to execute the named program inside the given image
+// with the given arguments as a Swarm job.
+func SwarmCommand(cli client.APIClient, image string, name string, arg ...string) *SwarmCmd {
+	one := uint64(1)
+
+	return &SwarmCmd{
+		Spec: &swarm.ServiceSpec{
+			Annotations: swarm.Annotations{
+				Labels: map[string]string{
+					LabelManaged: "true",
+					LabelOwner:   defaultOwner,
+				},
+			},
+			TaskTemplate: swarm.TaskSpec{
+				ContainerSpec: &swarm.ContainerSpec{
+					Image:   image,
+					Command: append([]string{name}, arg...),
+				},
+				RestartPolicy: &swarm.RestartPolicy{
+					Condition: swarm.RestartPolicyConditionNone,
+				},
+			},
+			Mode: swarm.ServiceMode{
+				ReplicatedJob: &swarm.ReplicatedJob{
+					MaxConcurrent:    &one,
+					TotalCompletions: &one,
+				},
+			},
+		},
+
+		StatusCode: -1,
+
+		cli: cli,
+	}
+}
+
+// SwarmCommandContext is like SwarmCommand but includes a context.
+//
+// The provided context is used to remove the service, and thereby stop its task, if the context
+// becomes done before the task completes on its own.
+func SwarmCommandContext(ctx context.Context, cli client.APIClient, image string, name string, arg ...string) *SwarmCmd {
+	if ctx == nil {
+		panic("nil Context")
+	}
+	cmd := SwarmCommand(cli, image, name, arg...)
+	cmd.ctx = ctx
+	return cmd
+}
+
+// String returns a human-readable description of c, with arguments quoted by ShellQuote.
+// It is intended only for debugging.
+func (c *SwarmCmd) String() string {
+	return ShellJoin(c.Spec.TaskTemplate.ContainerSpec.Command...)
+}
+
+// context returns the context of c, or context.Background if there is none.
+func (c *SwarmCmd) context() context.Context {
+	if c.ctx != nil {
+		return c.ctx
+	}
+	return context.Background()
+}
+
+// Run starts the job and waits for it to complete.
+//
+// If the task starts but does not complete successfully, the error is of type *ExitError. Other
+// error types may be returned for other situations.
+func (c *SwarmCmd) Run() error {
+	if err := c.Start(); err != nil {
+		return err
+	}
+	return c.Wait()
+}
+
+// Start creates the service of the job but does not wait for it to complete.
+//
+// If Start returns successfully, the c.ServiceID field will be set.
+func (c *SwarmCmd) Start() error {
+	if len(c.ServiceID) != 0 {
+		return errors.New("dockerexec: already started")
+	}
+	if err := c.checkLogsReadable(); err != nil {
+		return err
+	}
+
+	resp, err := c.cli.ServiceCreate(c.context(), *c.Spec, types.ServiceCreateOptions{})
+	if err != nil {
+		return wrapError("create service", err)
+	}
+
+	c.ServiceID = resp.ID
+	for _, message := range resp.Warnings {
+		c.warn(Warning{Code: WarningDaemon, Message: message})
+	}
+	return nil
+}
+
+// warn records w in Warnings and delivers it to OnWarning.
+func (c *SwarmCmd) warn(w Warning) {
+	c.Warnings = append(c.Warnings, w)
+	if c.OnWarning != nil {
+		c.OnWarning(w)
+	}
+}
+
+// Wait waits for the task of the job to complete, copies its output to Stdout and Stderr, and
+// removes the service.
+//
+// The returned error is nil if the task exits with a zero exit status. If the task fails to run
+// or doesn't complete successfully, the error is of type *ExitError, or describes why the task
+// failed if it never ran.
+func (c *SwarmCmd) Wait() error {
+	if len(c.ServiceID) == 0 {
+		return errors.New("dockerexec: not started")
+	}
+	if c.finished {
+		return errors.New("dockerexec: Wait was already called")
+	}
+	c.finished = true
+
+	defer func() {
+		_ = c.cli.ServiceRemove(context.Background(), c.ServiceID)
+	}()
+
+	task, err := c.waitTask()
+	if err != nil {
+		return err
+	}
+
+	copyErr := c.copyLogs()
+
+	if task.Status.ContainerStatus == nil {
+		return fmt.Errorf("dockerexec: task %s: %s", task.Status.State, task.Status.Err)
+	}
+
+	c.StatusCode = int64(task.Status.ContainerStatus.ExitCode)
+	if c.StatusCode != 0 {
+		return &ExitError{StatusCode: c.StatusCode}
+	}
+	return copyErr
+}
+
+// waitTask polls the task of the job until it reaches a terminal state.
+func (c *SwarmCmd) waitTask() (swarm.Task, error) {
+	ctx := c.context()
+
+	ticker := time.NewTicker(swarmPollInterval)
+	defer ticker.Stop()
+
+	for {
+		tasks, err := c.cli.TaskList(ctx, types.TaskListOptions{
+			Filters: filters.NewArgs(filters.Arg("service", c.ServiceID)),
+		})
+		if err != nil {
+			return swarm.Task{}, wrapError("list tasks", err)
+		}
+
+		for _, task := range tasks {
+			switch task.Status.State {
+			case swarm.TaskStateComplete, swarm.TaskStateFailed, swarm.TaskStateRejected,
+				swarm.TaskStateShutdown, swarm.TaskStateOrphaned:
+				return task, nil
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return swarm.Task{}, ctx.Err()
+		}
+	}
+}
+
+// checkLogsReadable returns a *LogDriverError if output is requested but the log driver of the
+// task doesn't support reading it back from the service logs.
+func (c *SwarmCmd) checkLogsReadable() error {
+	if c.Stdout == nil && c.Stderr == nil {
+		return nil
+	}
+	driver := c.Spec.TaskTemplate.LogDriver
+	if driver == nil {
+		return nil
+	}
+	return checkLogsReadable(container.LogConfig{Type: driver.Name, Config: driver.Options})
+}
+
+// copyLogs copies the logs of the service to Stdout and Stderr.
+func (c *SwarmCmd) copyLogs() error {
+	if c.Stdout == nil && c.Stderr == nil {
+		return nil
+	}
+
+	logs, err := c.cli.ServiceLogs(c.context(), c.ServiceID, container.LogsOptions{
+		ShowStdout: c.Stdout != nil,
+		ShowStderr: c.Stderr != nil,
+	})
+	if err != nil {
+		return wrapError("service logs", err)
+	}
+	defer logs.Close()
+
+	stdout := c.Stdout
+	if stdout == nil {
+		stdout = io.Discard
+	}
+	stderr := c.Stderr
+	if stderr == nil {
+		stderr = io.Discard
+	}
+
+	if c.Spec.TaskTemplate.ContainerSpec.TTY {
+		_, err = io.Copy(stdout, logs)
+	} else {
+		_, err = stdcopy.StdCopy(stdout, stderr, logs)
+	}
+	return wrapError("copy service logs", err)
+}
+
+// Output runs the job and returns its standard output.
+// Any returned error will usually be of type *ExitError.
+// If c.Stderr was nil, Output populates ExitError.Stderr.
+func (c *SwarmCmd) Output() ([]byte, error) {
+	if c.Stdout != nil {
+		return nil, errors.New("dockerexec: Stdout already set")
+	}
+	var stdout bytes.Buffer
+	c.Stdout = &stdout
+
+	captureErr := c.Stderr == nil && !c.Spec.TaskTemplate.ContainerSpec.TTY
+	if captureErr {
+		c.Stderr = &prefixSuffixSaver{N: 32 << 10}
+	}
+
+	err := c.Run()
+	if err != nil && captureErr {
+		if ee, ok := err.(*ExitError); ok {
+			ee.Stderr = c.Stderr.(*prefixSuffixSaver).Bytes()
+		}
+	}
+	return stdout.Bytes(), err
+}
+
+// CombinedOutput runs the job and returns its combined standard output and standard error.
+func (c *SwarmCmd) CombinedOutput() ([]byte, error) {
+	if c.Stdout != nil {
+		return nil, errors.New("dockerexec: Stdout already set")
+	}
+	if c.Stderr != nil {
+		return nil, errors.New("dockerexec: Stderr already set")
+	}
+	var b bytes.Buffer
+	c.Stdout = &b
+	if !c.Spec.TaskTemplate.ContainerSpec.TTY {
+		c.Stderr = &b
+	}
+	err := c.Run()
+	return b.Bytes(), err
+}
@@ -0,0 +1,58 @@
+package dockerexec_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestSwarmCommand(t *testing.T) {
+	info, err := dockerClient.Info(context.Background())
+	require.NoError(t, err)
+	if info.Swarm.LocalNodeState != swarm.LocalNodeStateActive || !info.Swarm.ControlAvailable {
+		t.Skip("not a swarm manager")
+	}
+
+	cmd := dockerexec.SwarmCommand(dockerClient, testImage, "sh", "-c", "echo Hello, World!")
+	output, err := cmd.Output()
+	require.NoError(t, err)
+	assert.Equal(t, "Hello, World!\n", string(output))
+	assert.EqualValues(t, 0, cmd.StatusCode)
+
+	cmd = dockerexec.SwarmCommand(dockerClient, testImage, "sh", "-c", "exit 3")
+	err = cmd.Run()
+	var exitErr *dockerexec.ExitError
+	require.ErrorAs(t, err, &exitErr)
+	assert.EqualValues(t, 3, exitErr.StatusCode)
+}
+
+func TestSwarmCommandWarnings(t *testing.T) {
+	info, err := dockerClient.Info(context.Background())
+	require.NoError(t, err)
+	if info.Swarm.LocalNodeState != swarm.LocalNodeStateActive || !info.Swarm.ControlAvailable {
+		t.Skip("not a swarm manager")
+	}
+
+	var received []dockerexec.Warning
+
+	// The daemon warns that it can't resolve the digest of an image missing from the registry.
+	cmd := dockerexec.SwarmCommand(dockerClient, "segevfiner/no-exist-image:latest", "true")
+	cmd.OnWarning = func(w dockerexec.Warning) {
+		received = append(received, w)
+	}
+	require.NoError(t, cmd.Start())
+	defer func() {
+		assert.NoError(t, dockerClient.ServiceRemove(context.Background(), cmd.ServiceID))
+	}()
+
+	require.NotEmpty(t, cmd.Warnings)
+	assert.Equal(t, cmd.Warnings, received)
+	for _, w := range cmd.Warnings {
+		assert.Equal(t, dockerexec.WarningDaemon, w.Code)
+	}
+}
@@ -0,0 +1,112 @@
+package dockerexec
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Syslog severities used by SyslogSink.
+const (
+	syslogSeverityErr  = 3
+	syslogSeverityInfo = 6
+)
+
+// SyslogSink is a LogSink that forwards output to syslog using RFC 5424 messages. The metadata of
+// the record is included as structured data. Standard output is logged with the info severity,
+// and standard error with the err severity.
+//
+// A SyslogSink is safe for concurrent use. It connects on the first record, and reconnects once
+// if sending a record fails.
+type SyslogSink struct {
+	// Network and Addr are the address of the syslog server, e.g. "udp" and "localhost:514". If
+	// Network is empty, the local syslog server is used.
+	Network string
+	Addr    string
+
+	// Tag is the APP-NAME of the messages, defaults to the base name of the running
+	// executable.
+	Tag string
+
+	// Facility is the syslog facility of the messages, defaults to 1 (user).
+	Facility int
+
+	once     sync.Once
+	conn     sinkConn
+	hostname string
+}
+
+// Send sends record to syslog.
+func (s *SyslogSink) Send(record LogRecord) error {
+	s.once.Do(s.init)
+
+	severity := syslogSeverityInfo
+	if record.Stream == StreamStderr {
+		severity = syslogSeverityErr
+	}
+	facility := s.Facility
+	if facility == 0 {
+		facility = 1
+	}
+	tag := s.Tag
+	if tag == "" {
+		tag = defaultOwner
+	}
+
+	msg := fmt.Sprintf("<%d>1 %s %s %s - - [container@dockerexec id=\"%s\" name=\"%s\" image=\"%s\" stream=\"%s\"] %s",
+		facility*8+severity,
+		record.Time.Format(time.RFC3339Nano),
+		s.hostname,
+		tag,
+		syslogParam(record.ContainerID),
+		syslogParam(record.ContainerName),
+		syslogParam(record.Image),
+		record.Stream,
+		record.Line,
+	)
+	if s.Network != "udp" && s.Network != "unixgram" && s.Network != "" {
+		// Stream transports use newline framing.
+		msg += "\n"
+	}
+	return wrapError("syslog", s.conn.write([]byte(msg)))
+}
+
+// Close closes the connection to the syslog server.
+func (s *SyslogSink) Close() error {
+	return s.conn.close()
+}
+
+func (s *SyslogSink) init() {
+	s.hostname, _ = os.Hostname()
+	if s.hostname == "" {
+		s.hostname = "-"
+	}
+	s.conn.dial = s.dial
+}
+
+func (s *SyslogSink) dial() (io.WriteCloser, error) {
+	if s.Network != "" {
+		return net.Dial(s.Network, s.Addr)
+	}
+
+	var err error
+	for _, path := range []string{"/dev/log", "/var/run/syslog", "/var/run/log"} {
+		var conn net.Conn
+		conn, err = net.Dial("unixgram", path)
+		if err == nil {
+			return conn, nil
+		}
+	}
+	return nil, err
+}
+
+var syslogParamReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+// syslogParam escapes a structured data parameter value.
+func syslogParam(s string) string {
+	return syslogParamReplacer.Replace(s)
+}
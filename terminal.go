@@ -0,0 +1,229 @@
+package dockerexec
+
+import (
+	"errors"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/moby/term"
+)
+
+// DefaultDetachKeys is the default key sequence for detaching from a terminal, like in the docker
+// CLI.
+const DefaultDetachKeys = "ctrl-p,ctrl-q"
+
+// ErrDetached is returned by RunTerminal when the user detached from the container using the
+// detach keys.
+var ErrDetached = errors.New("dockerexec: detached")
+
+// TerminalOptions configures RunTerminal.
+type TerminalOptions struct {
+	// In, Out and Err are the terminal, defaulting to os.Stdin, os.Stdout and os.Stderr. Err is
+	// only used if In or Out isn't a terminal, in which case Config.Tty isn't set.
+	In  io.Reader
+	Out io.Writer
+	Err io.Writer
+
+	// DetachKeys is the key sequence for detaching from the container, in the format of the
+	// docker CLI, e.g. "ctrl-p,ctrl-q". Defaults to DefaultDetachKeys.
+	DetachKeys string
+
+	// NoSignalProxy disables forwarding signals received by the process, such as SIGTERM, to the
+	// container.
+	NoSignalProxy bool
+}
+
+// RunTerminal runs c attached to the terminal of the process, like "docker run -it" does. If the
+// terminal is a TTY, Config.Tty is set, the terminal is put in raw mode for the duration of the
+// run, and the terminal of the container is resized to follow it. Signals received by the process
+// are forwarded to the container, unless NoSignalProxy is set.
+//
+// RunTerminal returns the result of Wait once the container exits. If the user types the detach
+// keys, the Cmd is detached from the container using Detach, leaving it running, and RunTerminal
+// returns ErrDetached. The Cmd may then be re-attached to using Attach, and Wait must still be
+// called once done with the container. See Detach regarding Config.StdinOnce when the terminal
+// isn't a TTY.
+//
+// Stdin, Stdout and Stderr of c must be unset.
+func RunTerminal(c *Cmd, opts TerminalOptions) error {
+	if c.Stdin != nil || c.Stdout != nil || c.Stderr != nil {
+		return errors.New("dockerexec: RunTerminal with Stdin, Stdout or Stderr already set")
+	}
+
+	in, out, errOut := opts.In, opts.Out, opts.Err
+	if in == nil {
+		in = os.Stdin
+	}
+	if out == nil {
+		out = os.Stdout
+	}
+	if errOut == nil {
+		errOut = os.Stderr
+	}
+	detachKeys := opts.DetachKeys
+	if detachKeys == "" {
+		detachKeys = DefaultDetachKeys
+	}
+	keys, err := term.ToBytes(detachKeys)
+	if err != nil {
+		return err
+	}
+
+	inFd, inTerminal := term.GetFdInfo(in)
+	outFd, outTerminal := term.GetFdInfo(out)
+	tty := inTerminal && outTerminal
+	c.Config.Tty = tty
+	if tty {
+		if ws, err := term.GetWinsize(outFd); err == nil && ws.Height != 0 && ws.Width != 0 {
+			if c.HostConfig == nil {
+				c.HostConfig = &container.HostConfig{}
+			}
+			c.HostConfig.ConsoleSize = [2]uint{uint(ws.Height), uint(ws.Width)}
+		}
+	}
+
+	t := &terminal{
+		outputDone: make(chan struct{}),
+		detached:   make(chan struct{}),
+	}
+
+	// The container's stdin is fed through a pipe, so that it can be released once the container
+	// exits, even though reading the terminal blocks until the next key press.
+	pr, pw := io.Pipe()
+	c.Stdin = pr
+	c.Stdout = &detachableWriter{w: out, t: t}
+	if !tty {
+		c.Stderr = &detachableWriter{w: errOut, t: t}
+	}
+	c.closeAfterStdin = append(c.closeAfterStdin, pr)
+	c.closeAfterOutput = append(c.closeAfterOutput, pr, closerFunc(func() error {
+		t.outputOnce.Do(func() { close(t.outputDone) })
+		return nil
+	}))
+
+	if tty {
+		inState, err := term.SetRawTerminal(inFd)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = term.RestoreTerminal(inFd, inState) }()
+
+		if outState, err := term.SetRawTerminalOutput(outFd); err == nil && outState != nil {
+			defer func() { _ = term.RestoreTerminal(outFd, outState) }()
+		}
+	}
+
+	if err := c.Start(); err != nil {
+		return err
+	}
+	go t.copyInput(pw, term.NewEscapeProxy(in, keys))
+
+	stop := make(chan struct{})
+	defer close(stop)
+	if tty {
+		go t.followSize(c, outFd, stop)
+	}
+	if !opts.NoSignalProxy {
+		go t.proxySignals(c, stop)
+	}
+
+	select {
+	case <-t.outputDone:
+		return c.Wait()
+	case <-t.detached:
+		if err := c.Detach(); err != nil {
+			return err
+		}
+		return ErrDetached
+	}
+}
+
+// terminal is the state of RunTerminal.
+type terminal struct {
+	outputDone chan struct{} // closed once the output of the container ends
+	detached   chan struct{} // closed once the user detached
+
+	outputOnce sync.Once
+	detachOnce sync.Once
+	isDetached atomic.Bool
+}
+
+// copyInput copies the input of the terminal to w. On detach, it stops copying without closing w,
+// so the container doesn't see EOF.
+func (t *terminal) copyInput(w *io.PipeWriter, r io.Reader) {
+	_, err := io.Copy(w, r)
+	var escapeErr term.EscapeError
+	if errors.As(err, &escapeErr) {
+		t.detachOnce.Do(func() {
+			t.isDetached.Store(true)
+			close(t.detached)
+		})
+		return
+	}
+	w.CloseWithError(err)
+}
+
+// followSize resizes the terminal of the container whenever the terminal is resized.
+func (t *terminal) followSize(c *Cmd, fd uintptr, stop <-chan struct{}) {
+	resized := make(chan struct{}, 1)
+	stopNotify := notifyResize(fd, resized)
+	defer stopNotify()
+
+	for {
+		select {
+		case <-resized:
+			ws, err := term.GetWinsize(fd)
+			if err != nil || ws.Height == 0 || ws.Width == 0 {
+				continue
+			}
+			_ = c.cli.ContainerResize(c.context(), c.ContainerID, container.ResizeOptions{
+				Height: uint(ws.Height),
+				Width:  uint(ws.Width),
+			})
+		case <-stop:
+			return
+		}
+	}
+}
+
+// proxySignals forwards signals received by the process to the container.
+func (t *terminal) proxySignals(c *Cmd, stop <-chan struct{}) {
+	signals := make(chan os.Signal, 16)
+	for sig := range proxiedSignalNames {
+		signal.Notify(signals, sig)
+	}
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case sig := <-signals:
+			_ = c.Kill(proxiedSignalNames[sig])
+		case <-stop:
+			return
+		}
+	}
+}
+
+// detachableWriter writes to w until the terminal is detached, and discards writes afterwards.
+type detachableWriter struct {
+	w io.Writer
+	t *terminal
+}
+
+func (w *detachableWriter) Write(p []byte) (int, error) {
+	if w.t.isDetached.Load() {
+		return len(p), nil
+	}
+	return w.w.Write(p)
+}
+
+// closerFunc is an adapter to allow the use of ordinary functions as an io.Closer.
+type closerFunc func() error
+
+func (f closerFunc) Close() error {
+	return f()
+}
@@ -0,0 +1,55 @@
+package dockerexec_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestRunTerminal(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	cmd := dockerexec.Command(dockerClient, testImage, "sh", "-c", "cat; echo err >&2")
+	err := dockerexec.RunTerminal(cmd, dockerexec.TerminalOptions{
+		In:            strings.NewReader("hello"),
+		Out:           &stdout,
+		Err:           &stderr,
+		NoSignalProxy: true,
+	})
+	require.NoError(t, err)
+	assert.False(t, cmd.Config.Tty)
+	assert.Equal(t, "hello", stdout.String())
+	assert.Equal(t, "err\n", stderr.String())
+}
+
+func TestRunTerminalDetach(t *testing.T) {
+	var stdout bytes.Buffer
+	cmd := dockerexec.Command(dockerClient, testImage, "cat")
+	cmd.Config.StdinOnce = false
+	err := dockerexec.RunTerminal(cmd, dockerexec.TerminalOptions{
+		In:            strings.NewReader("hello\x10\x11"),
+		Out:           &stdout,
+		NoSignalProxy: true,
+	})
+	require.ErrorIs(t, err, dockerexec.ErrDetached)
+
+	// The container is left running.
+	inspect, err := cmd.Inspect()
+	require.NoError(t, err)
+	assert.True(t, inspect.State.Running)
+	assert.True(t, cmd.Detached())
+
+	require.NoError(t, cmd.Kill("SIGKILL"))
+	var exitErr *dockerexec.ExitError
+	assert.ErrorAs(t, cmd.Wait(), &exitErr)
+}
+
+func TestRunTerminalStdoutSet(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "true")
+	cmd.Stdout = &bytes.Buffer{}
+	assert.Error(t, dockerexec.RunTerminal(cmd, dockerexec.TerminalOptions{}))
+}
@@ -0,0 +1,45 @@
+//go:build !windows
+
+package dockerexec
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// proxiedSignalNames are the signals RunTerminal forwards to the container, with their names as
+// accepted by ContainerKill.
+var proxiedSignalNames = map[os.Signal]string{
+	syscall.SIGINT:  "SIGINT",
+	syscall.SIGTERM: "SIGTERM",
+	syscall.SIGHUP:  "SIGHUP",
+	syscall.SIGQUIT: "SIGQUIT",
+	syscall.SIGUSR1: "SIGUSR1",
+	syscall.SIGUSR2: "SIGUSR2",
+}
+
+// notifyResize notifies ch whenever the terminal fd is resized, until the returned function is
+// called.
+func notifyResize(fd uintptr, ch chan<- struct{}) func() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGWINCH)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-signals:
+				select {
+				case ch <- struct{}{}:
+				default:
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
@@ -0,0 +1,47 @@
+package dockerexec
+
+import (
+	"os"
+	"time"
+
+	"github.com/moby/term"
+)
+
+// resizePollInterval is the interval in which the size of the terminal is polled, as Windows has
+// no resize signal.
+const resizePollInterval = 250 * time.Millisecond
+
+// proxiedSignalNames are the signals RunTerminal forwards to the container, with their names as
+// accepted by ContainerKill.
+var proxiedSignalNames = map[os.Signal]string{
+	os.Interrupt: "SIGINT",
+}
+
+// notifyResize notifies ch whenever the terminal fd is resized, until the returned function is
+// called.
+func notifyResize(fd uintptr, ch chan<- struct{}) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(resizePollInterval)
+		defer ticker.Stop()
+
+		var last term.Winsize
+		for {
+			select {
+			case <-ticker.C:
+				ws, err := term.GetWinsize(fd)
+				if err != nil || *ws == last {
+					continue
+				}
+				last = *ws
+				select {
+				case ch <- struct{}{}:
+				default:
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
@@ -0,0 +1,139 @@
+package dockerexec
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/docker/docker/client"
+)
+
+// TLSOptions configures mutual TLS for connecting to a remote daemon, see WithTLS.
+//
+// Each of the CA, certificate and key can be given either as a path to a PEM file or as in-memory
+// PEM data, where the in-memory data takes precedence.
+type TLSOptions struct {
+	// CertPath is a directory containing ca.pem, cert.pem and key.pem, like DOCKER_CERT_PATH.
+	// Files that are set explicitly take precedence over it.
+	CertPath string
+
+	CAFile   string
+	CertFile string
+	KeyFile  string
+
+	CA   []byte
+	Cert []byte
+	Key  []byte
+
+	// InsecureSkipVerify disables verifying the certificate of the daemon. It's the equivalent of
+	// leaving DOCKER_TLS_VERIFY unset.
+	InsecureSkipVerify bool
+}
+
+// TLSOptionsFromEnv returns the TLSOptions described by the DOCKER_CERT_PATH and DOCKER_TLS_VERIFY
+// environment variables, and whether DOCKER_CERT_PATH is set at all.
+func TLSOptionsFromEnv() (TLSOptions, bool) {
+	certPath := os.Getenv(client.EnvOverrideCertPath)
+	if certPath == "" {
+		return TLSOptions{}, false
+	}
+	return TLSOptions{
+		CertPath:           certPath,
+		InsecureSkipVerify: os.Getenv(client.EnvTLSVerify) == "",
+	}, true
+}
+
+// Config builds the tls.Config described by o.
+func (o TLSOptions) Config() (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: o.InsecureSkipVerify,
+	}
+
+	ca, err := o.pem(o.CA, o.CAFile, "ca.pem")
+	if err != nil {
+		return nil, err
+	}
+	if ca != nil && !o.InsecureSkipVerify {
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, errors.New("dockerexec: failed to parse CA certificate")
+		}
+	}
+
+	cert, err := o.pem(o.Cert, o.CertFile, "cert.pem")
+	if err != nil {
+		return nil, err
+	}
+	key, err := o.pem(o.Key, o.KeyFile, "key.pem")
+	if err != nil {
+		return nil, err
+	}
+	if (cert == nil) != (key == nil) {
+		return nil, errors.New("dockerexec: both a client certificate and key must be given")
+	}
+	if cert != nil {
+		pair, err := tls.X509KeyPair(cert, key)
+		if err != nil {
+			return nil, fmt.Errorf("dockerexec: failed to load client key pair: %w", err)
+		}
+		config.Certificates = []tls.Certificate{pair}
+	}
+
+	return config, nil
+}
+
+// pem returns data if set, otherwise the content of file, or of name inside CertPath. It returns
+// nil if neither is set, or if name doesn't exist inside CertPath.
+func (o TLSOptions) pem(data []byte, file, name string) ([]byte, error) {
+	if data != nil {
+		return data, nil
+	}
+	if file != "" {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("dockerexec: %w", err)
+		}
+		return b, nil
+	}
+	if o.CertPath != "" {
+		b, err := os.ReadFile(filepath.Join(o.CertPath, name))
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("dockerexec: %w", err)
+		}
+		return b, nil
+	}
+	return nil, nil
+}
+
+// WithTLS returns a client.Opt that configures the client to use mutual TLS as described by opts.
+//
+// It replaces the HTTP client of the client, so it must be given before client.WithHost and any
+// other option that configures the transport.
+//
+//	cli, err := client.NewClientWithOpts(
+//		dockerexec.WithTLS(dockerexec.TLSOptions{CertPath: "/etc/docker/certs"}),
+//		client.WithHost("tcp://docker.example.com:2376"),
+//		client.WithAPIVersionNegotiation(),
+//	)
+func WithTLS(opts TLSOptions) client.Opt {
+	return func(c *client.Client) error {
+		config, err := opts.Config()
+		if err != nil {
+			return err
+		}
+		// Keep the proxy, dial and handshake settings of the default transport.
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = config
+		return client.WithHTTPClient(&http.Client{
+			Transport:     transport,
+			CheckRedirect: client.CheckRedirect,
+		})(c)
+	}
+}
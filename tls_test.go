@@ -0,0 +1,89 @@
+package dockerexec_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func generateTestCert(t *testing.T) (certPEM, keyPEM []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "dockerexec"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func TestTLSOptions(t *testing.T) {
+	certPEM, keyPEM := generateTestCert(t)
+
+	config, err := dockerexec.TLSOptions{CA: certPEM, Cert: certPEM, Key: keyPEM}.Config()
+	require.NoError(t, err)
+	assert.NotNil(t, config.RootCAs)
+	assert.Len(t, config.Certificates, 1)
+	assert.False(t, config.InsecureSkipVerify)
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ca.pem"), certPEM, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "cert.pem"), certPEM, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "key.pem"), keyPEM, 0o600))
+
+	t.Setenv(client.EnvOverrideCertPath, dir)
+	t.Setenv(client.EnvTLSVerify, "1")
+	opts, ok := dockerexec.TLSOptionsFromEnv()
+	require.True(t, ok)
+	config, err = opts.Config()
+	require.NoError(t, err)
+	assert.NotNil(t, config.RootCAs)
+	assert.Len(t, config.Certificates, 1)
+
+	_, err = client.NewClientWithOpts(dockerexec.WithTLS(opts), client.WithHost("tcp://127.0.0.1:2376"))
+	require.NoError(t, err)
+
+	_, err = dockerexec.TLSOptions{Cert: certPEM}.Config()
+	assert.ErrorContains(t, err, "both a client certificate and key must be given")
+}
+
+func TestWithTLSTransport(t *testing.T) {
+	certPEM, keyPEM := generateTestCert(t)
+
+	cli, err := client.NewClientWithOpts(dockerexec.WithTLS(dockerexec.TLSOptions{CA: certPEM, Cert: certPEM, Key: keyPEM}))
+	require.NoError(t, err)
+
+	transport, ok := cli.HTTPClient().Transport.(*http.Transport)
+	require.True(t, ok)
+	assert.NotNil(t, transport.TLSClientConfig)
+	assert.NotNil(t, transport.Proxy, "should keep the proxy settings of the default transport")
+	assert.NotZero(t, transport.TLSHandshakeTimeout)
+}
@@ -0,0 +1,283 @@
+package dockerexec
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/registry"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// TraceCall is a single Docker API call recorded by a Trace.
+type TraceCall struct {
+	// Method is the name of the Client method called, e.g. "ContainerCreate".
+	Method string
+
+	// ID is the container, exec or image the call was for, if any.
+	ID string
+
+	// Start is when the call was made, and Duration how long it took. For ContainerWait, it's
+	// how long it took until the result of the wait was received.
+	Start    time.Time
+	Duration time.Duration
+
+	// Err is the error the call failed with, if any.
+	Err error
+}
+
+// Trace records the Docker API calls made on behalf of a Cmd, see Cmd.Trace. It's safe for
+// concurrent use, and may be shared by several Cmds. The zero value is an empty Trace ready to
+// use.
+type Trace struct {
+	mu    sync.Mutex
+	calls []TraceCall
+}
+
+// Calls returns the calls recorded so far, in the order they completed.
+func (t *Trace) Calls() []TraceCall {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]TraceCall(nil), t.calls...)
+}
+
+// WriteTo writes the recorded calls to w, one per line, e.g. to dump them when a run fails.
+func (t *Trace) WriteTo(w io.Writer) (int64, error) {
+	n, err := io.WriteString(w, t.String())
+	return int64(n), err
+}
+
+func (t *Trace) String() string {
+	var sb strings.Builder
+	for _, call := range t.Calls() {
+		outcome := "ok"
+		if call.Err != nil {
+			outcome = "error: " + call.Err.Error()
+		}
+		id := call.ID
+		if id == "" {
+			id = "-"
+		}
+		fmt.Fprintf(&sb, "%s %s %s %s %s\n", call.Start.Format("15:04:05.000"), call.Method, id, call.Duration.Round(time.Microsecond), outcome)
+	}
+	return sb.String()
+}
+
+func (t *Trace) record(method, id string, start time.Time, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.calls = append(t.calls, TraceCall{
+		Method:   method,
+		ID:       id,
+		Start:    start,
+		Duration: time.Since(start),
+		Err:      err,
+	})
+}
+
+// startTrace makes the calls of c go through a traceClient, if Trace is set.
+func (c *Cmd) startTrace() {
+	if c.Trace == nil {
+		return
+	}
+	if _, ok := c.cli.(*traceClient); !ok {
+		c.cli = &traceClient{Client: c.cli, trace: c.Trace}
+	}
+}
+
+// traceClient records the calls made through it to trace.
+type traceClient struct {
+	Client
+	trace *Trace
+}
+
+func (t *traceClient) Ping(ctx context.Context) (types.Ping, error) {
+	start := time.Now()
+	ping, err := t.Client.Ping(ctx)
+	t.trace.record("Ping", "", start, err)
+	return ping, err
+}
+
+func (t *traceClient) ContainerAttach(ctx context.Context, id string, options container.AttachOptions) (types.HijackedResponse, error) {
+	start := time.Now()
+	resp, err := t.Client.ContainerAttach(ctx, id, options)
+	t.trace.record("ContainerAttach", id, start, err)
+	return resp, err
+}
+
+func (t *traceClient) ContainerCreate(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, platform *ocispec.Platform, containerName string) (container.CreateResponse, error) {
+	start := time.Now()
+	resp, err := t.Client.ContainerCreate(ctx, config, hostConfig, networkingConfig, platform, containerName)
+	t.trace.record("ContainerCreate", resp.ID, start, err)
+	return resp, err
+}
+
+func (t *traceClient) NewVersionError(ctx context.Context, APIrequired, feature string) error {
+	return newVersionError(ctx, t.Client, APIrequired, feature)
+}
+
+func (t *traceClient) ContainerDiff(ctx context.Context, id string) ([]container.FilesystemChange, error) {
+	start := time.Now()
+	changes, err := containerDiff(ctx, t.Client, id)
+	t.trace.record("ContainerDiff", id, start, err)
+	return changes, err
+}
+
+func (t *traceClient) ContainerExecAttach(ctx context.Context, execID string, options container.ExecAttachOptions) (types.HijackedResponse, error) {
+	start := time.Now()
+	resp, err := t.Client.ContainerExecAttach(ctx, execID, options)
+	t.trace.record("ContainerExecAttach", execID, start, err)
+	return resp, err
+}
+
+func (t *traceClient) ContainerExecCreate(ctx context.Context, id string, options container.ExecOptions) (types.IDResponse, error) {
+	start := time.Now()
+	resp, err := t.Client.ContainerExecCreate(ctx, id, options)
+	t.trace.record("ContainerExecCreate", id, start, err)
+	return resp, err
+}
+
+func (t *traceClient) ContainerExecInspect(ctx context.Context, execID string) (container.ExecInspect, error) {
+	start := time.Now()
+	inspect, err := t.Client.ContainerExecInspect(ctx, execID)
+	t.trace.record("ContainerExecInspect", execID, start, err)
+	return inspect, err
+}
+
+func (t *traceClient) ContainerInspect(ctx context.Context, id string) (types.ContainerJSON, error) {
+	start := time.Now()
+	inspect, err := t.Client.ContainerInspect(ctx, id)
+	t.trace.record("ContainerInspect", id, start, err)
+	return inspect, err
+}
+
+func (t *traceClient) ContainerKill(ctx context.Context, id, signal string) error {
+	start := time.Now()
+	err := t.Client.ContainerKill(ctx, id, signal)
+	t.trace.record("ContainerKill", id, start, err)
+	return err
+}
+
+func (t *traceClient) ContainerList(ctx context.Context, options container.ListOptions) ([]types.Container, error) {
+	start := time.Now()
+	containers, err := t.Client.ContainerList(ctx, options)
+	t.trace.record("ContainerList", "", start, err)
+	return containers, err
+}
+
+func (t *traceClient) ContainerLogs(ctx context.Context, id string, options container.LogsOptions) (io.ReadCloser, error) {
+	start := time.Now()
+	rc, err := t.Client.ContainerLogs(ctx, id, options)
+	t.trace.record("ContainerLogs", id, start, err)
+	return rc, err
+}
+
+func (t *traceClient) ContainerRemove(ctx context.Context, id string, options container.RemoveOptions) error {
+	start := time.Now()
+	err := t.Client.ContainerRemove(ctx, id, options)
+	t.trace.record("ContainerRemove", id, start, err)
+	return err
+}
+
+func (t *traceClient) ContainerResize(ctx context.Context, id string, options container.ResizeOptions) error {
+	start := time.Now()
+	err := t.Client.ContainerResize(ctx, id, options)
+	t.trace.record("ContainerResize", id, start, err)
+	return err
+}
+
+func (t *traceClient) ContainerStart(ctx context.Context, id string, options container.StartOptions) error {
+	start := time.Now()
+	err := t.Client.ContainerStart(ctx, id, options)
+	t.trace.record("ContainerStart", id, start, err)
+	return err
+}
+
+func (t *traceClient) ContainerStats(ctx context.Context, id string, stream bool) (container.StatsResponseReader, error) {
+	start := time.Now()
+	stats, err := containerStats(ctx, t.Client, id, stream)
+	t.trace.record("ContainerStats", id, start, err)
+	return stats, err
+}
+
+func (t *traceClient) ContainerStatsOneShot(ctx context.Context, id string) (container.StatsResponseReader, error) {
+	start := time.Now()
+	stats, err := containerStatsOneShot(ctx, t.Client, id)
+	t.trace.record("ContainerStatsOneShot", id, start, err)
+	return stats, err
+}
+
+func (t *traceClient) ContainerStop(ctx context.Context, id string, options container.StopOptions) error {
+	start := time.Now()
+	err := t.Client.ContainerStop(ctx, id, options)
+	t.trace.record("ContainerStop", id, start, err)
+	return err
+}
+
+func (t *traceClient) ContainerWait(ctx context.Context, id string, condition container.WaitCondition) (<-chan container.WaitResponse, <-chan error) {
+	start := time.Now()
+	waitCh, errCh := t.Client.ContainerWait(ctx, id, condition)
+
+	resultC := make(chan container.WaitResponse, 1)
+	errC := make(chan error, 1)
+	go func() {
+		select {
+		case result := <-waitCh:
+			t.trace.record("ContainerWait", id, start, nil)
+			resultC <- result
+		case err := <-errCh:
+			t.trace.record("ContainerWait", id, start, err)
+			errC <- err
+		}
+	}()
+	return resultC, errC
+}
+
+func (t *traceClient) CopyFromContainer(ctx context.Context, id, srcPath string) (io.ReadCloser, container.PathStat, error) {
+	start := time.Now()
+	rc, stat, err := t.Client.CopyFromContainer(ctx, id, srcPath)
+	t.trace.record("CopyFromContainer", id, start, err)
+	return rc, stat, err
+}
+
+func (t *traceClient) CopyToContainer(ctx context.Context, id, path string, content io.Reader, options container.CopyToContainerOptions) error {
+	start := time.Now()
+	err := t.Client.CopyToContainer(ctx, id, path, content, options)
+	t.trace.record("CopyToContainer", id, start, err)
+	return err
+}
+
+func (t *traceClient) DistributionInspect(ctx context.Context, image, encodedRegistryAuth string) (registry.DistributionInspect, error) {
+	start := time.Now()
+	inspect, err := distributionInspect(ctx, t.Client, image, encodedRegistryAuth)
+	t.trace.record("DistributionInspect", image, start, err)
+	return inspect, err
+}
+
+func (t *traceClient) ImageInspectWithRaw(ctx context.Context, image string) (types.ImageInspect, []byte, error) {
+	start := time.Now()
+	inspect, raw, err := t.Client.ImageInspectWithRaw(ctx, image)
+	t.trace.record("ImageInspectWithRaw", image, start, err)
+	return inspect, raw, err
+}
+
+func (t *traceClient) ImagePull(ctx context.Context, ref string, options image.PullOptions) (io.ReadCloser, error) {
+	start := time.Now()
+	rc, err := t.Client.ImagePull(ctx, ref, options)
+	t.trace.record("ImagePull", ref, start, err)
+	return rc, err
+}
+
+func (t *traceClient) ImageTag(ctx context.Context, image, ref string) error {
+	start := time.Now()
+	err := t.Client.ImageTag(ctx, image, ref)
+	t.trace.record("ImageTag", image, start, err)
+	return err
+}
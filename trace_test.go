@@ -0,0 +1,46 @@
+package dockerexec_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestTrace(t *testing.T) {
+	var trace dockerexec.Trace
+	cmd := dockerexec.Command(dockerClient, testImage, "true")
+	cmd.Trace = &trace
+	err := cmd.Run()
+	require.NoError(t, err)
+
+	var methods []string
+	for _, call := range trace.Calls() {
+		methods = append(methods, call.Method)
+		assert.NoError(t, call.Err, call.Method)
+	}
+	assert.Contains(t, methods, "ContainerCreate")
+	assert.Contains(t, methods, "ContainerStart")
+	assert.Contains(t, methods, "ContainerWait")
+
+	var sb strings.Builder
+	_, err = trace.WriteTo(&sb)
+	require.NoError(t, err)
+	assert.Contains(t, sb.String(), "ContainerStart "+cmd.ContainerID)
+}
+
+func TestTraceError(t *testing.T) {
+	var trace dockerexec.Trace
+	cmd := dockerexec.Command(dockerClient, "dockerexec-does-not-exist:latest", "true")
+	cmd.Trace = &trace
+	err := cmd.Run()
+	require.Error(t, err)
+
+	calls := trace.Calls()
+	require.NotEmpty(t, calls)
+	assert.Error(t, calls[len(calls)-1].Err)
+	assert.Contains(t, trace.String(), "error: ")
+}
@@ -0,0 +1,60 @@
+package dockerexec
+
+import (
+	"fmt"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// ulimitNames are the resource limits supported by the daemon, as named by ulimit -a.
+var ulimitNames = map[string]bool{
+	"core":       true,
+	"cpu":        true,
+	"data":       true,
+	"fsize":      true,
+	"locks":      true,
+	"memlock":    true,
+	"msgqueue":   true,
+	"nice":       true,
+	"nofile":     true,
+	"nproc":      true,
+	"rss":        true,
+	"rtprio":     true,
+	"rttime":     true,
+	"sigpending": true,
+	"stack":      true,
+}
+
+// WithUlimit sets the soft and hard values of the resource limit name, such as "nofile" or
+// "nproc", in HostConfig.Ulimits, replacing any previous value of it. -1 means unlimited.
+//
+// An unknown name, or a soft value above the hard value, sets Err, so that Start fails. It
+// returns c to allow chaining.
+func (c *Cmd) WithUlimit(name string, soft, hard int64) *Cmd {
+	if !ulimitNames[name] {
+		c.setErr(fmt.Errorf("dockerexec: unknown ulimit %q", name))
+		return c
+	}
+	if hard != -1 && (soft == -1 || soft > hard) {
+		c.setErr(fmt.Errorf("dockerexec: soft ulimit %s of %d exceeds the hard limit of %d", name, soft, hard))
+		return c
+	}
+
+	hc := c.hostConfig()
+	ulimit := &container.Ulimit{Name: name, Soft: soft, Hard: hard}
+	for i := range hc.Ulimits {
+		if hc.Ulimits[i].Name == name {
+			hc.Ulimits[i] = ulimit
+			return c
+		}
+	}
+	hc.Ulimits = append(hc.Ulimits, ulimit)
+	return c
+}
+
+// setErr sets Err, unless it's already set.
+func (c *Cmd) setErr(err error) {
+	if c.Err == nil {
+		c.Err = err
+	}
+}
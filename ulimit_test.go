@@ -0,0 +1,30 @@
+package dockerexec_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestWithUlimit(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "sh", "-c", "ulimit -Sn; ulimit -Hn")
+	cmd.WithUlimit("nofile", 1000, 2000).WithUlimit("nofile", 512, 1024)
+	require.Len(t, cmd.HostConfig.Ulimits, 1)
+
+	output, err := cmd.Output()
+	require.NoError(t, err)
+	assert.Equal(t, "512\n1024\n", string(output))
+}
+
+func TestWithUlimitInvalid(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "true")
+	cmd.WithUlimit("files", 10, 10)
+	assert.EqualError(t, cmd.Run(), `dockerexec: unknown ulimit "files"`)
+
+	cmd = dockerexec.Command(dockerClient, testImage, "true")
+	cmd.WithUlimit("nofile", 20, 10)
+	assert.Error(t, cmd.Run())
+}
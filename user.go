@@ -0,0 +1,22 @@
+package dockerexec
+
+import (
+	"os"
+	"strconv"
+)
+
+// WithCurrentUser sets Config.User to the UID and GID of the current process, so that files
+// written by the container to bind mounts are owned by the current user rather than root. It also
+// sets EnsurePasswd, as the UID usually has no entry in the image.
+//
+// It does nothing on Windows, where there are no UIDs, and bind mounts of Docker Desktop are
+// owned by the current user regardless. It returns c to allow chaining.
+func (c *Cmd) WithCurrentUser() *Cmd {
+	uid, gid := os.Getuid(), os.Getgid()
+	if uid < 0 || gid < 0 {
+		return c
+	}
+	c.Config.User = strconv.Itoa(uid) + ":" + strconv.Itoa(gid)
+	c.EnsurePasswd = true
+	return c
+}
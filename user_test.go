@@ -0,0 +1,25 @@
+package dockerexec_test
+
+import (
+	"os"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestWithCurrentUser(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no UIDs on Windows")
+	}
+
+	cmd := dockerexec.Command(dockerClient, testImage, "sh", "-c", "id -u; id -g").WithCurrentUser()
+	assert.True(t, cmd.EnsurePasswd)
+	output, err := cmd.Output()
+	require.NoError(t, err)
+	assert.Equal(t, strconv.Itoa(os.Getuid())+"\n"+strconv.Itoa(os.Getgid())+"\n", string(output))
+}
@@ -0,0 +1,138 @@
+package dockerexec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/client"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// Validate checks the configuration of the Cmd before starting it, returning all problems found
+// at once, joined using errors.Join, instead of failing midway through Start.
+//
+// It checks that:
+//   - The image exists on the daemon, or can be pulled from its registry.
+//   - The image supports Platform, if set.
+//   - The host paths of bind mounts exist. This is only meaningful when the daemon runs on the
+//     same host.
+//   - Config.Tty isn't used together with Stderr.
+func (c *Cmd) Validate(ctx context.Context) error {
+	if err := c.ensureClient(); err != nil {
+		return err
+	}
+
+	var errs []error
+
+	if c.Config.Tty && c.Stderr != nil {
+		errs = append(errs, errors.New("dockerexec: can't set both Config.Tty and Stderr"))
+	}
+
+	if err := c.validateImage(ctx); err != nil {
+		errs = append(errs, err)
+	}
+
+	for _, source := range c.bindSources() {
+		if _, err := os.Stat(source); err != nil {
+			errs = append(errs, fmt.Errorf("dockerexec: bind mount source: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (c *Cmd) validateImage(ctx context.Context) error {
+	img, _, err := c.cli.ImageInspectWithRaw(ctx, c.Config.Image)
+	if err == nil {
+		if c.Platform != nil && !platformMatches(*c.Platform, ocispec.Platform{
+			OS:           img.Os,
+			Architecture: img.Architecture,
+			Variant:      img.Variant,
+		}) {
+			return fmt.Errorf("dockerexec: image %s is for platform %s, not %s",
+				c.Config.Image, formatPlatform(img.Os, img.Architecture, img.Variant), formatPlatform(c.Platform.OS, c.Platform.Architecture, c.Platform.Variant))
+		}
+		return nil
+	}
+	if !client.IsErrNotFound(err) {
+		return fmt.Errorf("dockerexec: inspecting image %s: %w", c.Config.Image, err)
+	}
+
+	dist, err := distributionInspect(ctx, c.cli, c.Config.Image, "")
+	if err != nil {
+		return fmt.Errorf("dockerexec: image %s not found locally and can't be pulled: %w", c.Config.Image, err)
+	}
+	if c.Platform != nil && len(dist.Platforms) > 0 {
+		for _, p := range dist.Platforms {
+			if platformMatches(*c.Platform, p) {
+				return nil
+			}
+		}
+		return fmt.Errorf("dockerexec: image %s has no variant for platform %s",
+			c.Config.Image, formatPlatform(c.Platform.OS, c.Platform.Architecture, c.Platform.Variant))
+	}
+	return nil
+}
+
+// platformMatches reports whether have satisfies want, where empty fields in want match anything.
+func platformMatches(want, have ocispec.Platform) bool {
+	return (want.OS == "" || want.OS == have.OS) &&
+		(want.Architecture == "" || want.Architecture == have.Architecture) &&
+		(want.Variant == "" || want.Variant == have.Variant)
+}
+
+func formatPlatform(goos, arch, variant string) string {
+	s := goos + "/" + arch
+	if variant != "" {
+		s += "/" + variant
+	}
+	return s
+}
+
+// bindSources returns the host paths of the bind mounts of c.
+func (c *Cmd) bindSources() []string {
+	if c.HostConfig == nil {
+		return nil
+	}
+
+	var sources []string
+	for _, bind := range c.HostConfig.Binds {
+		source := bindSource(bind)
+		// Otherwise it's a named volume.
+		if filepath.IsAbs(source) || strings.HasPrefix(source, "/") || isDrivePath(source) {
+			if runtime.GOOS == "windows" {
+				source = windowsHostPath(source)
+			}
+			sources = append(sources, source)
+		}
+	}
+	for _, m := range c.HostConfig.Mounts {
+		if m.Type == mount.TypeBind {
+			sources = append(sources, m.Source)
+		}
+	}
+	return sources
+}
+
+// bindSource returns the source of a bind string, which may start with a Windows drive letter, as
+// in C:\data:/data.
+func bindSource(bind string) string {
+	var drive string
+	if isDrivePath(bind) {
+		drive, bind = bind[:2], bind[2:]
+	}
+	source, _, _ := strings.Cut(bind, ":")
+	return drive + source
+}
+
+// isDrivePath reports whether p starts with a Windows drive letter followed by a colon and a path
+// separator.
+func isDrivePath(p string) bool {
+	return len(p) >= 3 && isDriveLetter(p[0]) && p[1] == ':' && (p[2] == '\\' || p[2] == '/')
+}
@@ -0,0 +1,43 @@
+package dockerexec_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestValidate(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "true")
+	cmd.HostConfig.Binds = []string{t.TempDir() + ":/data", "named-volume:/cache"}
+	assert.NoError(t, cmd.Validate(context.Background()))
+}
+
+func TestValidateProblems(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "true")
+	cmd.Config.Tty = true
+	cmd.Stderr = &bytes.Buffer{}
+	cmd.Platform = &ocispec.Platform{OS: "windows", Architecture: "amd64"}
+	cmd.HostConfig.Binds = []string{"/no-exist-directory:/data"}
+
+	err := cmd.Validate(context.Background())
+	assert.ErrorContains(t, err, "dockerexec: can't set both Config.Tty and Stderr")
+	assert.ErrorContains(t, err, "not windows/amd64")
+	assert.ErrorContains(t, err, "dockerexec: bind mount source: stat /no-exist-directory")
+}
+
+func TestValidateNoExistImage(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, "segevfiner/no-exist-image:latest", "true")
+	assert.ErrorContains(t, cmd.Validate(context.Background()), "not found locally and can't be pulled")
+}
+
+func TestValidateDriveLetterBind(t *testing.T) {
+	cmd := dockerexec.Command(dockerClient, testImage, "true")
+	cmd.HostConfig.Binds = []string{`C:\no-exist-directory:/data:ro`}
+	assert.ErrorContains(t, cmd.Validate(context.Background()), `dockerexec: bind mount source: `+
+		`stat C:\no-exist-directory`)
+}
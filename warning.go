@@ -0,0 +1,37 @@
+package dockerexec
+
+// WarningCode classifies a Warning.
+type WarningCode string
+
+// Warning codes.
+const (
+	// WarningDaemon is a warning returned by the daemon when creating the container or service,
+	// such as about a resource limit the kernel doesn't support.
+	WarningDaemon WarningCode = "daemon"
+
+	// WarningUnsupportedFeature is a setting that is ignored as the daemon's API version doesn't
+	// support it.
+	WarningUnsupportedFeature WarningCode = "unsupported-feature"
+
+	// WarningPrivileged is a container running privileged, see Cmd.Privileged.
+	WarningPrivileged WarningCode = "privileged"
+)
+
+// Warning is a warning about creating a container or service, see Cmd.Warnings, Cmd.OnWarning and
+// their SwarmCmd counterparts.
+type Warning struct {
+	Code    WarningCode
+	Message string
+}
+
+func (w Warning) String() string {
+	return w.Message
+}
+
+// warn records w in Warnings and delivers it to OnWarning.
+func (c *Cmd) warn(w Warning) {
+	c.Warnings = append(c.Warnings, w)
+	if c.OnWarning != nil {
+		c.OnWarning(w)
+	}
+}
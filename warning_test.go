@@ -0,0 +1,33 @@
+package dockerexec_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func warningCodes(warnings []dockerexec.Warning) []dockerexec.WarningCode {
+	var codes []dockerexec.WarningCode
+	for _, w := range warnings {
+		codes = append(codes, w.Code)
+	}
+	return codes
+}
+
+func TestOnWarning(t *testing.T) {
+	var received []dockerexec.Warning
+
+	cmd := dockerexec.Command(dockerClient, testImage, "true")
+	cmd.Privileged()
+	cmd.OnWarning = func(w dockerexec.Warning) {
+		received = append(received, w)
+	}
+	err := cmd.Run()
+	require.NoError(t, err)
+
+	assert.Equal(t, cmd.Warnings, received)
+	assert.Contains(t, warningCodes(received), dockerexec.WarningPrivileged)
+}
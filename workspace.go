@@ -0,0 +1,30 @@
+package dockerexec
+
+import (
+	"fmt"
+	"os"
+)
+
+// WorkspaceDir is where MountWorkspace mounts the workspace in the container.
+const WorkspaceDir = "/workspace"
+
+// MountWorkspace bind-mounts the host directory dir, or the current working directory if dir is
+// empty, at WorkspaceDir in the container, sets it as the working directory of the container,
+// and runs the container as the current user using WithCurrentUser, so files it writes there are
+// owned by the current user. It's a one-call mode for running a command against a checkout.
+func (c *Cmd) MountWorkspace(dir string) error {
+	if dir == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("dockerexec: workspace: %w", err)
+		}
+		dir = wd
+	}
+
+	if err := c.AddBind(dir, WorkspaceDir, false); err != nil {
+		return err
+	}
+	c.Config.WorkingDir = WorkspaceDir
+	c.WithCurrentUser()
+	return nil
+}
@@ -0,0 +1,27 @@
+package dockerexec_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/segevfiner/dockerexec"
+)
+
+func TestMountWorkspace(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "input.txt"), []byte("hello\n"), 0o644))
+
+	cmd := dockerexec.Command(dockerClient, testImage, "sh", "-c", "pwd; cat input.txt; echo bye > output.txt")
+	require.NoError(t, cmd.MountWorkspace(dir))
+	output, err := cmd.Output()
+	require.NoError(t, err)
+	assert.Equal(t, dockerexec.WorkspaceDir+"\nhello\n", string(output))
+
+	data, err := os.ReadFile(filepath.Join(dir, "output.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "bye\n", string(data))
+}